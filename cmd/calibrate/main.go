@@ -0,0 +1,115 @@
+// Command calibrate benchmarks password hashing on this machine and prints the
+// parameters of the password block of config.yml hashing within the target time.
+package main
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/PanziApp/backend/internal/domain"
+)
+
+const _runs = 3
+
+func main() {
+	algorithm := flag.String("algorithm", domain.Argon2id, "argon2id or bcrypt")
+	target := flag.Duration("target", 250*time.Millisecond, "longest time hashing a password may take")
+	maxMemory := flag.Uint("max-memory", 1024*1024, "most memory in KiB argon2id may use per hash")
+	parallelism := flag.Uint("parallelism", 2, "threads argon2id uses per hash")
+	flag.Parse()
+
+	switch *algorithm {
+	case domain.Argon2id:
+		h, took := calibrateArgon2id(*target, uint32(*maxMemory), uint8(*parallelism))
+		fmt.Printf("# hashing took %s\n", took)
+		fmt.Printf("hash_algorithm: '%s'\n", domain.Argon2id)
+		fmt.Printf("argon2_memory: %d\n", h.Argon2.Memory)
+		fmt.Printf("argon2_iterations: %d\n", h.Argon2.Iterations)
+		fmt.Printf("argon2_parallelism: %d\n", h.Argon2.Parallelism)
+	case domain.Bcrypt:
+		h, took := calibrateBcrypt(*target)
+		fmt.Printf("# hashing took %s\n", took)
+		fmt.Printf("hash_algorithm: '%s'\n", domain.Bcrypt)
+		fmt.Printf("bcrypt_cost: %d\n", h.BcryptCost)
+	default:
+		log.Fatalf("unknown algorithm %q", *algorithm)
+	}
+}
+
+// calibrateArgon2id doubles the memory up to maxMemory and then adds
+// iterations, as long as hashing stays within the target. Memory is raised
+// first as it is what makes cracking on GPUs costly.
+func calibrateArgon2id(target time.Duration, maxMemory uint32, parallelism uint8) (domain.PasswordHasher, time.Duration) {
+	h := domain.PasswordHasher{
+		Algorithm: domain.Argon2id,
+		Argon2:    domain.Argon2Params{Memory: 16 * 1024, Iterations: 1, Parallelism: parallelism},
+	}
+	if err := h.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	took := measure(h)
+	for h.Argon2.Memory*2 <= maxMemory {
+		next := h
+		next.Argon2.Memory *= 2
+
+		nextTook := measure(next)
+		if nextTook > target {
+			break
+		}
+		h, took = next, nextTook
+	}
+
+	for {
+		next := h
+		next.Argon2.Iterations++
+
+		nextTook := measure(next)
+		if nextTook > target {
+			return h, took
+		}
+		h, took = next, nextTook
+	}
+}
+
+func calibrateBcrypt(target time.Duration) (domain.PasswordHasher, time.Duration) {
+	h := domain.PasswordHasher{Algorithm: domain.Bcrypt, BcryptCost: 10}
+
+	took := measure(h)
+	for h.BcryptCost < 31 {
+		next := h
+		next.BcryptCost++
+
+		nextTook := measure(next)
+		if nextTook > target {
+			break
+		}
+		h, took = next, nextTook
+	}
+
+	return h, took
+}
+
+// measure returns the median time the hasher takes hashing a random password.
+func measure(h domain.PasswordHasher) time.Duration {
+	password := make([]byte, 16)
+	if _, err := rand.Read(password); err != nil {
+		log.Fatal(err)
+	}
+
+	times := make([]time.Duration, 0, _runs)
+	for i := 0; i < _runs; i++ {
+		start := time.Now()
+		if _, err := h.Hash(password); err != nil {
+			log.Fatal(err)
+		}
+		times = append(times, time.Since(start))
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	return times[_runs/2]
+}
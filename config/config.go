@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -9,11 +10,23 @@ import (
 type (
 	// Config -.
 	Config struct {
-		App  `yaml:"app"`
-		HTTP `yaml:"http"`
-		Log  `yaml:"logger"`
-		PG   `yaml:"postgres"`
-		RMQ  `yaml:"rabbitmq"`
+		App       `yaml:"app"`
+		HTTP      `yaml:"http"`
+		Log       `yaml:"logger"`
+		PG        `yaml:"postgres"`
+		RMQ       `yaml:"rabbitmq"`
+		Mail      `yaml:"mail"`
+		Storage   `yaml:"storage"`
+		Session   `yaml:"session"`
+		JWT       `yaml:"jwt"`
+		WebAuthn  `yaml:"webauthn"`
+		OIDC      `yaml:"oidc"`
+		OAuth     `yaml:"oauth"`
+		Account   `yaml:"account"`
+		SignIn    `yaml:"sign_in"`
+		RateLimit `yaml:"rate_limit"`
+		Password  `yaml:"password"`
+		Admin     `yaml:"admin"`
 	}
 
 	// App -.
@@ -24,7 +37,9 @@ type (
 
 	// HTTP -.
 	HTTP struct {
-		Port string `env-required:"true" yaml:"port" env:"HTTP_PORT"`
+		Port           string   `env-required:"true" yaml:"port" env:"HTTP_PORT"`
+		TrustedProxies []string `yaml:"trusted_proxies" env:"HTTP_TRUSTED_PROXIES"`
+		CookieAuth     bool     `yaml:"cookie_auth"     env:"HTTP_COOKIE_AUTH"`
 	}
 
 	// Log -.
@@ -44,6 +59,117 @@ type (
 		ClientExchange string `env-required:"true" yaml:"rpc_client_exchange" env:"RMQ_RPC_CLIENT"`
 		URL            string `env-required:"true"                            env:"RMQ_URL"`
 	}
+
+	// Mail -.
+	Mail struct {
+		Provider string `env-required:"true" yaml:"provider" env:"MAIL_PROVIDER"`
+		Username string `                                    env:"MAIL_USERNAME"`
+		Password string `                                    env:"MAIL_PASSWORD"`
+	}
+
+	// Storage -.
+	Storage struct {
+		Path string `env-required:"true" yaml:"path" env:"STORAGE_PATH"`
+	}
+
+	// Session -.
+	Session struct {
+		AccessTokenLifetime      time.Duration `env-required:"true" yaml:"access_token_lifetime"       env:"SESSION_ACCESS_TOKEN_LIFETIME"`
+		RefreshTokenIdleLifetime time.Duration `env-required:"true" yaml:"refresh_token_idle_lifetime" env:"SESSION_REFRESH_TOKEN_IDLE_LIFETIME"`
+		AbsoluteLifetime         time.Duration `env-required:"true" yaml:"absolute_lifetime"           env:"SESSION_ABSOLUTE_LIFETIME"`
+		MaxPerUser               int           `                    yaml:"max_per_user"                env:"SESSION_MAX_PER_USER"`
+	}
+
+	// JWT -.
+	JWT struct {
+		Enabled     bool   `yaml:"enabled"        env:"JWT_ENABLED"`
+		Issuer      string `yaml:"issuer"         env:"JWT_ISSUER"`
+		Algorithm   string `yaml:"algorithm"      env:"JWT_ALGORITHM"`
+		KeysPath    string `yaml:"keys_path"      env:"JWT_KEYS_PATH"`
+		ActiveKeyId string `yaml:"active_key_id"  env:"JWT_ACTIVE_KEY_ID"`
+	}
+
+	// WebAuthn -.
+	WebAuthn struct {
+		RPId    string   `env-required:"true" yaml:"rp_id"    env:"WEBAUTHN_RP_ID"`
+		RPName  string   `env-required:"true" yaml:"rp_name"  env:"WEBAUTHN_RP_NAME"`
+		Origins []string `env-required:"true" yaml:"origins"  env:"WEBAUTHN_ORIGINS"`
+	}
+
+	// OIDC -.
+	OIDC struct {
+		Providers []OIDCProvider `yaml:"providers"`
+	}
+
+	// OIDCProvider is an external OpenID provider users can sign in with,
+	// the redirect url is the page of the client receiving the code and state.
+	OIDCProvider struct {
+		Name         string   `yaml:"name"`
+		Issuer       string   `yaml:"issuer"`
+		ClientId     string   `yaml:"client_id"`
+		ClientSecret string   `yaml:"client_secret"`
+		RedirectURL  string   `yaml:"redirect_url"`
+		Scopes       []string `yaml:"scopes"`
+	}
+
+	// OAuth -.
+	OAuth struct {
+		AuthorizationPage string   `env-required:"true" yaml:"authorization_page" env:"OAUTH_AUTHORIZATION_PAGE"`
+		RedirectSchemes   []string `yaml:"redirect_schemes" env:"OAUTH_REDIRECT_SCHEMES"`
+	}
+
+	// Account -.
+	Account struct {
+		DeletionGracePeriod time.Duration `env-required:"true" yaml:"deletion_grace_period" env:"ACCOUNT_DELETION_GRACE_PERIOD"`
+		PurgeInterval       time.Duration `env-required:"true" yaml:"purge_interval"        env:"ACCOUNT_PURGE_INTERVAL"`
+		DataExportInterval  time.Duration `env-required:"true" yaml:"data_export_interval"  env:"ACCOUNT_DATA_EXPORT_INTERVAL"`
+	}
+
+	// SignIn -.
+	SignIn struct {
+		LockoutThreshold   int           `yaml:"lockout_threshold"    env:"SIGN_IN_LOCKOUT_THRESHOLD"`
+		IPLockoutThreshold int           `yaml:"ip_lockout_threshold" env:"SIGN_IN_IP_LOCKOUT_THRESHOLD"`
+		LockoutDuration    time.Duration `env-required:"true" yaml:"lockout_duration" env:"SIGN_IN_LOCKOUT_DURATION"`
+		CodeKey            string        `env-required:"true"                         env:"SIGN_IN_CODE_KEY"`
+	}
+
+	// RateLimit -.
+	RateLimit struct {
+		Store         string            `env-required:"true" yaml:"store"          env:"RATE_LIMIT_STORE"`
+		PruneInterval time.Duration     `env-required:"true" yaml:"prune_interval" env:"RATE_LIMIT_PRUNE_INTERVAL"`
+		Policies      []RateLimitPolicy `yaml:"policies"`
+	}
+
+	// Password -.
+	Password struct {
+		MinLength            int    `env-required:"true" yaml:"min_length" env:"PASSWORD_MIN_LENGTH"`
+		MinCharacterClasses  int    `yaml:"min_character_classes"  env:"PASSWORD_MIN_CHARACTER_CLASSES"`
+		MaxRepeatedChars     int    `yaml:"max_repeated_chars"     env:"PASSWORD_MAX_REPEATED_CHARS"`
+		DisallowPersonalInfo bool   `yaml:"disallow_personal_info" env:"PASSWORD_DISALLOW_PERSONAL_INFO"`
+		MinStrength          int    `yaml:"min_strength"           env:"PASSWORD_MIN_STRENGTH"`
+		BreachedCorpusPath   string `yaml:"breached_corpus_path"   env:"PASSWORD_BREACHED_CORPUS_PATH"`
+		BreachedMinCount     int    `yaml:"breached_min_count"     env:"PASSWORD_BREACHED_MIN_COUNT"`
+		HashAlgorithm        string `env-required:"true" yaml:"hash_algorithm" env:"PASSWORD_HASH_ALGORITHM"`
+		Argon2Memory         uint32 `yaml:"argon2_memory"          env:"PASSWORD_ARGON2_MEMORY"`
+		Argon2Iterations     uint32 `yaml:"argon2_iterations"      env:"PASSWORD_ARGON2_ITERATIONS"`
+		Argon2Parallelism    uint8  `yaml:"argon2_parallelism"     env:"PASSWORD_ARGON2_PARALLELISM"`
+		BcryptCost           int    `yaml:"bcrypt_cost"            env:"PASSWORD_BCRYPT_COST"`
+		Pepper               string `env:"PASSWORD_PEPPER"`
+	}
+
+	// Admin -.
+	Admin struct {
+		BootstrapEmail string `yaml:"bootstrap_email" env:"ADMIN_BOOTSTRAP_EMAIL"`
+	}
+
+	// RateLimitPolicy limits the calls of a route by a key, see config.yml.
+	RateLimitPolicy struct {
+		Route     string        `yaml:"route"`
+		Key       string        `yaml:"key"`
+		Algorithm string        `yaml:"algorithm"`
+		Limit     int           `yaml:"limit"`
+		Period    time.Duration `yaml:"period"`
+	}
 )
 
 // NewConfig returns app config.
@@ -2,6 +2,8 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -12,12 +14,20 @@ import (
 	"github.com/PanziApp/backend/config"
 	amqprpc "github.com/PanziApp/backend/internal/controller/amqp_rpc"
 	v1 "github.com/PanziApp/backend/internal/controller/http/v1"
+	"github.com/PanziApp/backend/internal/domain"
 	"github.com/PanziApp/backend/internal/usecase"
 	"github.com/PanziApp/backend/internal/usecase/repo"
+	"github.com/PanziApp/backend/pkg/breached"
 	"github.com/PanziApp/backend/pkg/httpserver"
+	"github.com/PanziApp/backend/pkg/jwt"
 	"github.com/PanziApp/backend/pkg/logger"
+	"github.com/PanziApp/backend/pkg/mail"
+	"github.com/PanziApp/backend/pkg/oidc"
 	"github.com/PanziApp/backend/pkg/postgres"
 	"github.com/PanziApp/backend/pkg/rabbitmq/rmq_rpc/server"
+	"github.com/PanziApp/backend/pkg/ratelimit"
+	"github.com/PanziApp/backend/pkg/storage"
+	"github.com/PanziApp/backend/pkg/webauthn"
 )
 
 // Run creates objects via constructors.
@@ -31,13 +41,165 @@ func Run(cfg *config.Config) {
 	}
 	defer pg.Close()
 
+	// Mailer
+	var mailer mail.Mailer
+	switch cfg.Mail.Provider {
+	case "gmail":
+		mailer = mail.NewGmailSender(cfg.Mail.Username, cfg.Mail.Password)
+	case "mock":
+		mailer = mail.MailMock{}
+	default:
+		l.Fatal(fmt.Errorf("app - Run - unknown mail provider: %s", cfg.Mail.Provider))
+	}
+
+	// Storage
+	fileStorage, err := storage.NewLocal(cfg.Storage.Path)
+	if err != nil {
+		l.Fatal(fmt.Errorf("app - Run - storage.NewLocal: %w", err))
+	}
+
 	// Use case
-	translationUseCase := usecase.New(
-		repo.New(pg),
+	userUseCaseOptions := []usecase.Option{
+		usecase.AccessTokenLifetime(cfg.Session.AccessTokenLifetime),
+		usecase.RefreshTokenIdleLifetime(cfg.Session.RefreshTokenIdleLifetime),
+		usecase.SessionAbsoluteLifetime(cfg.Session.AbsoluteLifetime),
+		usecase.MaxSessionsPerUser(cfg.Session.MaxPerUser),
+	}
+
+	var keyring *jwt.Keyring
+	if cfg.JWT.Enabled {
+		keyring, err = jwt.LoadKeyring(cfg.JWT.KeysPath, cfg.JWT.ActiveKeyId, cfg.JWT.Algorithm)
+		if err != nil {
+			l.Fatal(fmt.Errorf("app - Run - jwt.LoadKeyring: %w", err))
+		}
+
+		userUseCaseOptions = append(userUseCaseOptions, usecase.SignedAccessTokens(keyring, cfg.JWT.Issuer))
+	}
+
+	relyingParty := webauthn.New(cfg.WebAuthn.RPId, cfg.WebAuthn.RPName, cfg.WebAuthn.Origins)
+	userUseCaseOptions = append(userUseCaseOptions, usecase.Passkeys(relyingParty))
+
+	externalProviders := make(map[string]usecase.ExternalProvider, len(cfg.OIDC.Providers))
+	for _, p := range cfg.OIDC.Providers {
+		if _, ok := externalProviders[p.Name]; ok || p.Name == "" || len(p.Name) > 32 {
+			l.Fatal(fmt.Errorf("app - Run - oidc provider name %q must be unique and 1 to 32 characters", p.Name))
+		}
+
+		externalProviders[p.Name] = oidc.New(oidc.Config{
+			Issuer:       p.Issuer,
+			ClientId:     p.ClientId,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		})
+	}
+	userUseCaseOptions = append(userUseCaseOptions,
+		usecase.ExternalProviders(externalProviders),
+		usecase.AuthorizationPage(cfg.OAuth.AuthorizationPage),
+		usecase.RedirectSchemes(cfg.OAuth.RedirectSchemes),
+		usecase.DeletionGracePeriod(cfg.Account.DeletionGracePeriod),
+		usecase.SignInLockout(cfg.SignIn.LockoutThreshold, cfg.SignIn.IPLockoutThreshold, cfg.SignIn.LockoutDuration),
+		usecase.PasswordPolicy(domain.PasswordPolicy{
+			MinLength:            cfg.Password.MinLength,
+			MinCharacterClasses:  cfg.Password.MinCharacterClasses,
+			MaxRepeatedChars:     cfg.Password.MaxRepeatedChars,
+			DisallowPersonalInfo: cfg.Password.DisallowPersonalInfo,
+			MinStrength:          cfg.Password.MinStrength,
+		}),
 	)
 
+	passwordHasher := domain.PasswordHasher{
+		Algorithm: cfg.Password.HashAlgorithm,
+		Argon2: domain.Argon2Params{
+			Memory:      cfg.Password.Argon2Memory,
+			Iterations:  cfg.Password.Argon2Iterations,
+			Parallelism: cfg.Password.Argon2Parallelism,
+		},
+		BcryptCost: cfg.Password.BcryptCost,
+		Pepper:     []byte(cfg.Password.Pepper),
+	}
+	if err = passwordHasher.Validate(); err != nil {
+		l.Fatal(fmt.Errorf("app - Run - passwordHasher.Validate: %w", err))
+	}
+	userUseCaseOptions = append(userUseCaseOptions, usecase.PasswordHashing(passwordHasher))
+
+	if len(cfg.SignIn.CodeKey) < domain.MinSignInCodeKeySize {
+		l.Fatal(fmt.Errorf("app - Run - sign in code key must be at least %d bytes", domain.MinSignInCodeKeySize))
+	}
+	userUseCaseOptions = append(userUseCaseOptions, usecase.SignInCodeKey([]byte(cfg.SignIn.CodeKey)))
+
+	if cfg.Password.BreachedCorpusPath != "" {
+		corpus, err := breached.New(cfg.Password.BreachedCorpusPath, cfg.Password.BreachedMinCount)
+		if err != nil {
+			l.Fatal(fmt.Errorf("app - Run - breached.New: %w", err))
+		}
+
+		userUseCaseOptions = append(userUseCaseOptions, usecase.RejectBreachedPasswords(corpus))
+	}
+
+	userUseCase := usecase.New(
+		repo.NewUserRepository(pg),
+		repo.NewSessionRepository(pg),
+		repo.NewRecoveryCodeRepository(pg),
+		repo.NewPasskeyRepository(pg),
+		repo.NewExternalIdentityRepository(pg),
+		repo.NewOAuthRepository(pg),
+		repo.NewDataExportRepository(pg),
+		repo.NewSignInFailureRepository(pg),
+		repo.NewRoleRepository(pg),
+		repo.NewAuditRepository(pg),
+		repo.NewTransactor(pg),
+		mailer,
+		fileStorage,
+		userUseCaseOptions...,
+	)
+
+	// First administrator
+	err = userUseCase.BootstrapAdmin(context.Background(), cfg.Admin.BootstrapEmail)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		l.Warn("app - Run - sign up and verify %s, then restart to make it the first admin", cfg.Admin.BootstrapEmail)
+	} else if err != nil {
+		l.Fatal(fmt.Errorf("app - Run - userUseCase.BootstrapAdmin: %w", err))
+	}
+
+	// Background jobs
+	stopJobs := make(chan struct{})
+	go runPeriodically("PurgeDeletedAccounts", userUseCase.PurgeDeletedAccounts, cfg.Account.PurgeInterval, l, stopJobs)
+	go runPeriodically("ProcessDataExports", userUseCase.ProcessDataExports, cfg.Account.DataExportInterval, l, stopJobs)
+
+	// Rate limits
+	var rateLimitStore ratelimit.Store
+	switch cfg.RateLimit.Store {
+	case "memory":
+		rateLimitStore = ratelimit.NewMemoryStore()
+	case "postgres":
+		pgStore := ratelimit.NewPostgresStore(pg)
+		go runPeriodically("PruneRateLimits", pgStore.Prune, cfg.RateLimit.PruneInterval, l, stopJobs)
+		rateLimitStore = pgStore
+	default:
+		l.Fatal(fmt.Errorf("app - Run - unknown rate limit store: %s", cfg.RateLimit.Store))
+	}
+
+	rateLimits := make(ratelimit.Routes)
+	for _, p := range cfg.RateLimit.Policies {
+		name := fmt.Sprintf("%s %s %s", p.Route, p.Algorithm, p.Period)
+		limiter, err := ratelimit.New(name, ratelimit.Policy{
+			Algorithm: ratelimit.Algorithm(p.Algorithm),
+			Limit:     p.Limit,
+			Period:    p.Period,
+		}, rateLimitStore)
+		if err != nil {
+			l.Fatal(fmt.Errorf("app - Run - ratelimit.New: %w", err))
+		}
+
+		err = rateLimits.Add(p.Route, ratelimit.Rule{Key: p.Key, Limiter: limiter})
+		if err != nil {
+			l.Fatal(fmt.Errorf("app - Run - rateLimits.Add: %w", err))
+		}
+	}
+
 	// RabbitMQ RPC Server
-	rmqRouter := amqprpc.NewRouter(translationUseCase)
+	rmqRouter := amqprpc.NewRouter(userUseCase, rateLimits)
 
 	rmqServer, err := server.New(cfg.RMQ.URL, cfg.RMQ.ServerExchange, rmqRouter, l)
 	if err != nil {
@@ -46,7 +208,10 @@ func Run(cfg *config.Config) {
 
 	// HTTP Server
 	handler := gin.New()
-	v1.NewRouter(handler, l, translationUseCase)
+	if err = handler.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		l.Fatal(fmt.Errorf("app - Run - handler.SetTrustedProxies: %w", err))
+	}
+	v1.NewRouter(handler, l, userUseCase, keyring, rateLimits, cfg.HTTP.CookieAuth)
 	httpServer := httpserver.New(handler, httpserver.Port(cfg.HTTP.Port))
 
 	// Waiting signal
@@ -63,6 +228,8 @@ func Run(cfg *config.Config) {
 	}
 
 	// Shutdown
+	close(stopJobs)
+
 	err = httpServer.Shutdown()
 	if err != nil {
 		l.Error(fmt.Errorf("app - Run - httpServer.Shutdown: %w", err))
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PanziApp/backend/pkg/logger"
+)
+
+// runPeriodically runs the job right away and then every interval, until stop
+// is closed. The job returns how many items it handled.
+func runPeriodically(
+	name string,
+	job func(context.Context) (int, error),
+	interval time.Duration,
+	l logger.Interface,
+	stop <-chan struct{},
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		handled, err := job(context.Background())
+		if err != nil {
+			l.Error(fmt.Errorf("app - runPeriodically - %s: %w", name, err))
+		} else if handled > 0 {
+			l.Info("app - runPeriodically - %s: handled %d", name, handled)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package amqprpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/streadway/amqp"
+
+	rmqrpc "github.com/PanziApp/backend/pkg/rabbitmq/rmq_rpc"
+	"github.com/PanziApp/backend/pkg/rabbitmq/rmq_rpc/server"
+	"github.com/PanziApp/backend/pkg/ratelimit"
+)
+
+type rateLimitResponse struct {
+	Limit      int `json:"limit"`
+	Reset      int `json:"reset"`
+	RetryAfter int `json:"retry_after"`
+}
+
+// rateLimited limits the calls of the route by its rules, which are named "rpc"
+// and the route like "rpc validateToken". The email and api_key keys are the
+// email and client_id fields of the request.
+func rateLimited(limits ratelimit.Routes, route string, handler server.CallHandler) server.CallHandler {
+	route = "rpc " + route
+	if len(limits[route]) == 0 {
+		return handler
+	}
+
+	return func(d *amqp.Delivery) (interface{}, error) {
+		var request struct {
+			Email    string `json:"email"`
+			ClientId string `json:"client_id"`
+		}
+		_ = json.Unmarshal(d.Body, &request)
+
+		r, err := limits.Check(context.Background(), route, func(kind string) string {
+			switch kind {
+			case ratelimit.KeyEmail:
+				return request.Email
+			case ratelimit.KeyAPIKey:
+				return request.ClientId
+			}
+
+			return ""
+		})
+		if err != nil {
+			// The route is closed rather than left unlimited while the store fails.
+			return nil, fmt.Errorf("amqp_rpc - rateLimited - limits.Check: %w", err)
+		}
+
+		if r.Limit > 0 && !r.Allowed {
+			return rateLimitResponse{
+				Limit:      r.Limit,
+				Reset:      int(math.Ceil(r.Reset.Seconds())),
+				RetryAfter: int(math.Ceil(r.RetryAfter.Seconds())),
+			}, rmqrpc.ErrTooManyRequests
+		}
+
+		return handler(d)
+	}
+}
@@ -3,13 +3,18 @@ package amqprpc
 import (
 	"github.com/PanziApp/backend/internal/usecase"
 	"github.com/PanziApp/backend/pkg/rabbitmq/rmq_rpc/server"
+	"github.com/PanziApp/backend/pkg/ratelimit"
 )
 
 // NewRouter -.
-func NewRouter(t usecase.Translation) map[string]server.CallHandler {
+func NewRouter(u usecase.UserUseCase, limits ratelimit.Routes) map[string]server.CallHandler {
 	routes := make(map[string]server.CallHandler)
 	{
-		newTranslationRoutes(routes, t)
+		newTokenRoutes(routes, u)
+	}
+
+	for route, handler := range routes {
+		routes[route] = rateLimited(limits, route, handler)
 	}
 
 	return routes
@@ -0,0 +1,83 @@
+package amqprpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/streadway/amqp"
+
+	"github.com/PanziApp/backend/internal/domain"
+	"github.com/PanziApp/backend/internal/usecase"
+	"github.com/PanziApp/backend/pkg/rabbitmq/rmq_rpc/server"
+)
+
+type tokenRoutes struct {
+	u usecase.UserUseCase
+}
+
+func newTokenRoutes(routes map[string]server.CallHandler, u usecase.UserUseCase) {
+	r := &tokenRoutes{u}
+	{
+		routes["validateToken"] = r.validateToken()
+	}
+}
+
+type validateTokenRequest struct {
+	ClientId     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	Token        string `json:"token"`
+}
+
+// validateTokenResponse is the introspection response of the HTTP API, Error
+// holds the OAuth error code when the service or request is rejected.
+type validateTokenResponse struct {
+	Active      bool     `json:"active"`
+	UserId      string   `json:"user_id,omitempty"`
+	ClientId    string   `json:"client_id,omitempty"`
+	TokenType   string   `json:"token_type,omitempty"`
+	Scopes      []string `json:"scopes,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	IssuedAt    int64    `json:"iat,omitempty"`
+	ExpiresAt   int64    `json:"exp,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+func (r *tokenRoutes) validateToken() server.CallHandler {
+	return func(d *amqp.Delivery) (interface{}, error) {
+		var request validateTokenRequest
+		if err := json.Unmarshal(d.Body, &request); err != nil || request.Token == "" {
+			return validateTokenResponse{Error: domain.ErrInvalidRequest.Code}, nil
+		}
+
+		t, err := r.u.IntrospectToken(context.Background(), request.ClientId, request.ClientSecret, request.Token)
+		var oauthErr domain.OAuthError
+		if errors.As(err, &oauthErr) {
+			return validateTokenResponse{Error: oauthErr.Code}, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("amqp_rpc - tokenRoutes - validateToken - r.u.IntrospectToken: %w", err)
+		}
+
+		if !t.Active {
+			return validateTokenResponse{}, nil
+		}
+
+		response := validateTokenResponse{
+			Active:    true,
+			UserId:    t.UserId,
+			ClientId:  t.ClientId,
+			TokenType: t.TokenType,
+			Scopes:    t.Scopes,
+			IssuedAt:  t.IssueTime.Unix(),
+		}
+		if t.ValidUntil != nil {
+			response.ExpiresAt = t.ValidUntil.Unix()
+		}
+		for _, p := range t.Permissions {
+			response.Permissions = append(response.Permissions, string(p))
+		}
+
+		return response, nil
+	}
+}
@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type deleteAccountRequest struct {
+	Password string `json:"password" binding:"required" example:"secret-password"`
+}
+
+// @Summary     Delete account
+// @Description Schedule the account of the current user for deletion, which needs the password. Every session is signed out and a link
+// @Description to cancel the deletion is mailed, the account and its data are purged once the grace period passes
+// @ID          delete-account
+// @Tags  	    user
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body deleteAccountRequest true "Password"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     429 {object} response
+// @Failure     500 {object} response
+// @Router      /users/delete [post]
+func (r *userRoutes) deleteAccount(c *gin.Context) {
+	var request deleteAccountRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - deleteAccount")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	err := r.u.DeleteAccount(c.Request.Context(), principal(c), request.Password)
+	if err != nil {
+		r.l.Error(err, "http - v1 - deleteAccount")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type cancelAccountDeletionRequest struct {
+	Token string `json:"token" binding:"required" example:"VGhpcyBpcyBub3QgYSByZWFsIHRva2VuLCBqdXN0IGFuIGV4YW1wbGU="`
+}
+
+// @Summary     Cancel account deletion
+// @Description Restore an account pending deletion using the token of the cancel link mailed when it was deleted
+// @ID          cancel-account-deletion
+// @Tags  	    user
+// @Accept      json
+// @Produce     json
+// @Param       request body cancelAccountDeletionRequest true "Account deletion cancel token"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /account-deletion/cancel [post]
+func (r *userRoutes) cancelAccountDeletion(c *gin.Context) {
+	var request cancelAccountDeletionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - cancelAccountDeletion")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	err := r.u.CancelAccountDeletion(c.Request.Context(), request.Token)
+	if err != nil {
+		r.l.Error(err, "http - v1 - cancelAccountDeletion")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,383 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PanziApp/backend/internal/domain"
+)
+
+type adminUserResponse struct {
+	Id              int64      `json:"id"                 example:"42"`
+	CreateTime      time.Time  `json:"create_time"        example:"2022-06-25T09:10:00Z"`
+	Email           string     `json:"email"              example:"user@example.com"`
+	EmailVerifyTime *time.Time `json:"email_verify_time"  example:"2022-06-25T09:12:00Z"`
+	PendingEmail    string     `json:"pending_email"      example:""`
+	Fullname        string     `json:"fullname"           example:"John Doe"`
+	MfaEnabled      bool       `json:"mfa_enabled"        example:"false"`
+	DisableTime     *time.Time `json:"disable_time"       example:"2022-09-24T10:00:00Z"`
+	DeleteTime      *time.Time `json:"delete_time"        example:"2022-10-24T10:00:00Z"`
+}
+
+func newAdminUserResponse(u domain.User) adminUserResponse {
+	return adminUserResponse{
+		Id:              int64(u.Id),
+		CreateTime:      u.CreateTime,
+		Email:           string(u.Email),
+		EmailVerifyTime: u.EmailVerifyTime,
+		PendingEmail:    string(u.PendingEmail),
+		Fullname:        string(u.Fullname),
+		MfaEnabled:      u.MfaEnabled(),
+		DisableTime:     u.DisableTime,
+		DeleteTime:      u.DeleteTime,
+	}
+}
+
+type searchUsersRequest struct {
+	Query         string     `form:"query"           binding:"max=255"`
+	CreatedAfter  *time.Time `form:"created_after"   time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedBefore *time.Time `form:"created_before"  time_format:"2006-01-02T15:04:05Z07:00"`
+	Cursor        int64      `form:"cursor"          binding:"min=0"`
+	Limit         uint64     `form:"limit"           binding:"max=100"`
+}
+
+type usersPageResponse struct {
+	Users []adminUserResponse `json:"users"`
+	// NextCursor is passed as the cursor of the next page, zero on the last page.
+	NextCursor int64 `json:"next_cursor"  example:"42"`
+}
+
+// @Summary     Search users
+// @Description Page through the users whose email or name contains the query, by id. Needs the users:read permission.
+// @Description The users returned are recorded in the audit log
+// @ID          search-users
+// @Tags  	    admin
+// @Produce     json
+// @Security    BearerAuth
+// @Param       query query string false "Text in the email or name"
+// @Param       created_after query string false "RFC 3339 time the users signed up after"
+// @Param       created_before query string false "RFC 3339 time the users signed up before"
+// @Param       cursor query int false "next_cursor of the previous page"
+// @Param       limit query int false "Users per page, 20 by default and at most 100"
+// @Success     200 {object} usersPageResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     403 {object} response
+// @Failure     500 {object} response
+// @Router      /admin/users [get]
+func (r *userRoutes) searchUsers(c *gin.Context) {
+	var request searchUsersRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		r.l.Error(err, "http - v1 - searchUsers")
+		errorResponse(c, http.StatusBadRequest, "invalid request")
+
+		return
+	}
+
+	page, err := r.u.SearchUsers(c.Request.Context(), principal(c), domain.UserSearch{
+		Query:         request.Query,
+		CreatedAfter:  request.CreatedAfter,
+		CreatedBefore: request.CreatedBefore,
+		AfterId:       domain.EntityId(request.Cursor),
+		Limit:         request.Limit,
+	})
+	if err != nil {
+		r.l.Error(err, "http - v1 - searchUsers")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	resp := usersPageResponse{
+		Users:      make([]adminUserResponse, 0, len(page.Users)),
+		NextCursor: int64(page.NextCursor),
+	}
+	for _, u := range page.Users {
+		resp.Users = append(resp.Users, newAdminUserResponse(u))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+type adminUserDetailsResponse struct {
+	adminUserResponse
+	Roles    []roleResponse    `json:"roles"`
+	Sessions []sessionResponse `json:"sessions"`
+}
+
+// @Summary     Get user
+// @Description Show a user with their roles and active sessions, needs the users:read permission. The view is recorded in the audit log
+// @ID          get-user
+// @Tags  	    admin
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "User id"
+// @Success     200 {object} adminUserDetailsResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     403 {object} response
+// @Failure     500 {object} response
+// @Router      /admin/users/{id} [get]
+func (r *userRoutes) getUser(c *gin.Context) {
+	userId, ok := entityIdParam(c, "id")
+	if !ok {
+		return
+	}
+
+	u, err := r.u.GetUser(c.Request.Context(), principal(c), userId)
+	if err != nil {
+		r.l.Error(err, "http - v1 - getUser")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, adminUserDetailsResponse{
+		adminUserResponse: newAdminUserResponse(u.User),
+		Roles:             newRolesResponse(u.Roles).Roles,
+		Sessions:          newSessionResponses(u.Sessions),
+	})
+}
+
+// @Summary     Verify user email
+// @Description Mark the email of a user as verified, for users who cannot receive the verification link. Needs the users:manage permission
+// @ID          force-verify-email
+// @Tags  	    admin
+// @Security    BearerAuth
+// @Param       id path int true "User id"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     403 {object} response
+// @Failure     500 {object} response
+// @Router      /admin/users/{id}/verify-email [post]
+func (r *userRoutes) forceVerifyEmail(c *gin.Context) {
+	userId, ok := entityIdParam(c, "id")
+	if !ok {
+		return
+	}
+
+	err := r.u.ForceVerifyEmail(c.Request.Context(), principal(c), userId)
+	if err != nil {
+		r.l.Error(err, "http - v1 - forceVerifyEmail")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary     Reset user password
+// @Description Mail a user a link to reset their password, needs the users:manage permission
+// @ID          send-user-reset-password-link
+// @Tags  	    admin
+// @Security    BearerAuth
+// @Param       id path int true "User id"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     403 {object} response
+// @Failure     500 {object} response
+// @Router      /admin/users/{id}/reset-password [post]
+func (r *userRoutes) sendUserResetPasswordLink(c *gin.Context) {
+	userId, ok := entityIdParam(c, "id")
+	if !ok {
+		return
+	}
+
+	err := r.u.SendUserResetPasswordLink(c.Request.Context(), principal(c), userId)
+	if err != nil {
+		r.l.Error(err, "http - v1 - sendUserResetPasswordLink")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type disableUserRequest struct {
+	Reason string `json:"reason"  binding:"max=255"  example:"Reported for spam"`
+}
+
+// @Summary     Disable user
+// @Description Stop a user from signing in and sign out all of their sessions, until they are enabled again. Needs the users:manage permission, admins cannot disable themselves
+// @ID          disable-user
+// @Tags  	    admin
+// @Accept      json
+// @Security    BearerAuth
+// @Param       id path int true "User id"
+// @Param       request body disableUserRequest false "Reason recorded in the audit log"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     403 {object} response
+// @Failure     500 {object} response
+// @Router      /admin/users/{id}/disable [post]
+func (r *userRoutes) disableUser(c *gin.Context) {
+	userId, ok := entityIdParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var request disableUserRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			r.l.Error(err, "http - v1 - disableUser")
+			errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+			return
+		}
+	}
+
+	err := r.u.DisableUser(c.Request.Context(), principal(c), userId, request.Reason)
+	if err != nil {
+		r.l.Error(err, "http - v1 - disableUser")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary     Enable user
+// @Description Let a disabled user sign in again, needs the users:manage permission
+// @ID          enable-user
+// @Tags  	    admin
+// @Security    BearerAuth
+// @Param       id path int true "User id"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     403 {object} response
+// @Failure     500 {object} response
+// @Router      /admin/users/{id}/enable [post]
+func (r *userRoutes) enableUser(c *gin.Context) {
+	userId, ok := entityIdParam(c, "id")
+	if !ok {
+		return
+	}
+
+	err := r.u.EnableUser(c.Request.Context(), principal(c), userId)
+	if err != nil {
+		r.l.Error(err, "http - v1 - enableUser")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type revokeUserSessionsResponse struct {
+	Revoked int `json:"revoked"  example:"3"`
+}
+
+// @Summary     Revoke user sessions
+// @Description Sign a user out everywhere and revoke every link mailed to them, needs the users:manage permission
+// @ID          revoke-user-sessions
+// @Tags  	    admin
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "User id"
+// @Success     200 {object} revokeUserSessionsResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     403 {object} response
+// @Failure     500 {object} response
+// @Router      /admin/users/{id}/revoke-sessions [post]
+func (r *userRoutes) revokeUserSessions(c *gin.Context) {
+	userId, ok := entityIdParam(c, "id")
+	if !ok {
+		return
+	}
+
+	revoked, err := r.u.RevokeUserSessions(c.Request.Context(), principal(c), userId)
+	if err != nil {
+		r.l.Error(err, "http - v1 - revokeUserSessions")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, revokeUserSessionsResponse{Revoked: revoked})
+}
+
+type auditLogRequest struct {
+	UserId int64  `form:"user_id"  binding:"min=0"`
+	Cursor int64  `form:"cursor"   binding:"min=0"`
+	Limit  uint64 `form:"limit"    binding:"max=200"`
+}
+
+type auditEntryResponse struct {
+	Id           int64     `json:"id"              example:"7"`
+	CreateTime   time.Time `json:"create_time"     example:"2022-09-24T10:00:00Z"`
+	ActorId      int64     `json:"actor_id"        example:"1"`
+	Action       string    `json:"action"          example:"user.disable"`
+	TargetUserId int64     `json:"target_user_id"  example:"42"`
+	Details      string    `json:"details"         example:"user@example.com, 2 sessions revoked: Reported for spam"`
+}
+
+type auditLogResponse struct {
+	Entries []auditEntryResponse `json:"entries"`
+	// NextCursor is passed as the cursor of the next page, zero on the last page.
+	NextCursor int64 `json:"next_cursor"  example:"7"`
+}
+
+// @Summary     List audit log
+// @Description Page through the actions admins took, including the user records they read, newest first. Needs the audit:read permission
+// @ID          list-audit-log
+// @Tags  	    admin
+// @Produce     json
+// @Security    BearerAuth
+// @Param       user_id query int false "Only list the actions taken on the user"
+// @Param       cursor query int false "next_cursor of the previous page"
+// @Param       limit query int false "Entries per page, 50 by default and at most 200"
+// @Success     200 {object} auditLogResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     403 {object} response
+// @Failure     500 {object} response
+// @Router      /admin/audit-log [get]
+func (r *userRoutes) listAuditLog(c *gin.Context) {
+	var request auditLogRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		r.l.Error(err, "http - v1 - listAuditLog")
+		errorResponse(c, http.StatusBadRequest, "invalid request")
+
+		return
+	}
+
+	page, err := r.u.ListAuditLog(c.Request.Context(), principal(c), domain.AuditSearch{
+		TargetUserId: domain.EntityId(request.UserId),
+		BeforeId:     domain.EntityId(request.Cursor),
+		Limit:        request.Limit,
+	})
+	if err != nil {
+		r.l.Error(err, "http - v1 - listAuditLog")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	resp := auditLogResponse{
+		Entries:    make([]auditEntryResponse, 0, len(page.Entries)),
+		NextCursor: int64(page.NextCursor),
+	}
+	for _, e := range page.Entries {
+		resp.Entries = append(resp.Entries, auditEntryResponse{
+			Id:           int64(e.Id),
+			CreateTime:   e.CreateTime,
+			ActorId:      int64(e.ActorId),
+			Action:       string(e.Action),
+			TargetUserId: int64(e.TargetUserId),
+			Details:      e.Details,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
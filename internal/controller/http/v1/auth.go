@@ -0,0 +1,117 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PanziApp/backend/internal/domain"
+	"github.com/PanziApp/backend/internal/usecase"
+	"github.com/PanziApp/backend/pkg/logger"
+)
+
+const (
+	// tokenCookieName is read when the request has no Authorization header and
+	// cookie authentication is enabled.
+	tokenCookieName = "token"
+	// csrfHeaderName must be sent with requests authenticated by the token
+	// cookie. Other sites cannot add it without a CORS preflight, so forms and
+	// links they make the browser send are not authenticated.
+	csrfHeaderName = "X-Requested-With"
+
+	principalContextKey   = "principal"
+	cookieTokenContextKey = "cookie_token"
+)
+
+// bearerToken returns the token of the "Authorization: Bearer <token>" header,
+// falling back to the token cookie accepted by cookieAuthMiddleware.
+func bearerToken(c *gin.Context) string {
+	const prefix = "Bearer "
+
+	h := c.GetHeader("Authorization")
+	if h == "" {
+		return c.GetString(cookieTokenContextKey)
+	}
+
+	if len(h) < len(prefix) || !strings.EqualFold(h[:len(prefix)], prefix) {
+		return ""
+	}
+
+	return h[len(prefix):]
+}
+
+// cookieAuthMiddleware accepts the token cookie of requests without an
+// Authorization header when enabled, provided they send the CSRF header.
+func cookieAuthMiddleware(enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := c.Cookie(tokenCookieName)
+		if !enabled || err != nil || token == "" || c.GetHeader("Authorization") != "" {
+			c.Next()
+
+			return
+		}
+
+		if c.GetHeader(csrfHeaderName) == "" {
+			errorResponse(c, http.StatusForbidden, "missing "+csrfHeaderName+" header")
+
+			return
+		}
+
+		c.Set(cookieTokenContextKey, token)
+		c.Next()
+	}
+}
+
+// authMiddleware resolves the principal of the request token and stores it in
+// the context, unless the rate limits of the route already did.
+func authMiddleware(u usecase.UserUseCase, l logger.Interface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get(principalContextKey); ok {
+			c.Next()
+
+			return
+		}
+
+		p, err := u.Authenticate(c.Request.Context(), bearerToken(c))
+		if err != nil {
+			l.Error(err, "http - v1 - authMiddleware")
+
+			if errors.Is(err, domain.ErrInvalidToken) {
+				c.Header("WWW-Authenticate", `Bearer realm="panzi"`)
+				errorResponse(c, http.StatusUnauthorized, "invalid token")
+
+				return
+			}
+
+			useCaseErrorResponse(c, err)
+
+			return
+		}
+
+		c.Set(principalContextKey, p)
+		c.Next()
+	}
+}
+
+// principal returns the principal stored by authMiddleware.
+func principal(c *gin.Context) usecase.Principal {
+	p, _ := c.MustGet(principalContextKey).(usecase.Principal)
+
+	return p
+}
+
+// RequirePermission aborts the requests of principals lacking one of the
+// permissions, it runs after authMiddleware.
+func RequirePermission(permissions ...domain.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := principal(c).Authorize(permissions...); err != nil {
+			errorResponse(c, http.StatusForbidden, err.Error())
+
+			return
+		}
+
+		c.Next()
+	}
+}
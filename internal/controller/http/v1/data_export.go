@@ -0,0 +1,72 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary     Request data export
+// @Description Queue an export of the personal data of the current user, a download link is mailed once the archive is built.
+// @Description An export can be requested once a day
+// @ID          request-data-export
+// @Tags  	    user
+// @Produce     json
+// @Security    BearerAuth
+// @Success     202
+// @Failure     401 {object} response
+// @Failure     429 {object} response
+// @Failure     500 {object} response
+// @Router      /users/data-export [post]
+func (r *userRoutes) requestDataExport(c *gin.Context) {
+	err := r.u.RequestDataExport(c.Request.Context(), principal(c))
+	if err != nil {
+		r.l.Error(err, "http - v1 - requestDataExport")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+type downloadDataExportRequest struct {
+	Token string `json:"token" form:"token" binding:"required" example:"VGhpcyBpcyBub3QgYSByZWFsIHRva2VuLCBqdXN0IGFuIGV4YW1wbGU="`
+}
+
+// @Summary     Download data export
+// @Description Download the zip archive of a data export with the token of the link mailed when it was built.
+// @Description The token is posted as JSON or as a form, so it stays out of urls and access logs
+// @ID          download-data-export
+// @Tags  	    user
+// @Accept      json,x-www-form-urlencoded
+// @Produce     application/zip
+// @Param       request body downloadDataExportRequest true "Data export download token"
+// @Success     200 {file} binary
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /data-export/download [post]
+func (r *userRoutes) downloadDataExport(c *gin.Context) {
+	var request downloadDataExportRequest
+	if err := c.ShouldBind(&request); err != nil {
+		r.l.Error(err, "http - v1 - downloadDataExport")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	archive, err := r.u.DownloadDataExport(c.Request.Context(), request.Token)
+	if err != nil {
+		r.l.Error(err, "http - v1 - downloadDataExport")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+	defer archive.Close()
+
+	c.DataFromReader(http.StatusOK, -1, "application/zip", archive, map[string]string{
+		"Content-Disposition": `attachment; filename="panzi-data-export.zip"`,
+		"Cache-Control":       "no-store",
+	})
+}
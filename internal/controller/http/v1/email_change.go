@@ -0,0 +1,115 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type requestEmailChangeRequest struct {
+	Email    string `json:"email"     binding:"required"  example:"new@example.com"`
+	Password string `json:"password"  binding:"required"  example:"secret-password"`
+}
+
+// @Summary     Change email
+// @Description Ask to change the email of the current user, which needs the password. A confirmation link is mailed to the new address
+// @Description and a cancel link to the current one, the email changes once confirmed
+// @ID          request-email-change
+// @Tags  	    user
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body requestEmailChangeRequest true "New email and password"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     429 {object} response
+// @Failure     500 {object} response
+// @Router      /users/email [post]
+func (r *userRoutes) requestEmailChange(c *gin.Context) {
+	var request requestEmailChangeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - requestEmailChange")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	err := r.u.RequestEmailChange(c.Request.Context(), principal(c), request.Password, request.Email)
+	if err != nil {
+		r.l.Error(err, "http - v1 - requestEmailChange")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type emailChangeTokenRequest struct {
+	Token string `json:"token" binding:"required" example:"VGhpcyBpcyBub3QgYSByZWFsIHRva2VuLCBqdXN0IGFuIGV4YW1wbGU="`
+}
+
+// @Summary     Confirm email change
+// @Description Change the email to the new address using the token of the confirmation link mailed to it
+// @ID          confirm-email-change
+// @Tags  	    user
+// @Accept      json
+// @Produce     json
+// @Param       request body emailChangeTokenRequest true "Email change confirmation token"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /email/confirm [post]
+func (r *userRoutes) confirmEmailChange(c *gin.Context) {
+	var request emailChangeTokenRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - confirmEmailChange")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	err := r.u.ConfirmEmailChange(c.Request.Context(), request.Token)
+	if err != nil {
+		r.l.Error(err, "http - v1 - confirmEmailChange")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary     Cancel email change
+// @Description Cancel a pending email change using the token of the cancel link mailed to the current address
+// @ID          cancel-email-change
+// @Tags  	    user
+// @Accept      json
+// @Produce     json
+// @Param       request body emailChangeTokenRequest true "Email change cancel token"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /email/cancel [post]
+func (r *userRoutes) cancelEmailChange(c *gin.Context) {
+	var request emailChangeTokenRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - cancelEmailChange")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	err := r.u.CancelEmailChange(c.Request.Context(), request.Token)
+	if err != nil {
+		r.l.Error(err, "http - v1 - cancelEmailChange")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -1,7 +1,14 @@
 package v1
 
 import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
+
+	"github.com/PanziApp/backend/internal/domain"
 )
 
 type response struct {
@@ -11,3 +18,70 @@ type response struct {
 func errorResponse(c *gin.Context, code int, msg string) {
 	c.AbortWithStatusJSON(code, response{msg})
 }
+
+// useCaseErrorResponse maps use case errors to http responses.
+func useCaseErrorResponse(c *gin.Context, err error) {
+	var (
+		validationErr domain.ValidationError
+		serviceErr    domain.ServiceError
+		rateLimitErr  domain.RateLimitError
+		oauthErr      domain.OAuthError
+	)
+
+	switch {
+	case errors.As(err, &rateLimitErr):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))))
+		errorResponse(c, http.StatusTooManyRequests, rateLimitErr.Err.Error())
+	case errors.Is(err, domain.ErrInvalidToken):
+		errorResponse(c, http.StatusUnauthorized, "invalid token")
+	case errors.Is(err, domain.ErrPermissionDenied):
+		errorResponse(c, http.StatusForbidden, domain.ErrPermissionDenied.Error())
+	case errors.Is(err, domain.ErrInvalidPassword):
+		errorResponse(c, http.StatusUnauthorized, domain.ErrInvalidPassword.Err.Error())
+	case errors.As(err, &oauthErr):
+		errorResponse(c, oauthErrorStatus(oauthErr), oauthErr.Description)
+	case errors.As(err, &validationErr):
+		errorResponse(c, http.StatusBadRequest, validationErr.Err.Error())
+	case errors.As(err, &serviceErr):
+		errorResponse(c, http.StatusServiceUnavailable, "service problems")
+	default:
+		errorResponse(c, http.StatusInternalServerError, "internal problems")
+	}
+}
+
+type oauthErrorResponse struct {
+	Error            string `json:"error"              example:"invalid_grant"`
+	ErrorDescription string `json:"error_description"  example:"grant is invalid, expired or revoked"`
+}
+
+func oauthErrorStatus(err domain.OAuthError) int {
+	if err.Code == domain.ErrInvalidClient.Code {
+		return http.StatusUnauthorized
+	}
+
+	return http.StatusBadRequest
+}
+
+// oauthUseCaseErrorResponse maps use case errors to the error responses of
+// the OAuth protocol endpoints, RFC 6749 section 5.2.
+func oauthUseCaseErrorResponse(c *gin.Context, err error) {
+	var (
+		oauthErr      domain.OAuthError
+		validationErr domain.ValidationError
+		serviceErr    domain.ServiceError
+	)
+
+	switch {
+	case errors.As(err, &oauthErr):
+		if oauthErr.Code == domain.ErrInvalidClient.Code {
+			c.Header("WWW-Authenticate", `Basic realm="panzi"`)
+		}
+		c.AbortWithStatusJSON(oauthErrorStatus(oauthErr), oauthErrorResponse{oauthErr.Code, oauthErr.Description})
+	case errors.As(err, &validationErr):
+		c.AbortWithStatusJSON(http.StatusBadRequest, oauthErrorResponse{"invalid_request", validationErr.Err.Error()})
+	case errors.As(err, &serviceErr):
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, oauthErrorResponse{"temporarily_unavailable", "service problems"})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, oauthErrorResponse{"server_error", "internal problems"})
+	}
+}
@@ -0,0 +1,241 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type externalAuthorizationResponse struct {
+	AuthorizationURL string `json:"authorization_url" example:"https://accounts.example.com/authorize?client_id=panzi&state=..."`
+}
+
+type externalLinkPendingResponse struct {
+	// Email is the address the link to confirm linking the account was mailed to.
+	Email string `json:"email"  example:"user@example.com"`
+}
+
+type externalIdentityResponse struct {
+	Id          string     `json:"id"             example:"1"`
+	Provider    string     `json:"provider"       example:"google"`
+	Email       string     `json:"email"          example:"user@example.com"`
+	LinkTime    time.Time  `json:"link_time"      example:"2022-07-23T10:00:00Z"`
+	LastUseTime *time.Time `json:"last_use_time"  example:"2022-07-24T08:30:00Z"`
+}
+
+type externalIdentitiesResponse struct {
+	Identities []externalIdentityResponse `json:"identities"`
+}
+
+// @Summary     External sign in
+// @Description Start signing in with an external OpenID Connect provider, send the user to the returned URL
+// @ID          external-sign-in
+// @Tags  	    external
+// @Produce     json
+// @Param       provider path string true "Provider name"
+// @Success     200 {object} externalAuthorizationResponse
+// @Failure     400 {object} response
+// @Failure     500 {object} response
+// @Failure     503 {object} response
+// @Router      /sign-in/external/{provider} [post]
+func (r *userRoutes) externalSignIn(c *gin.Context) {
+	authURL, err := r.u.BeginExternalSignIn(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - externalSignIn")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, externalAuthorizationResponse{AuthorizationURL: authURL})
+}
+
+type externalCallbackRequest struct {
+	Code       string `json:"code"         binding:"required"  example:"SplxlOBeZQQYbYS6WxSbIA"`
+	State      string `json:"state"        binding:"required"  example:"VGhpcyBpcyBub3QgYSByZWFsIHRva2VuLCBqdXN0IGFuIGV4YW1wbGU="`
+	DeviceName string `json:"device_name"  binding:"max=100"   example:"Work laptop"`
+}
+
+// @Summary     External sign in callback
+// @Description Finish an external sign in with the code and state the provider redirected back with.
+// @Description When the provider account has the email of an existing user, 202 is returned and the user is mailed a link token to confirm with /sign-in/external-link/confirm.
+// @Description When the sign in was started to link an account, 204 is returned once it is linked.
+// @ID          external-sign-in-callback
+// @Tags  	    external
+// @Accept      json
+// @Produce     json
+// @Param       provider path string true "Provider name"
+// @Param       request body externalCallbackRequest true "Authorization code and state"
+// @Success     200 {object} tokensResponse
+// @Success     202 {object} externalLinkPendingResponse
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Failure     503 {object} response
+// @Router      /sign-in/external/{provider}/callback [post]
+func (r *userRoutes) externalSignInCallback(c *gin.Context) {
+	var request externalCallbackRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - externalSignInCallback")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	result, err := r.u.FinishExternalSignIn(
+		c.Request.Context(),
+		c.Param("provider"),
+		request.State,
+		request.Code,
+		sessionMeta(c, request.DeviceName),
+	)
+	if err != nil {
+		r.l.Error(err, "http - v1 - externalSignInCallback")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	switch {
+	case result.Linked:
+		c.Status(http.StatusNoContent)
+	case result.LinkPending:
+		c.JSON(http.StatusAccepted, externalLinkPendingResponse{Email: result.LinkEmail})
+	default:
+		signInResponse(c, result.SignIn)
+	}
+}
+
+type confirmExternalLinkRequest struct {
+	LinkToken  string `json:"link_token"   binding:"required"  example:"VGhpcyBpcyBub3QgYSByZWFsIHRva2VuLCBqdXN0IGFuIGV4YW1wbGU="`
+	DeviceName string `json:"device_name"  binding:"max=100"   example:"Work laptop"`
+}
+
+// @Summary     Confirm external link
+// @Description Link the provider account to the existing user with the same email and sign in, with the link token mailed to the user
+// @ID          confirm-external-link
+// @Tags  	    external
+// @Accept      json
+// @Produce     json
+// @Param       request body confirmExternalLinkRequest true "Link token"
+// @Success     200 {object} tokensResponse
+// @Success     202 {object} mfaChallengeResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /sign-in/external-link/confirm [post]
+func (r *userRoutes) confirmExternalLink(c *gin.Context) {
+	var request confirmExternalLinkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - confirmExternalLink")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	result, err := r.u.ConfirmExternalLink(c.Request.Context(), request.LinkToken, sessionMeta(c, request.DeviceName))
+	if err != nil {
+		r.l.Error(err, "http - v1 - confirmExternalLink")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	signInResponse(c, result)
+}
+
+// @Summary     List external identities
+// @Description Show the provider accounts linked to the current user
+// @ID          list-external-identities
+// @Tags  	    external
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200 {object} externalIdentitiesResponse
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /users/external-identities [get]
+func (r *userRoutes) listExternalIdentities(c *gin.Context) {
+	identities, err := r.u.ListExternalIdentities(c.Request.Context(), principal(c))
+	if err != nil {
+		r.l.Error(err, "http - v1 - listExternalIdentities")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	resp := externalIdentitiesResponse{Identities: make([]externalIdentityResponse, 0, len(identities))}
+	for _, i := range identities {
+		resp.Identities = append(resp.Identities, externalIdentityResponse{
+			Id:          i.Id,
+			Provider:    i.Provider,
+			Email:       i.Email,
+			LinkTime:    i.LinkTime,
+			LastUseTime: i.LastUseTime,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+type linkExternalIdentityRequest struct {
+	Provider string `json:"provider" binding:"required" example:"google"`
+}
+
+// @Summary     Link external identity
+// @Description Start linking a provider account to the current user, send the user to the returned URL
+// @ID          link-external-identity
+// @Tags  	    external
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body linkExternalIdentityRequest true "Provider name"
+// @Success     200 {object} externalAuthorizationResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Failure     503 {object} response
+// @Router      /users/external-identities [post]
+func (r *userRoutes) linkExternalIdentity(c *gin.Context) {
+	var request linkExternalIdentityRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - linkExternalIdentity")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	authURL, err := r.u.BeginExternalLink(c.Request.Context(), principal(c), request.Provider)
+	if err != nil {
+		r.l.Error(err, "http - v1 - linkExternalIdentity")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, externalAuthorizationResponse{AuthorizationURL: authURL})
+}
+
+// @Summary     Unlink external identity
+// @Description Remove a provider account from the current user
+// @ID          unlink-external-identity
+// @Tags  	    external
+// @Security    BearerAuth
+// @Param       id path string true "External identity id"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /users/external-identities/{id}/unlink [post]
+func (r *userRoutes) unlinkExternalIdentity(c *gin.Context) {
+	err := r.u.UnlinkExternalIdentity(c.Request.Context(), principal(c), c.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - unlinkExternalIdentity")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
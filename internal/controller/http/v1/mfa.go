@@ -0,0 +1,188 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type verifyMfaRequest struct {
+	MfaToken string `json:"mfa_token"  binding:"required"         example:"TWZhIGNoYWxsZW5nZSB0b2tlbiwganVzdCBhbiBleGFtcGxl"`
+	Code     string `json:"code"       binding:"required,max=20"  example:"123456"`
+}
+
+// @Summary     Verify two-factor authentication
+// @Description Complete the sign in challenge with an authenticator app code or a recovery code
+// @ID          verify-mfa
+// @Tags  	    mfa
+// @Accept      json
+// @Produce     json
+// @Param       request body verifyMfaRequest true "Challenge token and code"
+// @Success     200 {object} tokensResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /sign-in/mfa [post]
+func (r *userRoutes) verifyMfa(c *gin.Context) {
+	var request verifyMfaRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - verifyMfa")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	tokens, err := r.u.VerifyMfa(c.Request.Context(), request.MfaToken, request.Code)
+	if err != nil {
+		r.l.Error(err, "http - v1 - verifyMfa")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, newTokensResponse(tokens))
+}
+
+type totpEnrollmentResponse struct {
+	Secret string `json:"secret"  example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	URI    string `json:"uri"     example:"otpauth://totp/Panzi:user@example.com?secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP&issuer=Panzi"`
+}
+
+// @Summary     Begin TOTP enrollment
+// @Description Generate the secret of an authenticator app, two-factor authentication is enabled once a code of it is confirmed
+// @ID          begin-totp-enrollment
+// @Tags  	    mfa
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200 {object} totpEnrollmentResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /users/mfa/totp [post]
+func (r *userRoutes) beginTotpEnrollment(c *gin.Context) {
+	e, err := r.u.BeginTotpEnrollment(c.Request.Context(), principal(c))
+	if err != nil {
+		r.l.Error(err, "http - v1 - beginTotpEnrollment")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, totpEnrollmentResponse{
+		Secret: e.Secret,
+		URI:    e.URI,
+	})
+}
+
+type confirmTotpEnrollmentRequest struct {
+	Code string `json:"code" binding:"required,max=20" example:"123456"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"k3m9x-p2q7w,7hf4d-c8n2v"`
+}
+
+// @Summary     Confirm TOTP enrollment
+// @Description Enable two-factor authentication with a code of the authenticator app, the recovery codes are shown only once
+// @ID          confirm-totp-enrollment
+// @Tags  	    mfa
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body confirmTotpEnrollmentRequest true "Authenticator app code"
+// @Success     200 {object} recoveryCodesResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /users/mfa/totp/confirm [post]
+func (r *userRoutes) confirmTotpEnrollment(c *gin.Context) {
+	var request confirmTotpEnrollmentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - confirmTotpEnrollment")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	codes, err := r.u.ConfirmTotpEnrollment(c.Request.Context(), principal(c), request.Code)
+	if err != nil {
+		r.l.Error(err, "http - v1 - confirmTotpEnrollment")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+type passwordConfirmationRequest struct {
+	Password string `json:"password" binding:"required" example:"secret-password"`
+}
+
+// @Summary     Regenerate recovery codes
+// @Description Replace the recovery codes of the current user, the previous ones stop working
+// @ID          regenerate-recovery-codes
+// @Tags  	    mfa
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body passwordConfirmationRequest true "Current password"
+// @Success     200 {object} recoveryCodesResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     429 {object} response
+// @Failure     500 {object} response
+// @Router      /users/mfa/recovery-codes [post]
+func (r *userRoutes) regenerateRecoveryCodes(c *gin.Context) {
+	var request passwordConfirmationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - regenerateRecoveryCodes")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	codes, err := r.u.RegenerateRecoveryCodes(c.Request.Context(), principal(c), request.Password)
+	if err != nil {
+		r.l.Error(err, "http - v1 - regenerateRecoveryCodes")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// @Summary     Disable two-factor authentication
+// @Description Remove the authenticator app and the recovery codes of the current user
+// @ID          disable-mfa
+// @Tags  	    mfa
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body passwordConfirmationRequest true "Current password"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     429 {object} response
+// @Failure     500 {object} response
+// @Router      /users/mfa/disable [post]
+func (r *userRoutes) disableMfa(c *gin.Context) {
+	var request passwordConfirmationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - disableMfa")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	err := r.u.DisableMfa(c.Request.Context(), principal(c), request.Password)
+	if err != nil {
+		r.l.Error(err, "http - v1 - disableMfa")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,587 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PanziApp/backend/internal/domain"
+	"github.com/PanziApp/backend/internal/usecase"
+	"github.com/PanziApp/backend/pkg/jwt"
+)
+
+type oauthClientResponse struct {
+	ClientId     string    `json:"client_id"                example:"Y2xpZW50LWlkLWV4YW1wbGU"`
+	ClientSecret string    `json:"client_secret,omitempty"  example:"c2VjcmV0LW9ubHktc2hvd24tb25jZQ"`
+	Name         string    `json:"name"                     example:"Panzi Notes"`
+	Confidential bool      `json:"confidential"             example:"true"`
+	RedirectURIs []string  `json:"redirect_uris"            example:"https://notes.example.com/callback"`
+	GrantTypes   []string  `json:"grant_types"              example:"authorization_code,refresh_token"`
+	Scopes       []string  `json:"scopes"                   example:"openid,email"`
+	Trusted      bool      `json:"trusted"                  example:"false"`
+	CreateTime   time.Time `json:"create_time"              example:"2022-07-30T10:00:00Z"`
+}
+
+func newOAuthClientResponse(c usecase.OAuthClientDTO) oauthClientResponse {
+	return oauthClientResponse{
+		ClientId:     c.ClientId,
+		ClientSecret: c.ClientSecret,
+		Name:         c.Name,
+		Confidential: c.Confidential,
+		RedirectURIs: c.RedirectURIs,
+		GrantTypes:   c.GrantTypes,
+		Scopes:       c.Scopes,
+		Trusted:      c.Trusted,
+		CreateTime:   c.CreateTime,
+	}
+}
+
+type oauthClientsResponse struct {
+	Clients []oauthClientResponse `json:"clients"`
+}
+
+type registerOAuthClientRequest struct {
+	Name         string   `json:"name"           binding:"required,max=100"  example:"Panzi Notes"`
+	Confidential bool     `json:"confidential"                               example:"true"`
+	Trusted      bool     `json:"trusted"                                    example:"false"`
+	RedirectURIs []string `json:"redirect_uris"                              example:"https://notes.example.com/callback"`
+	GrantTypes   []string `json:"grant_types"    binding:"required"          example:"authorization_code,refresh_token"`
+	Scopes       []string `json:"scopes"         binding:"required"          example:"openid,email"`
+}
+
+// @Summary     Register OAuth client
+// @Description Register an application to sign users in with their Panzi account, or to call other services with client credentials.
+// @Description Redirect uris must use https, http on the loopback interface, or a private-use scheme allowed by the server.
+// @Description The secret of confidential clients is only returned now, public clients must use PKCE.
+// @Description Trusted clients, which may introspect tokens, and client_credentials clients need the oauth_clients:manage permission
+// @ID          register-oauth-client
+// @Tags  	    oauth
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body registerOAuthClientRequest true "Client metadata"
+// @Success     200 {object} oauthClientResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     403 {object} response
+// @Failure     500 {object} response
+// @Router      /users/oauth-clients [post]
+func (r *userRoutes) registerOAuthClient(c *gin.Context) {
+	var request registerOAuthClientRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - registerOAuthClient")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	client, err := r.u.RegisterOAuthClient(
+		c.Request.Context(),
+		principal(c),
+		request.Name,
+		request.Confidential,
+		request.Trusted,
+		request.RedirectURIs,
+		request.GrantTypes,
+		request.Scopes,
+	)
+	if err != nil {
+		r.l.Error(err, "http - v1 - registerOAuthClient")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, newOAuthClientResponse(client))
+}
+
+// @Summary     List OAuth clients
+// @Description Show the OAuth clients registered by the current user
+// @ID          list-oauth-clients
+// @Tags  	    oauth
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200 {object} oauthClientsResponse
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /users/oauth-clients [get]
+func (r *userRoutes) listOAuthClients(c *gin.Context) {
+	clients, err := r.u.ListOAuthClients(c.Request.Context(), principal(c))
+	if err != nil {
+		r.l.Error(err, "http - v1 - listOAuthClients")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	resp := oauthClientsResponse{Clients: make([]oauthClientResponse, 0, len(clients))}
+	for _, client := range clients {
+		resp.Clients = append(resp.Clients, newOAuthClientResponse(client))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// @Summary     Delete OAuth client
+// @Description Delete an OAuth client of the current user, the tokens issued to it stop working
+// @ID          delete-oauth-client
+// @Tags  	    oauth
+// @Security    BearerAuth
+// @Param       client_id path string true "Client id"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /users/oauth-clients/{client_id}/delete [post]
+func (r *userRoutes) deleteOAuthClient(c *gin.Context) {
+	err := r.u.DeleteOAuthClient(c.Request.Context(), principal(c), c.Param("client_id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - deleteOAuthClient")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type authorizationRequest struct {
+	ResponseType        string `json:"response_type"          form:"response_type"          example:"code"`
+	ClientId            string `json:"client_id"              form:"client_id"              example:"Y2xpZW50LWlkLWV4YW1wbGU"`
+	RedirectURI         string `json:"redirect_uri"           form:"redirect_uri"           example:"https://notes.example.com/callback"`
+	Scope               string `json:"scope"                  form:"scope"                  example:"openid email"`
+	State               string `json:"state"                  form:"state"                  example:"af0ifjsldkj"`
+	Nonce               string `json:"nonce"                  form:"nonce"                  example:"n-0S6_WzA2Mj"`
+	CodeChallenge       string `json:"code_challenge"         form:"code_challenge"         example:"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"`
+	CodeChallengeMethod string `json:"code_challenge_method"  form:"code_challenge_method"  example:"S256"`
+}
+
+func (a authorizationRequest) toDTO() usecase.AuthorizationRequestDTO {
+	return usecase.AuthorizationRequestDTO{
+		ResponseType:        a.ResponseType,
+		ClientId:            a.ClientId,
+		RedirectURI:         a.RedirectURI,
+		Scope:               a.Scope,
+		State:               a.State,
+		Nonce:               a.Nonce,
+		CodeChallenge:       a.CodeChallenge,
+		CodeChallengeMethod: a.CodeChallengeMethod,
+	}
+}
+
+type oauthConsentResponse struct {
+	ClientId   string   `json:"client_id"    example:"Y2xpZW50LWlkLWV4YW1wbGU"`
+	ClientName string   `json:"client_name"  example:"Panzi Notes"`
+	Scopes     []string `json:"scopes"       example:"email,openid"`
+	Granted    bool     `json:"granted"      example:"false"`
+}
+
+// @Summary     OAuth consent
+// @Description Validate the authorization request the authorization page was opened with, and show what the user is asked to authorize.
+// @Description When granted is true the user already consented to the scopes and the page may authorize the client without asking
+// @ID          oauth-consent
+// @Tags  	    oauth
+// @Produce     json
+// @Security    BearerAuth
+// @Param       response_type query string true "Must be code"
+// @Param       client_id query string true "Client id"
+// @Param       redirect_uri query string false "Registered redirect uri, optional when the client has only one"
+// @Param       scope query string true "Space separated scopes"
+// @Param       state query string false "Opaque value returned to the client"
+// @Param       nonce query string false "Value copied to the ID token"
+// @Param       code_challenge query string false "PKCE code challenge, required for public clients"
+// @Param       code_challenge_method query string false "Must be S256"
+// @Success     200 {object} oauthConsentResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /oauth/authorize [get]
+func (r *userRoutes) getOAuthConsent(c *gin.Context) {
+	var request authorizationRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		r.l.Error(err, "http - v1 - getOAuthConsent")
+		errorResponse(c, http.StatusBadRequest, "invalid request")
+
+		return
+	}
+
+	consent, err := r.u.GetOAuthConsent(c.Request.Context(), principal(c), request.toDTO())
+	if err != nil {
+		r.l.Error(err, "http - v1 - getOAuthConsent")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, oauthConsentResponse{
+		ClientId:   consent.ClientId,
+		ClientName: consent.ClientName,
+		Scopes:     consent.Scopes,
+		Granted:    consent.Granted,
+	})
+}
+
+type authorizeOAuthClientRequest struct {
+	authorizationRequest
+	Approve bool `json:"approve" example:"true"`
+}
+
+type oauthRedirectResponse struct {
+	RedirectURI string `json:"redirect_uri" example:"https://notes.example.com/callback?code=SplxlOBeZQQYbYS6WxSbIA&state=af0ifjsldkj"`
+}
+
+// @Summary     Authorize OAuth client
+// @Description Record whether the user approved the authorization request, and return where to send them back to the client
+// @ID          authorize-oauth-client
+// @Tags  	    oauth
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body authorizeOAuthClientRequest true "Authorization request and decision"
+// @Success     200 {object} oauthRedirectResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /oauth/authorize [post]
+func (r *userRoutes) authorizeOAuthClient(c *gin.Context) {
+	var request authorizeOAuthClientRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - authorizeOAuthClient")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	redirectURI, err := r.u.AuthorizeOAuthClient(
+		c.Request.Context(),
+		principal(c),
+		request.toDTO(),
+		request.Approve,
+	)
+	if err != nil {
+		r.l.Error(err, "http - v1 - authorizeOAuthClient")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, oauthRedirectResponse{RedirectURI: redirectURI})
+}
+
+type oauthTokenRequest struct {
+	GrantType    string `form:"grant_type"     binding:"required"`
+	ClientId     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+}
+
+type oauthTokenResponse struct {
+	AccessToken  string `json:"access_token"             example:"VGhpcyBpcyBub3QgYSByZWFsIHRva2VuLCBqdXN0IGFuIGV4YW1wbGU="`
+	TokenType    string `json:"token_type"               example:"Bearer"`
+	ExpiresIn    int64  `json:"expires_in"               example:"900"`
+	RefreshToken string `json:"refresh_token,omitempty"  example:"VGhpcyBpcyBub3QgYSByZWFsIHRva2VuLCBqdXN0IGFuIGV4YW1wbGU="`
+	Scope        string `json:"scope"                    example:"openid email"`
+	IdToken      string `json:"id_token,omitempty"       example:"eyJhbGciOiJFZERTQSIsImtpZCI6ImtleS0xIiwidHlwIjoiSldUIn0..."`
+}
+
+// clientCredentials returns the credentials of the client_secret_basic
+// authentication, falling back to the client_secret_post parameters.
+func clientCredentials(c *gin.Context, clientId, clientSecret string) (string, string) {
+	id, secret, ok := c.Request.BasicAuth()
+	if !ok {
+		return clientId, clientSecret
+	}
+
+	// Both are form encoded before being joined, RFC 6749 section 2.3.1.
+	if decoded, err := url.QueryUnescape(id); err == nil {
+		id = decoded
+	}
+	if decoded, err := url.QueryUnescape(secret); err == nil {
+		secret = decoded
+	}
+
+	return id, secret
+}
+
+// @Summary     OAuth token
+// @Description Exchange an authorization code, a refresh token or the client credentials for tokens, RFC 6749.
+// @Description Confidential clients authenticate with HTTP Basic or the client_secret parameter, public clients send their client_id
+// @ID          oauth-token
+// @Tags  	    oauth
+// @Accept      x-www-form-urlencoded
+// @Produce     json
+// @Param       grant_type formData string true "authorization_code, refresh_token or client_credentials"
+// @Param       client_id formData string false "Client id, when not using HTTP Basic"
+// @Param       client_secret formData string false "Client secret, when not using HTTP Basic"
+// @Param       code formData string false "Authorization code"
+// @Param       redirect_uri formData string false "Redirect uri of the authorization request"
+// @Param       code_verifier formData string false "PKCE code verifier"
+// @Param       refresh_token formData string false "Refresh token"
+// @Param       scope formData string false "Space separated scopes"
+// @Success     200 {object} oauthTokenResponse
+// @Failure     400 {object} oauthErrorResponse
+// @Failure     401 {object} oauthErrorResponse
+// @Failure     500 {object} oauthErrorResponse
+// @Router      /oauth/token [post]
+func (r *userRoutes) oauthToken(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var request oauthTokenRequest
+	if err := c.ShouldBind(&request); err != nil {
+		r.l.Error(err, "http - v1 - oauthToken")
+		oauthUseCaseErrorResponse(c, domain.ErrInvalidRequest)
+
+		return
+	}
+
+	clientId, clientSecret := clientCredentials(c, request.ClientId, request.ClientSecret)
+
+	tokens, err := r.u.ExchangeOAuthToken(c.Request.Context(), usecase.TokenRequestDTO{
+		GrantType:    request.GrantType,
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		Code:         request.Code,
+		RedirectURI:  request.RedirectURI,
+		CodeVerifier: request.CodeVerifier,
+		RefreshToken: request.RefreshToken,
+		Scope:        request.Scope,
+	}, sessionMeta(c, ""))
+	if err != nil {
+		r.l.Error(err, "http - v1 - oauthToken")
+		oauthUseCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, oauthTokenResponse{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(time.Until(tokens.AccessTokenValidUntil).Round(time.Second).Seconds()),
+		RefreshToken: tokens.RefreshToken,
+		Scope:        tokens.Scope,
+		IdToken:      tokens.IdToken,
+	})
+}
+
+type revokeOAuthTokenRequest struct {
+	Token         string `form:"token"            binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientId      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// @Summary     Revoke OAuth token
+// @Description Revoke an access or refresh token of the client, RFC 7009. Revoking a refresh token revokes the access tokens of the grant too
+// @ID          revoke-oauth-token
+// @Tags  	    oauth
+// @Accept      x-www-form-urlencoded
+// @Produce     json
+// @Param       token formData string true "Access or refresh token"
+// @Param       token_type_hint formData string false "access_token or refresh_token, both are looked up either way"
+// @Param       client_id formData string false "Client id, when not using HTTP Basic"
+// @Param       client_secret formData string false "Client secret, when not using HTTP Basic"
+// @Success     200
+// @Failure     400 {object} oauthErrorResponse
+// @Failure     401 {object} oauthErrorResponse
+// @Failure     500 {object} oauthErrorResponse
+// @Router      /oauth/revoke [post]
+func (r *userRoutes) revokeOAuthToken(c *gin.Context) {
+	var request revokeOAuthTokenRequest
+	if err := c.ShouldBind(&request); err != nil {
+		r.l.Error(err, "http - v1 - revokeOAuthToken")
+		oauthUseCaseErrorResponse(c, domain.ErrInvalidRequest)
+
+		return
+	}
+
+	clientId, clientSecret := clientCredentials(c, request.ClientId, request.ClientSecret)
+
+	err := r.u.RevokeOAuthToken(c.Request.Context(), clientId, clientSecret, request.Token)
+	if err != nil {
+		r.l.Error(err, "http - v1 - revokeOAuthToken")
+		oauthUseCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+type introspectOAuthTokenRequest struct {
+	Token         string `form:"token"            binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientId      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+type tokenIntrospectionResponse struct {
+	Active      bool     `json:"active"                 example:"true"`
+	UserId      string   `json:"user_id,omitempty"      example:"42"`
+	ClientId    string   `json:"client_id,omitempty"    example:"Y2xpZW50LWlkLWV4YW1wbGU"`
+	TokenType   string   `json:"token_type,omitempty"   example:"access_token"`
+	Scope       string   `json:"scope,omitempty"        example:"openid email"`
+	Permissions []string `json:"permissions,omitempty"  example:"users:read"`
+	IssuedAt    int64    `json:"iat,omitempty"          example:"1659175200"`
+	ExpiresAt   int64    `json:"exp,omitempty"          example:"1659176100"`
+}
+
+func newTokenIntrospectionResponse(t usecase.TokenIntrospectionDTO) tokenIntrospectionResponse {
+	if !t.Active {
+		return tokenIntrospectionResponse{}
+	}
+
+	r := tokenIntrospectionResponse{
+		Active:    true,
+		UserId:    t.UserId,
+		ClientId:  t.ClientId,
+		TokenType: t.TokenType,
+		Scope:     domain.FormatScope(t.Scopes),
+		IssuedAt:  t.IssueTime.Unix(),
+	}
+	if t.ValidUntil != nil {
+		r.ExpiresAt = t.ValidUntil.Unix()
+	}
+	for _, p := range t.Permissions {
+		r.Permissions = append(r.Permissions, string(p))
+	}
+
+	return r
+}
+
+// @Summary     Introspect OAuth token
+// @Description Tell a service whether a token is active and what it grants, RFC 7662. Services authenticate with the credentials of a trusted client, registered by an admin.
+// @Description Access tokens are described to any service, refresh tokens only to the client they were issued to
+// @ID          introspect-oauth-token
+// @Tags  	    oauth
+// @Accept      x-www-form-urlencoded
+// @Produce     json
+// @Param       token formData string true "Access or refresh token"
+// @Param       token_type_hint formData string false "access_token or refresh_token, both are looked up either way"
+// @Param       client_id formData string false "Client id, when not using HTTP Basic"
+// @Param       client_secret formData string false "Client secret, when not using HTTP Basic"
+// @Success     200 {object} tokenIntrospectionResponse
+// @Failure     400 {object} oauthErrorResponse
+// @Failure     401 {object} oauthErrorResponse
+// @Failure     500 {object} oauthErrorResponse
+// @Router      /oauth/introspect [post]
+func (r *userRoutes) introspectOAuthToken(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+
+	var request introspectOAuthTokenRequest
+	if err := c.ShouldBind(&request); err != nil {
+		r.l.Error(err, "http - v1 - introspectOAuthToken")
+		oauthUseCaseErrorResponse(c, domain.ErrInvalidRequest)
+
+		return
+	}
+
+	clientId, clientSecret := clientCredentials(c, request.ClientId, request.ClientSecret)
+
+	introspection, err := r.u.IntrospectToken(c.Request.Context(), clientId, clientSecret, request.Token)
+	if err != nil {
+		r.l.Error(err, "http - v1 - introspectOAuthToken")
+		oauthUseCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, newTokenIntrospectionResponse(introspection))
+}
+
+type userInfoResponse struct {
+	Subject       string `json:"sub"                       example:"42"`
+	Email         string `json:"email,omitempty"           example:"user@example.com"`
+	EmailVerified *bool  `json:"email_verified,omitempty"  example:"true"`
+	Name          string `json:"name,omitempty"            example:"Jane Doe"`
+}
+
+// @Summary     OpenID Connect userinfo
+// @Description Show the claims about the user of an access token with the openid scope, the email and profile scopes add claims
+// @ID          oauth-userinfo
+// @Tags  	    oauth
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200 {object} userInfoResponse
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /oauth/userinfo [get]
+func (r *userRoutes) oauthUserInfo(c *gin.Context) {
+	info, err := r.u.GetUserInfo(c.Request.Context(), bearerToken(c))
+	if err != nil {
+		r.l.Error(err, "http - v1 - oauthUserInfo")
+
+		if errors.Is(err, domain.ErrInvalidToken) {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		}
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, userInfoResponse{
+		Subject:       info.Subject,
+		Email:         info.Email,
+		EmailVerified: info.EmailVerified,
+		Name:          info.Name,
+	})
+}
+
+type openIdConfigurationResponse struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IdTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// openIdConfiguration serves the OpenID Connect discovery document, the
+// endpoints are resolved against the issuer.
+func openIdConfiguration(u usecase.UserUseCase, keyring *jwt.Keyring) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m := u.OAuthServerMetadata()
+
+		var algorithms []string
+		if keys := keyring.JWKS().Keys; len(keys) > 0 {
+			// The active key comes first, it signs the ID tokens.
+			algorithms = []string{keys[0].Algorithm}
+		}
+
+		c.JSON(http.StatusOK, openIdConfigurationResponse{
+			Issuer:                            m.Issuer,
+			AuthorizationEndpoint:             m.AuthorizationPage,
+			TokenEndpoint:                     m.Issuer + "/v1/oauth/token",
+			UserInfoEndpoint:                  m.Issuer + "/v1/oauth/userinfo",
+			RevocationEndpoint:                m.Issuer + "/v1/oauth/revoke",
+			IntrospectionEndpoint:             m.Issuer + "/v1/oauth/introspect",
+			JwksURI:                           m.Issuer + "/.well-known/jwks.json",
+			ScopesSupported:                   m.Scopes,
+			ResponseTypesSupported:            []string{"code"},
+			GrantTypesSupported:               m.GrantTypes,
+			SubjectTypesSupported:             []string{"public"},
+			IdTokenSigningAlgValuesSupported:  algorithms,
+			TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+			CodeChallengeMethodsSupported:     []string{domain.PkceMethod},
+			ClaimsSupported: []string{
+				"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "email", "email_verified", "name",
+			},
+		})
+	}
+}
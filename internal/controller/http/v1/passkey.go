@@ -0,0 +1,228 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PanziApp/backend/internal/usecase"
+	"github.com/PanziApp/backend/pkg/webauthn"
+)
+
+type passkeyCreationOptionsResponse struct {
+	PublicKey webauthn.CreationOptions `json:"publicKey"`
+}
+
+type passkeyRequestOptionsResponse struct {
+	PublicKey webauthn.RequestOptions `json:"publicKey"`
+}
+
+type passkeyResponse struct {
+	Id          string     `json:"id"             example:"bWFkZS11cC1jcmVkZW50aWFsLWlk"`
+	Name        string     `json:"name"           example:"Work laptop"`
+	CreateTime  time.Time  `json:"create_time"    example:"2022-07-09T10:00:00Z"`
+	LastUseTime *time.Time `json:"last_use_time"  example:"2022-07-10T08:30:00Z"`
+}
+
+func newPasskeyResponse(p usecase.PasskeyDTO) passkeyResponse {
+	return passkeyResponse{
+		Id:          p.Id,
+		Name:        p.Name,
+		CreateTime:  p.CreateTime,
+		LastUseTime: p.LastUseTime,
+	}
+}
+
+type passkeysResponse struct {
+	Passkeys []passkeyResponse `json:"passkeys"`
+}
+
+type passkeySignInOptionsRequest struct {
+	Email string `json:"email" example:"user@example.com"`
+}
+
+// @Summary     Passkey sign in options
+// @Description Start a passkey sign in, pass the options to navigator.credentials.get. Without an email the discoverable passkeys are offered
+// @ID          passkey-sign-in-options
+// @Tags  	    passkey
+// @Accept      json
+// @Produce     json
+// @Param       request body passkeySignInOptionsRequest false "Email of the account"
+// @Success     200 {object} passkeyRequestOptionsResponse
+// @Failure     400 {object} response
+// @Failure     500 {object} response
+// @Router      /sign-in/passkey/options [post]
+func (r *userRoutes) passkeySignInOptions(c *gin.Context) {
+	var request passkeySignInOptionsRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			r.l.Error(err, "http - v1 - passkeySignInOptions")
+			errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+			return
+		}
+	}
+
+	options, err := r.u.BeginPasskeySignIn(c.Request.Context(), request.Email)
+	if err != nil {
+		r.l.Error(err, "http - v1 - passkeySignInOptions")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, passkeyRequestOptionsResponse{PublicKey: options})
+}
+
+type passkeySignInRequest struct {
+	Credential webauthn.AssertionResponse `json:"credential"`
+	DeviceName string                     `json:"device_name"  binding:"max=100"  example:"Work laptop"`
+}
+
+// @Summary     Sign in with passkey
+// @Description Finish a passkey sign in with the credential returned by navigator.credentials.get
+// @ID          passkey-sign-in
+// @Tags  	    passkey
+// @Accept      json
+// @Produce     json
+// @Param       request body passkeySignInRequest true "Assertion of the authenticator"
+// @Success     200 {object} tokensResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /sign-in/passkey [post]
+func (r *userRoutes) passkeySignIn(c *gin.Context) {
+	var request passkeySignInRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - passkeySignIn")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	tokens, err := r.u.FinishPasskeySignIn(
+		c.Request.Context(),
+		request.Credential,
+		sessionMeta(c, request.DeviceName),
+	)
+	if err != nil {
+		r.l.Error(err, "http - v1 - passkeySignIn")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, newTokensResponse(tokens))
+}
+
+// @Summary     List passkeys
+// @Description Show the passkeys of the current user
+// @ID          list-passkeys
+// @Tags  	    passkey
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200 {object} passkeysResponse
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /users/passkeys [get]
+func (r *userRoutes) listPasskeys(c *gin.Context) {
+	passkeys, err := r.u.ListPasskeys(c.Request.Context(), principal(c))
+	if err != nil {
+		r.l.Error(err, "http - v1 - listPasskeys")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	resp := passkeysResponse{Passkeys: make([]passkeyResponse, 0, len(passkeys))}
+	for _, p := range passkeys {
+		resp.Passkeys = append(resp.Passkeys, newPasskeyResponse(p))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// @Summary     Passkey registration options
+// @Description Start a passkey registration, pass the options to navigator.credentials.create
+// @ID          passkey-registration-options
+// @Tags  	    passkey
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200 {object} passkeyCreationOptionsResponse
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /users/passkeys/options [post]
+func (r *userRoutes) passkeyRegistrationOptions(c *gin.Context) {
+	options, err := r.u.BeginPasskeyRegistration(c.Request.Context(), principal(c))
+	if err != nil {
+		r.l.Error(err, "http - v1 - passkeyRegistrationOptions")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, passkeyCreationOptionsResponse{PublicKey: options})
+}
+
+type registerPasskeyRequest struct {
+	Name       string                       `json:"name"        binding:"max=100"  example:"Work laptop"`
+	Credential webauthn.AttestationResponse `json:"credential"`
+}
+
+// @Summary     Register passkey
+// @Description Finish a passkey registration with the credential returned by navigator.credentials.create
+// @ID          register-passkey
+// @Tags  	    passkey
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body registerPasskeyRequest true "Attestation of the authenticator"
+// @Success     200 {object} passkeyResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /users/passkeys [post]
+func (r *userRoutes) registerPasskey(c *gin.Context) {
+	var request registerPasskeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - registerPasskey")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	p, err := r.u.FinishPasskeyRegistration(c.Request.Context(), principal(c), request.Name, request.Credential)
+	if err != nil {
+		r.l.Error(err, "http - v1 - registerPasskey")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, newPasskeyResponse(p))
+}
+
+// @Summary     Delete passkey
+// @Description Remove the passkey with the id from the current user
+// @ID          delete-passkey
+// @Tags  	    passkey
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path string true "Passkey id"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /users/passkeys/{id}/delete [post]
+func (r *userRoutes) deletePasskey(c *gin.Context) {
+	err := r.u.DeletePasskey(c.Request.Context(), principal(c), c.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - deletePasskey")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
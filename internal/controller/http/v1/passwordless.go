@@ -0,0 +1,156 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type signInEmailRequest struct {
+	Email string `json:"email" binding:"required" example:"user@example.com"`
+}
+
+// @Summary     Send sign in link
+// @Description Email a single use link for signing in without a password
+// @ID          sign-in-link
+// @Tags  	    user
+// @Accept      json
+// @Produce     json
+// @Param       request body signInEmailRequest true "Account email"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     500 {object} response
+// @Router      /sign-in/link [post]
+func (r *userRoutes) sendSignInLink(c *gin.Context) {
+	var request signInEmailRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - sendSignInLink")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	err := r.u.SendSignInLink(c.Request.Context(), request.Email)
+	if err != nil {
+		r.l.Error(err, "http - v1 - sendSignInLink")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type redeemSignInLinkRequest struct {
+	Token      string `json:"token"        binding:"required"  example:"VGhpcyBpcyBub3QgYSByZWFsIHRva2VuLCBqdXN0IGFuIGV4YW1wbGU="`
+	DeviceName string `json:"device_name"  binding:"max=100"   example:"Work laptop"`
+}
+
+// @Summary     Sign in with link
+// @Description Sign in with the token of a sign in link, when two-factor authentication is enabled a challenge to complete with /sign-in/mfa is returned instead of the tokens
+// @ID          redeem-sign-in-link
+// @Tags  	    user
+// @Accept      json
+// @Produce     json
+// @Param       request body redeemSignInLinkRequest true "Sign in link token"
+// @Success     200 {object} tokensResponse
+// @Success     202 {object} mfaChallengeResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /sign-in/link/redeem [post]
+func (r *userRoutes) redeemSignInLink(c *gin.Context) {
+	var request redeemSignInLinkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - redeemSignInLink")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	result, err := r.u.RedeemSignInLink(c.Request.Context(), request.Token, sessionMeta(c, request.DeviceName))
+	if err != nil {
+		r.l.Error(err, "http - v1 - redeemSignInLink")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	signInResponse(c, result)
+}
+
+// @Summary     Send sign in code
+// @Description Email a single use 6-digit code for signing in without a password, for clients that cannot open links
+// @ID          sign-in-code
+// @Tags  	    user
+// @Accept      json
+// @Produce     json
+// @Param       request body signInEmailRequest true "Account email"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     500 {object} response
+// @Router      /sign-in/code [post]
+func (r *userRoutes) sendSignInCode(c *gin.Context) {
+	var request signInEmailRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - sendSignInCode")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	err := r.u.SendSignInCode(c.Request.Context(), request.Email)
+	if err != nil {
+		r.l.Error(err, "http - v1 - sendSignInCode")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type redeemSignInCodeRequest struct {
+	Email      string `json:"email"        binding:"required"        example:"user@example.com"`
+	Code       string `json:"code"         binding:"required,len=6"  example:"042817"`
+	DeviceName string `json:"device_name"  binding:"max=100"         example:"Work phone"`
+}
+
+// @Summary     Sign in with code
+// @Description Sign in with the last code emailed to the account, the code stops working after 5 failed attempts.
+// @Description After 10 failed codes within an hour, codes of the email are locked for an hour
+// @ID          redeem-sign-in-code
+// @Tags  	    user
+// @Accept      json
+// @Produce     json
+// @Param       request body redeemSignInCodeRequest true "Account email and code"
+// @Success     200 {object} tokensResponse
+// @Success     202 {object} mfaChallengeResponse
+// @Failure     400 {object} response
+// @Failure     429 {object} response
+// @Failure     500 {object} response
+// @Router      /sign-in/code/redeem [post]
+func (r *userRoutes) redeemSignInCode(c *gin.Context) {
+	var request redeemSignInCodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - redeemSignInCode")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	result, err := r.u.RedeemSignInCode(
+		c.Request.Context(),
+		request.Email,
+		request.Code,
+		sessionMeta(c, request.DeviceName),
+	)
+	if err != nil {
+		r.l.Error(err, "http - v1 - redeemSignInCode")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	signInResponse(c, result)
+}
@@ -0,0 +1,113 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PanziApp/backend/internal/domain"
+	"github.com/PanziApp/backend/internal/usecase"
+	"github.com/PanziApp/backend/pkg/logger"
+	"github.com/PanziApp/backend/pkg/ratelimit"
+)
+
+// rateLimitMiddleware limits the calls of the routes with rules, which are named
+// by the method and full path like "POST /v1/sign-in", and reports the limit of
+// the strictest rule in the RateLimit-* headers.
+func rateLimitMiddleware(routes ratelimit.Routes, u usecase.UserUseCase, l logger.Interface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		if len(routes[route]) == 0 {
+			c.Next()
+
+			return
+		}
+
+		r, err := routes.Check(c.Request.Context(), route, func(kind string) string {
+			return rateLimitKey(c, u, kind)
+		})
+		if err != nil {
+			// The route is closed rather than left unlimited while the store fails.
+			l.Error(err, "http - v1 - rateLimitMiddleware")
+			useCaseErrorResponse(c, domain.ServiceError{Name: "rate limits", Err: err})
+
+			return
+		}
+
+		if r.Limit == 0 {
+			c.Next()
+
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(r.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(r.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(int(math.Ceil(r.Reset.Seconds()))))
+
+		if !r.Allowed {
+			useCaseErrorResponse(c, domain.RateLimitError{Err: domain.ErrTooManyRequests, RetryAfter: r.RetryAfter})
+
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitKey returns the value of the key kind for the request, or an empty
+// string when the request has none.
+func rateLimitKey(c *gin.Context, u usecase.UserUseCase, kind string) string {
+	switch kind {
+	case ratelimit.KeyIP:
+		return c.ClientIP()
+	case ratelimit.KeyEmail:
+		return requestEmail(c)
+	case ratelimit.KeyUser:
+		// The principal is stored for authMiddleware so the token is resolved once.
+		p, err := u.Authenticate(c.Request.Context(), bearerToken(c))
+		if err != nil {
+			return ""
+		}
+		c.Set(principalContextKey, p)
+
+		return strconv.FormatInt(int64(p.User.Id), 10)
+	case ratelimit.KeyAPIKey:
+		// OAuth clients authenticate with their id and secret.
+		if clientId, _, ok := c.Request.BasicAuth(); ok {
+			return clientId
+		}
+
+		return c.PostForm("client_id")
+	}
+
+	return ""
+}
+
+// requestEmail returns the email field of a JSON request body, which is left
+// to be read again by the handler. The body is decoded whatever its content
+// type, like the handlers bind it.
+func requestEmail(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return ""
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var request struct {
+		Email string `json:"email"`
+	}
+	if err = json.Unmarshal(body, &request); err != nil {
+		return ""
+	}
+
+	return strings.ToLower(strings.TrimSpace(request.Email))
+}
@@ -0,0 +1,315 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PanziApp/backend/internal/domain"
+	"github.com/PanziApp/backend/internal/usecase"
+)
+
+// entityIdParam parses the numeric id of the path parameter, responding with
+// 400 when it is not one.
+func entityIdParam(c *gin.Context, name string) (domain.EntityId, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id <= 0 {
+		errorResponse(c, http.StatusBadRequest, "invalid "+name)
+
+		return 0, false
+	}
+
+	return domain.EntityId(id), true
+}
+
+type roleResponse struct {
+	Id          int64     `json:"id"           example:"1"`
+	CreateTime  time.Time `json:"create_time"  example:"2022-09-17T10:00:00Z"`
+	Name        string    `json:"name"         example:"support"`
+	Description string    `json:"description"  example:"Support staff"`
+	Permissions []string  `json:"permissions"  example:"users:read"`
+}
+
+type rolesResponse struct {
+	Roles []roleResponse `json:"roles"`
+}
+
+func newRoleResponse(role domain.Role) roleResponse {
+	r := roleResponse{
+		Id:          int64(role.Id),
+		CreateTime:  role.CreateTime,
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: make([]string, 0, len(role.Permissions)),
+	}
+	for _, p := range role.Permissions {
+		r.Permissions = append(r.Permissions, string(p))
+	}
+
+	return r
+}
+
+func newRolesResponse(roles []domain.Role) rolesResponse {
+	r := rolesResponse{Roles: make([]roleResponse, 0, len(roles))}
+	for _, role := range roles {
+		r.Roles = append(r.Roles, newRoleResponse(role))
+	}
+
+	return r
+}
+
+// @Summary     List roles
+// @Description Show every role with its permissions, needs the roles:read permission
+// @ID          list-roles
+// @Tags  	    admin
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200 {object} rolesResponse
+// @Failure     401 {object} response
+// @Failure     403 {object} response
+// @Failure     500 {object} response
+// @Router      /admin/roles [get]
+func (r *userRoutes) listRoles(c *gin.Context) {
+	roles, err := r.u.ListRoles(c.Request.Context(), principal(c))
+	if err != nil {
+		r.l.Error(err, "http - v1 - listRoles")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, newRolesResponse(roles))
+}
+
+type createRoleRequest struct {
+	Name        string   `json:"name"         binding:"required,max=64"  example:"support"`
+	Description string   `json:"description"  binding:"max=255"          example:"Support staff"`
+	Permissions []string `json:"permissions"                             example:"users:read"`
+}
+
+// @Summary     Create role
+// @Description Create a role granting the permissions, needs the roles:manage permission
+// @ID          create-role
+// @Tags  	    admin
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body createRoleRequest true "Name, description and permissions of the role"
+// @Success     200 {object} roleResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     403 {object} response
+// @Failure     500 {object} response
+// @Router      /admin/roles [post]
+func (r *userRoutes) createRole(c *gin.Context) {
+	var request createRoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - createRole")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	role, err := r.u.CreateRole(
+		c.Request.Context(),
+		principal(c),
+		request.Name,
+		request.Description,
+		request.Permissions,
+	)
+	if err != nil {
+		r.l.Error(err, "http - v1 - createRole")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, newRoleResponse(role))
+}
+
+type updateRoleRequest struct {
+	Description *string   `json:"description"  binding:"omitempty,max=255"  example:"Support staff"`
+	Permissions *[]string `json:"permissions"                               example:"users:read"`
+}
+
+// @Summary     Update role
+// @Description Update the description or permissions of a role, omitted fields are left unchanged. Needs the roles:manage permission, the admin role cannot be changed
+// @ID          update-role
+// @Tags  	    admin
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "Role id"
+// @Param       request body updateRoleRequest true "Role fields to update"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     403 {object} response
+// @Failure     500 {object} response
+// @Router      /admin/roles/{id} [post]
+func (r *userRoutes) updateRole(c *gin.Context) {
+	roleId, ok := entityIdParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var request updateRoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - updateRole")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	err := r.u.UpdateRole(c.Request.Context(), principal(c), roleId, usecase.RoleUpdateDTO{
+		Description: request.Description,
+		Permissions: request.Permissions,
+	})
+	if err != nil {
+		r.l.Error(err, "http - v1 - updateRole")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary     Delete role
+// @Description Delete a role and unassign it from its users, needs the roles:manage permission
+// @ID          delete-role
+// @Tags  	    admin
+// @Security    BearerAuth
+// @Param       id path int true "Role id"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     403 {object} response
+// @Failure     500 {object} response
+// @Router      /admin/roles/{id}/delete [post]
+func (r *userRoutes) deleteRole(c *gin.Context) {
+	roleId, ok := entityIdParam(c, "id")
+	if !ok {
+		return
+	}
+
+	err := r.u.DeleteRole(c.Request.Context(), principal(c), roleId)
+	if err != nil {
+		r.l.Error(err, "http - v1 - deleteRole")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary     List user roles
+// @Description Show the roles assigned to a user, needs the roles:read permission
+// @ID          list-user-roles
+// @Tags  	    admin
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "User id"
+// @Success     200 {object} rolesResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     403 {object} response
+// @Failure     500 {object} response
+// @Router      /admin/users/{id}/roles [get]
+func (r *userRoutes) listUserRoles(c *gin.Context) {
+	userId, ok := entityIdParam(c, "id")
+	if !ok {
+		return
+	}
+
+	roles, err := r.u.ListUserRoles(c.Request.Context(), principal(c), userId)
+	if err != nil {
+		r.l.Error(err, "http - v1 - listUserRoles")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, newRolesResponse(roles))
+}
+
+type assignRoleRequest struct {
+	RoleId int64 `json:"role_id"  binding:"required"  example:"1"`
+}
+
+// @Summary     Assign role
+// @Description Assign a role to a user, needs the roles:manage permission
+// @ID          assign-role
+// @Tags  	    admin
+// @Accept      json
+// @Security    BearerAuth
+// @Param       id path int true "User id"
+// @Param       request body assignRoleRequest true "Role to assign"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     403 {object} response
+// @Failure     500 {object} response
+// @Router      /admin/users/{id}/roles [post]
+func (r *userRoutes) assignRole(c *gin.Context) {
+	userId, ok := entityIdParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var request assignRoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - assignRole")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	err := r.u.AssignRole(c.Request.Context(), principal(c), userId, domain.EntityId(request.RoleId))
+	if err != nil {
+		r.l.Error(err, "http - v1 - assignRole")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary     Unassign role
+// @Description Take a role from a user, needs the roles:manage permission. The last admin keeps the admin role
+// @ID          unassign-role
+// @Tags  	    admin
+// @Security    BearerAuth
+// @Param       id path int true "User id"
+// @Param       role_id path int true "Role id"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     403 {object} response
+// @Failure     500 {object} response
+// @Router      /admin/users/{id}/roles/{role_id}/unassign [post]
+func (r *userRoutes) unassignRole(c *gin.Context) {
+	userId, ok := entityIdParam(c, "id")
+	if !ok {
+		return
+	}
+
+	roleId, ok := entityIdParam(c, "role_id")
+	if !ok {
+		return
+	}
+
+	err := r.u.UnassignRole(c.Request.Context(), principal(c), userId, roleId)
+	if err != nil {
+		r.l.Error(err, "http - v1 - unassignRole")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -12,17 +12,29 @@ import (
 	// Swagger docs.
 	_ "github.com/PanziApp/backend/docs"
 	"github.com/PanziApp/backend/internal/usecase"
+	"github.com/PanziApp/backend/pkg/jwt"
 	"github.com/PanziApp/backend/pkg/logger"
+	"github.com/PanziApp/backend/pkg/ratelimit"
 )
 
 // NewRouter -.
 // Swagger spec:
-// @title       Go Clean Template API
-// @description Using a translation service as an example
+// @title       Panzi API
+// @description Panzi users and authentication service
 // @version     1.0
 // @host        localhost:8080
 // @BasePath    /v1
-func NewRouter(handler *gin.Engine, l logger.Interface, t usecase.Translation) {
+// @securityDefinitions.apikey BearerAuth
+// @in          header
+// @name        Authorization
+func NewRouter(
+	handler *gin.Engine,
+	l logger.Interface,
+	u usecase.UserUseCase,
+	keyring *jwt.Keyring,
+	limits ratelimit.Routes,
+	cookieAuth bool,
+) {
 	// Options
 	handler.Use(gin.Logger())
 	handler.Use(gin.Recovery())
@@ -37,9 +49,16 @@ func NewRouter(handler *gin.Engine, l logger.Interface, t usecase.Translation) {
 	// Prometheus metrics
 	handler.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
+	// Public keys of signed access tokens, and the OpenID Connect discovery
+	// document as ID tokens are signed with them
+	if keyring != nil {
+		handler.GET("/.well-known/jwks.json", func(c *gin.Context) { c.JSON(http.StatusOK, keyring.JWKS()) })
+		handler.GET("/.well-known/openid-configuration", openIdConfiguration(u, keyring))
+	}
+
 	// Routers
-	h := handler.Group("/v1")
+	h := handler.Group("/v1", cookieAuthMiddleware(cookieAuth), rateLimitMiddleware(limits, u, l))
 	{
-		newUserRoutes(h, t, l)
+		newUserRoutes(h, u, l)
 	}
 }
@@ -0,0 +1,109 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PanziApp/backend/internal/usecase"
+)
+
+type sessionResponse struct {
+	Id          string    `json:"id"             example:"c2Vzc2lvbi1mYW1pbHkt"`
+	SignInTime  time.Time `json:"sign_in_time"   example:"2022-06-25T09:10:00Z"`
+	LastUseTime time.Time `json:"last_use_time"  example:"2022-06-25T11:42:00Z"`
+	UserAgent   string    `json:"user_agent"     example:"Mozilla/5.0 (X11; Linux x86_64)"`
+	IP          string    `json:"ip"             example:"203.0.113.7"`
+	DeviceName  string    `json:"device_name"    example:"Work laptop"`
+	Current     bool      `json:"current"        example:"true"`
+}
+
+type sessionsResponse struct {
+	Sessions []sessionResponse `json:"sessions"`
+}
+
+func newSessionResponses(sessions []usecase.SessionDTO) []sessionResponse {
+	r := make([]sessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		r = append(r, sessionResponse{
+			Id:          s.Id,
+			SignInTime:  s.SignInTime,
+			LastUseTime: s.LastUseTime,
+			UserAgent:   s.UserAgent,
+			IP:          s.IP,
+			DeviceName:  s.DeviceName,
+			Current:     s.Current,
+		})
+	}
+
+	return r
+}
+
+// @Summary     List sessions
+// @Description Show the active sessions of the current user
+// @ID          list-sessions
+// @Tags  	    session
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200 {object} sessionsResponse
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /users/sessions [get]
+func (r *userRoutes) listSessions(c *gin.Context) {
+	sessions, err := r.u.ListSessions(c.Request.Context(), principal(c))
+	if err != nil {
+		r.l.Error(err, "http - v1 - listSessions")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, sessionsResponse{Sessions: newSessionResponses(sessions)})
+}
+
+// @Summary     Revoke session
+// @Description Sign out the session with the id
+// @ID          revoke-session
+// @Tags  	    session
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path string true "Session id"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /users/sessions/{id}/revoke [post]
+func (r *userRoutes) revokeSession(c *gin.Context) {
+	err := r.u.RevokeSession(c.Request.Context(), principal(c), c.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - revokeSession")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary     Revoke other sessions
+// @Description Sign out every session of the current user except the current one
+// @ID          revoke-other-sessions
+// @Tags  	    session
+// @Produce     json
+// @Security    BearerAuth
+// @Success     204
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /users/sessions/revoke-others [post]
+func (r *userRoutes) revokeOtherSessions(c *gin.Context) {
+	err := r.u.RevokeOtherSessions(c.Request.Context(), principal(c))
+	if err != nil {
+		r.l.Error(err, "http - v1 - revokeOtherSessions")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
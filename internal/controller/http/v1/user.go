@@ -1,7 +1,9 @@
 package v1
 
 import (
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -11,94 +13,619 @@ import (
 )
 
 type userRoutes struct {
-	t usecase.Translation
+	u usecase.UserUseCase
 	l logger.Interface
 }
 
-func newUserRoutes(handler *gin.RouterGroup, t usecase.Translation, l logger.Interface) {
-	r := &userRoutes{t, l}
+func newUserRoutes(handler *gin.RouterGroup, u usecase.UserUseCase, l logger.Interface) {
+	r := &userRoutes{u, l}
 
-	handler.POST("/sign-up")
-	handler.POST("/sign-in")
-	handler.POST("/reset-password/link")
-	handler.POST("/reset-password")
+	handler.POST("/sign-up", r.signUp)
+	handler.POST("/sign-in", r.signIn)
+	handler.POST("/sign-in/mfa", r.verifyMfa)
+	handler.POST("/sign-in/passkey/options", r.passkeySignInOptions)
+	handler.POST("/sign-in/passkey", r.passkeySignIn)
+	handler.POST("/sign-in/link", r.sendSignInLink)
+	handler.POST("/sign-in/link/redeem", r.redeemSignInLink)
+	handler.POST("/sign-in/code", r.sendSignInCode)
+	handler.POST("/sign-in/code/redeem", r.redeemSignInCode)
+	handler.POST("/sign-in/external/:provider", r.externalSignIn)
+	handler.POST("/sign-in/external/:provider/callback", r.externalSignInCallback)
+	handler.POST("/sign-in/external-link/confirm", r.confirmExternalLink)
+	handler.GET("/oauth/authorize", authMiddleware(u, l), r.getOAuthConsent)
+	handler.POST("/oauth/authorize", authMiddleware(u, l), r.authorizeOAuthClient)
+	handler.POST("/oauth/token", r.oauthToken)
+	handler.POST("/oauth/revoke", r.revokeOAuthToken)
+	handler.POST("/oauth/introspect", r.introspectOAuthToken)
+	handler.GET("/oauth/userinfo", r.oauthUserInfo)
+	handler.POST("/oauth/userinfo", r.oauthUserInfo)
+	handler.POST("/token/refresh", r.refreshTokens)
+	handler.POST("/reset-password/link", r.sendResetPasswordLink)
+	handler.POST("/reset-password", r.resetPassword)
+	handler.POST("/verify-email", r.verifyEmail)
+	handler.POST("/email/confirm", r.confirmEmailChange)
+	handler.POST("/email/cancel", r.cancelEmailChange)
+	handler.POST("/account-deletion/cancel", r.cancelAccountDeletion)
+	handler.POST("/data-export/download", r.downloadDataExport)
 
-	h := handler.Group("/users")
+	h := handler.Group("/users", authMiddleware(u, l))
 	{
-		h.POST("/sign-out")
-		h.GET("/profile")
-		h.POST("/profile")
-		h.POST("/password")
-		h.GET("/avatar")
-		h.POST("/avatar")
+		h.POST("/sign-out", r.signOut)
+		h.POST("/verify-email/link", r.resendEmailVerification)
+		h.POST("/email", r.requestEmailChange)
+		h.POST("/delete", r.deleteAccount)
+		h.POST("/data-export", r.requestDataExport)
+		h.GET("/profile", r.getProfile)
+		h.POST("/profile", r.updateProfile)
+		h.POST("/password", r.changePassword)
+		h.GET("/avatar", r.getAvatar)
+		h.POST("/avatar", r.uploadAvatar)
+		h.GET("/sessions", r.listSessions)
+		h.POST("/sessions/:id/revoke", r.revokeSession)
+		h.POST("/sessions/revoke-others", r.revokeOtherSessions)
+		h.POST("/mfa/totp", r.beginTotpEnrollment)
+		h.POST("/mfa/totp/confirm", r.confirmTotpEnrollment)
+		h.POST("/mfa/recovery-codes", r.regenerateRecoveryCodes)
+		h.POST("/mfa/disable", r.disableMfa)
+		h.GET("/passkeys", r.listPasskeys)
+		h.POST("/passkeys/options", r.passkeyRegistrationOptions)
+		h.POST("/passkeys", r.registerPasskey)
+		h.POST("/passkeys/:id/delete", r.deletePasskey)
+		h.GET("/external-identities", r.listExternalIdentities)
+		h.POST("/external-identities", r.linkExternalIdentity)
+		h.POST("/external-identities/:id/unlink", r.unlinkExternalIdentity)
+		h.GET("/oauth-clients", r.listOAuthClients)
+		h.POST("/oauth-clients", r.registerOAuthClient)
+		h.POST("/oauth-clients/:client_id/delete", r.deleteOAuthClient)
 	}
+
+	a := handler.Group("/admin", authMiddleware(u, l))
+	{
+		a.GET("/roles", RequirePermission(domain.PermissionReadRoles), r.listRoles)
+		a.POST("/roles", RequirePermission(domain.PermissionManageRoles), r.createRole)
+		a.POST("/roles/:id", RequirePermission(domain.PermissionManageRoles), r.updateRole)
+		a.POST("/roles/:id/delete", RequirePermission(domain.PermissionManageRoles), r.deleteRole)
+		a.GET("/users", RequirePermission(domain.PermissionReadUsers), r.searchUsers)
+		a.GET("/users/:id", RequirePermission(domain.PermissionReadUsers), r.getUser)
+		a.POST("/users/:id/verify-email", RequirePermission(domain.PermissionManageUsers), r.forceVerifyEmail)
+		a.POST("/users/:id/reset-password", RequirePermission(domain.PermissionManageUsers), r.sendUserResetPasswordLink)
+		a.POST("/users/:id/disable", RequirePermission(domain.PermissionManageUsers), r.disableUser)
+		a.POST("/users/:id/enable", RequirePermission(domain.PermissionManageUsers), r.enableUser)
+		a.POST("/users/:id/revoke-sessions", RequirePermission(domain.PermissionManageUsers), r.revokeUserSessions)
+		a.GET("/users/:id/roles", RequirePermission(domain.PermissionReadRoles), r.listUserRoles)
+		a.POST("/users/:id/roles", RequirePermission(domain.PermissionManageRoles), r.assignRole)
+		a.POST("/users/:id/roles/:role_id/unassign", RequirePermission(domain.PermissionManageRoles), r.unassignRole)
+		a.GET("/audit-log", RequirePermission(domain.PermissionReadAudit), r.listAuditLog)
+	}
+}
+
+type credentialsRequest struct {
+	Email      string `json:"email"        binding:"required"          example:"user@example.com"`
+	Password   string `json:"password"     binding:"required"          example:"secret-password"`
+	DeviceName string `json:"device_name"  binding:"max=100"           example:"Work laptop"`
+}
+
+// sessionMeta describes the client of the request for the sessions it creates.
+func sessionMeta(c *gin.Context, deviceName string) domain.SessionMeta {
+	return domain.NewSessionMeta(c.Request.UserAgent(), c.ClientIP(), deviceName)
+}
+
+type tokensResponse struct {
+	TokenType    string `json:"token_type"     example:"Bearer"`
+	AccessToken  string `json:"access_token"   example:"VGhpcyBpcyBub3QgYSByZWFsIHRva2VuLCBqdXN0IGFuIGV4YW1wbGU="`
+	ExpiresIn    int64  `json:"expires_in"     example:"900"`
+	RefreshToken string `json:"refresh_token"  example:"QW5vdGhlciBleGFtcGxlIHRva2VuLCBmb3IgcmVmcmVzaGluZyB0b2tlbnM="`
+}
+
+func newTokensResponse(t usecase.TokensDTO) tokensResponse {
+	return tokensResponse{
+		TokenType:    "Bearer",
+		AccessToken:  t.AccessToken,
+		ExpiresIn:    int64(time.Until(t.AccessTokenValidUntil).Seconds()),
+		RefreshToken: t.RefreshToken,
+	}
+}
+
+// @Summary     Sign up
+// @Description Create an account and send an email verification link, the password is checked against the password policy
+// @ID          sign-up
+// @Tags  	    user
+// @Accept      json
+// @Produce     json
+// @Param       request body credentialsRequest true "Email and password"
+// @Success     200 {object} tokensResponse
+// @Failure     400 {object} response
+// @Failure     429 {object} response
+// @Failure     500 {object} response
+// @Router      /sign-up [post]
+func (r *userRoutes) signUp(c *gin.Context) {
+	var request credentialsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - signUp")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	tokens, err := r.u.SignUp(
+		c.Request.Context(),
+		request.Email,
+		request.Password,
+		sessionMeta(c, request.DeviceName),
+	)
+	if err != nil {
+		r.l.Error(err, "http - v1 - signUp")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, newTokensResponse(tokens))
+}
+
+type mfaChallengeResponse struct {
+	MfaToken string `json:"mfa_token" example:"TWZhIGNoYWxsZW5nZSB0b2tlbiwganVzdCBhbiBleGFtcGxl"`
+}
+
+// @Summary     Sign in
+// @Description Sign in with email and password, when two-factor authentication is enabled a challenge to complete with /sign-in/mfa is returned instead of the tokens. Failed attempts back off exponentially and lock the email after too many, which the Retry-After header tells
+// @ID          sign-in
+// @Tags  	    user
+// @Accept      json
+// @Produce     json
+// @Param       request body credentialsRequest true "Email and password"
+// @Success     200 {object} tokensResponse
+// @Success     202 {object} mfaChallengeResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     429 {object} response
+// @Failure     500 {object} response
+// @Router      /sign-in [post]
+func (r *userRoutes) signIn(c *gin.Context) {
+	var request credentialsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - signIn")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	result, err := r.u.SignIn(
+		c.Request.Context(),
+		request.Email,
+		request.Password,
+		sessionMeta(c, request.DeviceName),
+	)
+	if err != nil {
+		r.l.Error(err, "http - v1 - signIn")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	signInResponse(c, result)
+}
+
+// signInResponse responds with the tokens, or with the challenge when two-factor
+// authentication is enabled.
+func signInResponse(c *gin.Context, result usecase.SignInDTO) {
+	if result.MfaToken != "" {
+		c.JSON(http.StatusAccepted, mfaChallengeResponse{MfaToken: result.MfaToken})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, newTokensResponse(result.Tokens))
+}
+
+type refreshTokensRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required" example:"QW5vdGhlciBleGFtcGxlIHRva2VuLCBmb3IgcmVmcmVzaGluZyB0b2tlbnM="`
+}
+
+// @Summary     Refresh tokens
+// @Description Exchange a refresh token for a new access and refresh token, the used refresh token is invalidated.
+// @Description Refused like sign in while the account is disabled or pending deletion
+// @ID          refresh-tokens
+// @Tags  	    user
+// @Accept      json
+// @Produce     json
+// @Param       request body refreshTokensRequest true "Refresh token"
+// @Success     200 {object} tokensResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /token/refresh [post]
+func (r *userRoutes) refreshTokens(c *gin.Context) {
+	var request refreshTokensRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - refreshTokens")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	tokens, err := r.u.RefreshTokens(c.Request.Context(), request.RefreshToken, sessionMeta(c, ""))
+	if err != nil {
+		r.l.Error(err, "http - v1 - refreshTokens")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, newTokensResponse(tokens))
+}
+
+type resetPasswordLinkRequest struct {
+	Email string `json:"email" binding:"required" example:"user@example.com"`
+}
+
+// @Summary     Send reset password link
+// @Description Email a link for resetting the password
+// @ID          reset-password-link
+// @Tags  	    user
+// @Accept      json
+// @Produce     json
+// @Param       request body resetPasswordLinkRequest true "Account email"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     429 {object} response
+// @Failure     500 {object} response
+// @Router      /reset-password/link [post]
+func (r *userRoutes) sendResetPasswordLink(c *gin.Context) {
+	var request resetPasswordLinkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - sendResetPasswordLink")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	err := r.u.SendResetPasswordLink(c.Request.Context(), request.Email)
+	if err != nil {
+		r.l.Error(err, "http - v1 - sendResetPasswordLink")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"     binding:"required"          example:"VGhpcyBpcyBub3QgYSByZWFsIHRva2VuLCBqdXN0IGFuIGV4YW1wbGU="`
+	Password string `json:"password"  binding:"required"          example:"new-secret-password"`
+}
+
+// @Summary     Reset password
+// @Description Set a new password using the token of a reset password link and sign out every session
+// @ID          reset-password
+// @Tags  	    user
+// @Accept      json
+// @Produce     json
+// @Param       request body resetPasswordRequest true "Reset password token and the new password"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /reset-password [post]
+func (r *userRoutes) resetPassword(c *gin.Context) {
+	var request resetPasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - resetPassword")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	err := r.u.ResetPassword(c.Request.Context(), request.Token, request.Password)
+	if err != nil {
+		r.l.Error(err, "http - v1 - resetPassword")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
 }
 
-type historyResponse struct {
-	History []domain.Translation `json:"history"`
+type verifyEmailRequest struct {
+	Token string `json:"token" binding:"required" example:"VGhpcyBpcyBub3QgYSByZWFsIHRva2VuLCBqdXN0IGFuIGV4YW1wbGU="`
 }
 
-// @Summary     Show history
-// @Description Show all translation history
-// @ID          history
-// @Tags  	    translation
+// @Summary     Verify email
+// @Description Verify the email address using the token of an email verification link
+// @ID          verify-email
+// @Tags  	    user
 // @Accept      json
 // @Produce     json
-// @Success     200 {object} historyResponse
+// @Param       request body verifyEmailRequest true "Email verification token"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
 // @Failure     500 {object} response
-// @Router      /translation/history [get]
-func (r *userRoutes) history(c *gin.Context) {
-	translations, err := r.t.History(c.Request.Context())
+// @Router      /verify-email [post]
+func (r *userRoutes) verifyEmail(c *gin.Context) {
+	var request verifyEmailRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - verifyEmail")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	err := r.u.VerifyEmail(c.Request.Context(), request.Token)
 	if err != nil {
-		r.l.Error(err, "http - v1 - history")
-		errorResponse(c, http.StatusInternalServerError, "database problems")
+		r.l.Error(err, "http - v1 - verifyEmail")
+		useCaseErrorResponse(c, err)
 
 		return
 	}
 
-	c.JSON(http.StatusOK, historyResponse{translations})
+	c.Status(http.StatusNoContent)
 }
 
-type doTranslateRequest struct {
-	Source      string `json:"source"       binding:"required"  example:"auto"`
-	Destination string `json:"destination"  binding:"required"  example:"en"`
-	Original    string `json:"original"     binding:"required"  example:"текст для перевода"`
+// @Summary     Resend email verification link
+// @Description Email a new verification link to the current user if the email is not verified yet
+// @ID          resend-email-verification
+// @Tags  	    user
+// @Produce     json
+// @Security    BearerAuth
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     429 {object} response
+// @Failure     500 {object} response
+// @Router      /users/verify-email/link [post]
+func (r *userRoutes) resendEmailVerification(c *gin.Context) {
+	err := r.u.ResendEmailVerification(c.Request.Context(), principal(c))
+	if err != nil {
+		r.l.Error(err, "http - v1 - resendEmailVerification")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
 }
 
-// @Summary     Translate
-// @Description Translate a text
-// @ID          do-translate
-// @Tags  	    translation
+// @Summary     Sign out
+// @Description Invalidate the access and refresh tokens of the current session
+// @ID          sign-out
+// @Tags  	    user
+// @Produce     json
+// @Security    BearerAuth
+// @Success     204
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /users/sign-out [post]
+func (r *userRoutes) signOut(c *gin.Context) {
+	err := r.u.SignOut(c.Request.Context(), principal(c))
+	if err != nil {
+		r.l.Error(err, "http - v1 - signOut")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type profileResponse struct {
+	Email           string   `json:"email"              example:"user@example.com"`
+	EmailIsVerified bool     `json:"email_is_verified"  example:"true"`
+	PendingEmail    string   `json:"pending_email"      example:""`
+	Fullname        string   `json:"fullname"           example:"John Doe"`
+	Avatar          string   `json:"avatar"             example:"k2Vd2JmVJ0Jx1cRQ3IlYp6tKn8w0SpJ8Y7bd4XyzVhQ8kZrT0GcmVwI5"`
+	MfaEnabled      bool     `json:"mfa_enabled"        example:"false"`
+	Permissions     []string `json:"permissions"        example:"users:read"`
+}
+
+// @Summary     Get profile
+// @Description Show the profile of the current user
+// @ID          get-profile
+// @Tags  	    user
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200 {object} profileResponse
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /users/profile [get]
+func (r *userRoutes) getProfile(c *gin.Context) {
+	p, err := r.u.GetProfile(c.Request.Context(), principal(c))
+	if err != nil {
+		r.l.Error(err, "http - v1 - getProfile")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	resp := profileResponse{
+		Email:           string(p.Email),
+		EmailIsVerified: p.EmailIsVerified,
+		PendingEmail:    string(p.PendingEmail),
+		Fullname:        string(p.Fullname),
+		Avatar:          p.Avatar,
+		MfaEnabled:      p.MfaEnabled,
+		Permissions:     make([]string, 0, len(p.Permissions)),
+	}
+	for _, permission := range p.Permissions {
+		resp.Permissions = append(resp.Permissions, string(permission))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+type updateProfileRequest struct {
+	Fullname *string `json:"fullname"  binding:"omitempty,min=5,max=100"  example:"John Doe"`
+	Avatar   *string `json:"avatar"    binding:"omitempty"                example:"k2Vd2JmVJ0Jx1cRQ3IlYp6tKn8w0SpJ8Y7bd4XyzVhQ8kZrT0GcmVwI5"`
+}
+
+// @Summary     Update profile
+// @Description Update the profile of the current user, omitted fields are left unchanged
+// @ID          update-profile
+// @Tags  	    user
 // @Accept      json
 // @Produce     json
-// @Param       request body doTranslateRequest true "Set up translation"
-// @Success     200 {object} domain.Translation
+// @Security    BearerAuth
+// @Param       request body updateProfileRequest true "Profile fields to update"
+// @Success     204
 // @Failure     400 {object} response
+// @Failure     401 {object} response
 // @Failure     500 {object} response
-// @Router      /translation/do-translate [post]
-func (r *userRoutes) doTranslate(c *gin.Context) {
-	var request doTranslateRequest
+// @Router      /users/profile [post]
+func (r *userRoutes) updateProfile(c *gin.Context) {
+	var request updateProfileRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		r.l.Error(err, "http - v1 - doTranslate")
+		r.l.Error(err, "http - v1 - updateProfile")
 		errorResponse(c, http.StatusBadRequest, "invalid request body")
 
 		return
 	}
 
-	translation, err := r.t.Translate(
+	err := r.u.UpdateProfile(c.Request.Context(), principal(c), usecase.ProfileUpdateDTO{
+		Fullname: request.Fullname,
+		Avatar:   request.Avatar,
+	})
+	if err != nil {
+		r.l.Error(err, "http - v1 - updateProfile")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type changePasswordRequest struct {
+	OldPassword string `json:"old_password"  binding:"required"        example:"secret-password"`
+	NewPassword string `json:"new_password"  binding:"required"        example:"new-secret-password"`
+	// SignOutCurrent signs out the current session too, other sessions are always signed out.
+	SignOutCurrent bool `json:"sign_out_current" example:"false"`
+}
+
+// @Summary     Change password
+// @Description Change the password of the current user and sign out every other session
+// @ID          change-password
+// @Tags  	    user
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body changePasswordRequest true "Current and new password"
+// @Success     204
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     429 {object} response
+// @Failure     500 {object} response
+// @Router      /users/password [post]
+func (r *userRoutes) changePassword(c *gin.Context) {
+	var request changePasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - changePassword")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	err := r.u.ChangePassword(
 		c.Request.Context(),
-		domain.Translation{
-			Source:      request.Source,
-			Destination: request.Destination,
-			Original:    request.Original,
-		},
+		principal(c),
+		request.OldPassword,
+		request.NewPassword,
+		!request.SignOutCurrent,
 	)
 	if err != nil {
-		r.l.Error(err, "http - v1 - doTranslate")
-		errorResponse(c, http.StatusInternalServerError, "translation service problems")
+		r.l.Error(err, "http - v1 - changePassword")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary     Get avatar
+// @Description Download the avatar image of the current user
+// @ID          get-avatar
+// @Tags  	    user
+// @Produce     png,jpeg,gif
+// @Security    BearerAuth
+// @Success     200 {file} binary
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /users/avatar [get]
+func (r *userRoutes) getAvatar(c *gin.Context) {
+	avatar, err := r.u.GetAvatar(c.Request.Context(), principal(c))
+	if err != nil {
+		r.l.Error(err, "http - v1 - getAvatar")
+		useCaseErrorResponse(c, err)
+
+		return
+	}
+	defer avatar.Close()
+
+	content, err := io.ReadAll(io.LimitReader(avatar, domain.MaxAvatarSize))
+	if err != nil {
+		r.l.Error(err, "http - v1 - getAvatar")
+		errorResponse(c, http.StatusInternalServerError, "storage problems")
+
+		return
+	}
+
+	c.Data(http.StatusOK, http.DetectContentType(content), content)
+}
+
+type uploadAvatarResponse struct {
+	Avatar string `json:"avatar" example:"k2Vd2JmVJ0Jx1cRQ3IlYp6tKn8w0SpJ8Y7bd4XyzVhQ8kZrT0GcmVwI5"`
+}
+
+// @Summary     Upload avatar
+// @Description Replace the avatar image of the current user
+// @ID          upload-avatar
+// @Tags  	    user
+// @Accept      mpfd
+// @Produce     json
+// @Security    BearerAuth
+// @Param       avatar formData file true "Png, jpeg, gif or webp image up to 1MB"
+// @Success     200 {object} uploadAvatarResponse
+// @Failure     400 {object} response
+// @Failure     401 {object} response
+// @Failure     500 {object} response
+// @Router      /users/avatar [post]
+func (r *userRoutes) uploadAvatar(c *gin.Context) {
+	fileHeader, err := c.FormFile("avatar")
+	if err != nil {
+		r.l.Error(err, "http - v1 - uploadAvatar")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		r.l.Error(err, "http - v1 - uploadAvatar")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+	defer file.Close()
+
+	// Read one byte more than allowed so oversized files fail validation.
+	content, err := io.ReadAll(io.LimitReader(file, domain.MaxAvatarSize+1))
+	if err != nil {
+		r.l.Error(err, "http - v1 - uploadAvatar")
+		errorResponse(c, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	avatar, err := r.u.UploadAvatar(c.Request.Context(), principal(c), content)
+	if err != nil {
+		r.l.Error(err, "http - v1 - uploadAvatar")
+		useCaseErrorResponse(c, err)
 
 		return
 	}
 
-	c.JSON(http.StatusOK, translation)
+	c.JSON(http.StatusOK, uploadAvatarResponse{avatar})
 }
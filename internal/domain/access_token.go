@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultAccessTokenScope = "user"
+
+// AccessTokenClaims are the claims of a signed access token, backed by the
+// session SessionId so revoking the session revokes the token. Permissions
+// are the ones of the user when the token was issued, for other services.
+type AccessTokenClaims struct {
+	Issuer      string       `json:"iss"`
+	Subject     string       `json:"sub"`
+	SessionId   EntityId     `json:"sid"`
+	Scope       string       `json:"scope"`
+	ClientId    string       `json:"client_id,omitempty"`
+	Permissions []Permission `json:"permissions,omitempty"`
+	IssuedAt    int64        `json:"iat"`
+	ExpiresAt   int64        `json:"exp"`
+}
+
+func NewAccessTokenClaims(issuer string, s Session, scope string) AccessTokenClaims {
+	c := AccessTokenClaims{
+		Issuer:    issuer,
+		Subject:   strconv.FormatInt(int64(s.UserId), 10),
+		SessionId: s.Id,
+		Scope:     scope,
+		IssuedAt:  s.CreateTime.Unix(),
+	}
+	if s.ValidUntil != nil {
+		c.ExpiresAt = s.ValidUntil.Unix()
+	}
+
+	return c
+}
+
+func (c AccessTokenClaims) Validate(issuer string, now time.Time) error {
+	if c.Issuer != issuer || c.ExpiresAt <= now.Unix() {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
+
+func (c AccessTokenClaims) UserId() (EntityId, error) {
+	id, err := strconv.ParseInt(c.Subject, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+
+	return EntityId(id), nil
+}
+
+// IsSignedToken tells signed tokens apart from opaque ones, which never contain dots.
+func IsSignedToken(t string) bool {
+	return strings.Count(t, ".") == 2
+}
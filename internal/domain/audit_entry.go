@@ -0,0 +1,48 @@
+package domain
+
+import "time"
+
+// AuditEntry records an action an admin took, with the user it was taken on.
+type AuditEntry struct {
+	Id         EntityId
+	CreateTime time.Time
+	// ActorId is the admin who took the action, zero once they were purged.
+	ActorId EntityId
+	Action  AuditAction
+	// TargetUserId is zero for actions not taken on a user, like role changes.
+	TargetUserId EntityId
+	// Details describes the action for people reading the log. It holds no
+	// personal data, the entries outlive the users they are about.
+	Details string
+}
+
+type AuditAction string
+
+const (
+	AuditUserSearch         AuditAction = "user.search"
+	AuditUserView           AuditAction = "user.view"
+	AuditUserVerifyEmail    AuditAction = "user.verify_email"
+	AuditUserResetPassword  AuditAction = "user.reset_password"
+	AuditUserDisable        AuditAction = "user.disable"
+	AuditUserEnable         AuditAction = "user.enable"
+	AuditUserRevokeSessions AuditAction = "user.revoke_sessions"
+	AuditRoleCreate         AuditAction = "role.create"
+	AuditRoleUpdate         AuditAction = "role.update"
+	AuditRoleDelete         AuditAction = "role.delete"
+	AuditRoleAssign         AuditAction = "role.assign"
+	AuditRoleUnassign       AuditAction = "role.unassign"
+)
+
+const (
+	DefaultAuditPageSize = 50
+	MaxAuditPageSize     = 200
+)
+
+// AuditSearch pages the audit log from newest to oldest.
+type AuditSearch struct {
+	// TargetUserId only lists the actions taken on the user when it is not zero.
+	TargetUserId EntityId
+	// BeforeId is the id of the last entry of the previous page.
+	BeforeId EntityId
+	Limit    uint64
+}
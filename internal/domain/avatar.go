@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"errors"
+	"net/http"
+)
+
+const MaxAvatarSize = 1 << 20
+
+var (
+	ErrInvalidAvatar  = ValidationError{Err: errors.New("avatar should be a png, jpeg, gif or webp image up to 1MB")}
+	ErrAvatarNotFound = ValidationError{Err: errors.New("avatar not found")}
+)
+
+func ValidateAvatar(content []byte) error {
+	if len(content) == 0 || MaxAvatarSize < len(content) {
+		return ErrInvalidAvatar
+	}
+
+	switch http.DetectContentType(content) {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return nil
+	default:
+		return ErrInvalidAvatar
+	}
+}
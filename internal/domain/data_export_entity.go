@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// DataExport is an archive of the personal data of a user. It is built in the
+// background after it is requested, then downloadable with the token of its
+// TokenHash until ValidUntil.
+type DataExport struct {
+	Id         EntityId
+	CreateTime time.Time
+	UserId     EntityId
+	// CompleteTime is set once the archive is built and the user is mailed.
+	CompleteTime *time.Time
+	Filename     Filename
+	// TokenHash is the hash of the token of the download link, which is only
+	// known when it is mailed.
+	TokenHash  TokenHash
+	ValidUntil *time.Time
+	// Attempts counts the failures to build or mail the export, it is retried
+	// after RetryTime until DataExportMaxAttempts.
+	Attempts  int
+	RetryTime *time.Time
+}
+
+const (
+	DataExportCompleteTimeFieldName EntityFieldName = "data_export_complete_time"
+	DataExportFilenameFieldName     EntityFieldName = "data_export_filename"
+	DataExportTokenHashFieldName    EntityFieldName = "data_export_token_hash"
+	DataExportValidUntilFieldName   EntityFieldName = "data_export_valid_until"
+)
+
+const (
+	// DataExportRequestInterval is how often a user may request an export.
+	DataExportRequestInterval = 24 * time.Hour
+	DataExportLifetime        = 7 * 24 * time.Hour
+	DataExportMaxAttempts     = 5
+	// DataExportRetryDelay is how long to wait after each failed attempt, it
+	// grows with the attempts.
+	DataExportRetryDelay = 15 * time.Minute
+)
+
+var (
+	ErrDataExportNotFound = ValidationError{Err: errors.New("data export not found")}
+	// ErrDataExportTooSoon is returned in a RateLimitError with the time left
+	// until another export can be requested.
+	ErrDataExportTooSoon = errors.New("data export was requested recently")
+)
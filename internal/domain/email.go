@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"time"
 )
 
 type Email string
@@ -48,3 +49,130 @@ Fundever Team`,
 		link,
 	)
 }
+
+func PasswordChangedEmailMessage() string {
+	return `Hello,<br />
+<br />
+The password of your account was just changed and every other device was signed out.<br />
+<br />
+If you didn't change it, please reset your password right away and contact us.<br />
+<br />
+Best Regards,<br />
+Fundever Team`
+}
+
+func SignInLinkEmailMessage(link string) string {
+	return fmt.Sprintf(`Hello,<br />
+<br />
+A sign in link was requested for your account. If you didn't request it, please ignore this email.<br />
+<br />
+In order to sign in please click <a href="%s">here</a>.<br />
+The link will be valid for the next 15 minutes and can be used only once.<br />
+<br />
+Best Regards,<br />
+Fundever Team`,
+		link,
+	)
+}
+
+func ExternalLinkEmailMessage(provider, link string) string {
+	return fmt.Sprintf(`Hello,<br />
+<br />
+Someone signed in with a %s account that has your email address. If it wasn't you, please ignore this email and your account stays unchanged.<br />
+<br />
+In order to link the %s account to your account and sign in please click <a href="%s">here</a>.<br />
+The link will be valid for the next 10 minutes and can be used only once.<br />
+<br />
+Best Regards,<br />
+Fundever Team`,
+		provider,
+		provider,
+		link,
+	)
+}
+
+func SignInCodeEmailMessage(code string) string {
+	return fmt.Sprintf(`Hello,<br />
+<br />
+A sign in code was requested for your account. If you didn't request it, please ignore this email.<br />
+<br />
+Your sign in code is <b>%s</b>.<br />
+The code will be valid for the next 10 minutes and can be used only once.<br />
+<br />
+Best Regards,<br />
+Fundever Team`,
+		code,
+	)
+}
+
+func EmailChangeConfirmationMessage(link string) string {
+	return fmt.Sprintf(`Hello,<br />
+<br />
+A request was sent to change the email address of your account to this address.<br />
+<br />
+In order to confirm the change please click <a href="%s">here</a>.<br />
+The link will be valid for the next 24 hours. If you didn't request it, please ignore this email.<br />
+<br />
+Best Regards,<br />
+Fundever Team`,
+		link,
+	)
+}
+
+func EmailChangeAlertMessage(newEmail, cancelLink string) string {
+	return fmt.Sprintf(`Hello,<br />
+<br />
+A request was sent to change the email address of your account to %s.<br />
+The address will change once the request is confirmed from the new address.<br />
+<br />
+If you didn't request it, please cancel the change <a href="%s">here</a> and reset your password right away.<br />
+<br />
+Best Regards,<br />
+Fundever Team`,
+		newEmail,
+		cancelLink,
+	)
+}
+
+func AccountDeletionEmailMessage(cancelLink string, purgeTime time.Time) string {
+	return fmt.Sprintf(`Hello,<br />
+<br />
+Your account is scheduled for deletion and you were signed out of every device.<br />
+Your account and its data will be permanently deleted on %s.<br />
+<br />
+If you changed your mind, or didn't request it, please cancel the deletion <a href="%s">here</a> before then.<br />
+<br />
+Best Regards,<br />
+Fundever Team`,
+		purgeTime.UTC().Format("January 2, 2006"),
+		cancelLink,
+	)
+}
+
+func DataExportEmailMessage(link string, validUntil time.Time) string {
+	return fmt.Sprintf(`Hello,<br />
+<br />
+The export of your personal data you requested is ready.<br />
+<br />
+In order to download it please click <a href="%s">here</a>.<br />
+The link will be valid until %s. If you didn't request the export, please reset your password right away.<br />
+<br />
+Best Regards,<br />
+Fundever Team`,
+		link,
+		validUntil.UTC().Format("January 2, 2006"),
+	)
+}
+
+func SignInLockedEmailMessage(lockUntil time.Time) string {
+	return fmt.Sprintf(`Hello,<br />
+<br />
+Signing in to your account with a password was locked until %s after too many failed attempts.<br />
+<br />
+If it wasn't you, someone may be guessing your password. Resetting your password unlocks the account right away.<br />
+<br />
+Best Regards,<br />
+Fundever Team`,
+		lockUntil.UTC().Format("15:04 MST, January 2, 2006"),
+	)
+}
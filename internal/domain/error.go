@@ -1,6 +1,10 @@
 package domain
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 type ValidationError struct {
 	Err error
@@ -39,3 +43,33 @@ func (e ServiceError) Error() string {
 func (e ServiceError) Unwrap() error {
 	return e.Err
 }
+
+// ErrTooManyRequests is the error of calls denied by the rate limits of the controllers.
+var ErrTooManyRequests = errors.New("too many requests")
+
+// ErrPermissionDenied is the error of calls by principals lacking a permission of the call.
+var ErrPermissionDenied = errors.New("permission denied")
+
+type RateLimitError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit error: %s", e.Err.Error())
+}
+
+func (e RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// OAuthError is an error of the OAuth 2.0 protocol endpoints, Code is one of
+// the error codes of RFC 6749 section 5.2 that clients rely on.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e OAuthError) Error() string {
+	return fmt.Sprintf("oauth error: %s: %s", e.Code, e.Description)
+}
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ExternalIdentity links the account of an external OpenID provider to a user.
+// A link made by matching the email is pending until the user confirms it.
+type ExternalIdentity struct {
+	Id         EntityId
+	CreateTime time.Time
+	UserId     EntityId
+	Provider   string
+	// Subject is the id of the account at the provider, emails may change but it does not.
+	Subject string
+	Email   string
+	// ConfirmTime is nil while the link is pending, it is confirmed with ConfirmToken.
+	ConfirmTime *time.Time
+	// ConfirmToken is only known when the link is created, its ConfirmTokenHash
+	// is stored instead.
+	ConfirmToken      *Token
+	ConfirmTokenHash  TokenHash
+	ConfirmValidUntil *time.Time
+	LastUseTime       *time.Time
+}
+
+func (i ExternalIdentity) Confirmed() bool {
+	return i.ConfirmTime != nil
+}
+
+const (
+	ExternalIdentityLastUseTimeFieldName EntityFieldName = "external_identity_last_use_time"
+)
+
+// ExternalSignIn is a pending authorization request to a provider, it binds the
+// state the provider sends back to the nonce and PKCE code verifier of the request.
+// UserId is set when a signed in user links a provider to their account.
+type ExternalSignIn struct {
+	Id           EntityId
+	CreateTime   time.Time
+	Provider     string
+	State        Token
+	Nonce        string
+	CodeVerifier string
+	UserId       *EntityId
+	ValidUntil   time.Time
+	UseTime      *time.Time
+}
+
+const (
+	ExternalSignInLifetime      = 10 * time.Minute
+	ExternalLinkConfirmLifetime = 10 * time.Minute
+)
+
+var (
+	ErrUnknownProvider          = ValidationError{Err: errors.New("unknown provider")}
+	ErrExternalSignInFailed     = ValidationError{Err: errors.New("external sign in failed")}
+	ErrExternalEmailRequired    = ValidationError{Err: errors.New("external account has no email")}
+	ErrExternalEmailNotVerified = ValidationError{Err: errors.New("email of the external account is not verified")}
+	ErrExternalIdentityInUse    = ValidationError{Err: errors.New("external account is linked to another user")}
+	ErrExternalIdentityNotFound = ValidationError{Err: errors.New("external account not found")}
+)
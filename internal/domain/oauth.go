@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	AuthorizationCodeGrant = "authorization_code"
+	RefreshTokenGrant      = "refresh_token"
+	ClientCredentialsGrant = "client_credentials"
+)
+
+var OAuthGrantTypes = []string{AuthorizationCodeGrant, RefreshTokenGrant, ClientCredentialsGrant}
+
+const (
+	OpenIdScope  = "openid"
+	ProfileScope = "profile"
+	EmailScope   = "email"
+)
+
+// OAuthScopes are the scopes clients may be granted, DefaultAccessTokenScope
+// gives access to the user API like a first-party session.
+var OAuthScopes = []string{OpenIdScope, ProfileScope, EmailScope, DefaultAccessTokenScope}
+
+const (
+	OAuthAuthorizationCodeLifetime = 5 * time.Minute
+	// PkceMethod is the only accepted code challenge method, "plain" offers no protection.
+	PkceMethod = "S256"
+)
+
+var (
+	ErrInvalidRequest          = OAuthError{Code: "invalid_request", Description: "request is missing or has an invalid parameter"}
+	ErrInvalidClient           = OAuthError{Code: "invalid_client", Description: "client authentication failed"}
+	ErrInvalidGrant            = OAuthError{Code: "invalid_grant", Description: "grant is invalid, expired or revoked"}
+	ErrUnauthorizedClient      = OAuthError{Code: "unauthorized_client", Description: "client is not allowed to use the grant type"}
+	ErrUnsupportedGrantType    = OAuthError{Code: "unsupported_grant_type", Description: "grant type is not supported"}
+	ErrUnsupportedResponseType = OAuthError{Code: "unsupported_response_type", Description: "response type is not supported"}
+	ErrInvalidScope            = OAuthError{Code: "invalid_scope", Description: "scope is invalid or not allowed for the client"}
+	ErrAccessDenied            = OAuthError{Code: "access_denied", Description: "user denied the authorization"}
+)
+
+// ParseScope splits a space separated scope into its sorted, distinct scopes.
+func ParseScope(scope string) []string {
+	scopes := strings.Fields(scope)
+	sort.Strings(scopes)
+
+	distinct := scopes[:0]
+	for i, s := range scopes {
+		if i == 0 || s != scopes[i-1] {
+			distinct = append(distinct, s)
+		}
+	}
+
+	return distinct
+}
+
+func FormatScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// ScopeContains tells whether every scope of sub is in scopes.
+func ScopeContains(scopes, sub []string) bool {
+	for _, s := range sub {
+		if !contains(scopes, s) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MergeScopes returns the sorted, distinct scopes of both.
+func MergeScopes(a, b []string) []string {
+	return ParseScope(FormatScope(a) + " " + FormatScope(b))
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsFold(values []string, v string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IdTokenClaims are the claims of an OpenID Connect ID token.
+type IdTokenClaims struct {
+	Issuer        string `json:"iss"`
+	Subject       string `json:"sub"`
+	Audience      string `json:"aud"`
+	IssuedAt      int64  `json:"iat"`
+	ExpiresAt     int64  `json:"exp"`
+	AuthTime      int64  `json:"auth_time"`
+	Nonce         string `json:"nonce,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	Name          string `json:"name,omitempty"`
+}
@@ -0,0 +1,186 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/url"
+	"time"
+)
+
+// OAuthClient is an application registered to sign users in with this service,
+// or to call other services with client credentials. Public clients, like
+// single page and mobile apps, have no secret and must use PKCE.
+type OAuthClient struct {
+	Id         EntityId
+	CreateTime time.Time
+	// OwnerId is the user who registered the client.
+	OwnerId      EntityId
+	ClientId     string
+	HashedSecret HashedClientSecret
+	Name         string
+	RedirectURIs []string
+	GrantTypes   []string
+	Scopes       []string
+	// Trusted clients may introspect tokens, only admins register them.
+	Trusted bool
+}
+
+func (c OAuthClient) Confidential() bool {
+	return len(c.HashedSecret) != 0
+}
+
+func (c OAuthClient) AllowsGrant(grantType string) bool {
+	return contains(c.GrantTypes, grantType)
+}
+
+// AllowsRedirectURI compares the uri exactly to the registered ones, as
+// partial matching has led to open redirects leaking codes.
+func (c OAuthClient) AllowsRedirectURI(uri string) bool {
+	return contains(c.RedirectURIs, uri)
+}
+
+// Validate checks the grant types, redirect uris and scopes go together.
+// Redirect uris may use the private schemes besides https and loopback http.
+func (c OAuthClient) Validate(privateSchemes []string) error {
+	if len(c.GrantTypes) == 0 || !ScopeContains(OAuthGrantTypes, c.GrantTypes) {
+		return ErrInvalidClientGrantTypes
+	}
+	if c.AllowsGrant(RefreshTokenGrant) && !c.AllowsGrant(AuthorizationCodeGrant) {
+		return ErrInvalidClientGrantTypes
+	}
+	if c.AllowsGrant(ClientCredentialsGrant) && !c.Confidential() {
+		return ErrInvalidClientGrantTypes
+	}
+	if c.Trusted && !c.Confidential() {
+		return ErrPublicTrustedClient
+	}
+
+	if c.AllowsGrant(AuthorizationCodeGrant) && len(c.RedirectURIs) == 0 {
+		return ErrInvalidRedirectURI
+	}
+	if len(c.RedirectURIs) > 10 {
+		return ErrInvalidRedirectURI
+	}
+	for _, uri := range c.RedirectURIs {
+		if err := ValidateRedirectURI(uri, privateSchemes); err != nil {
+			return err
+		}
+	}
+
+	if len(c.Scopes) == 0 || !ScopeContains(OAuthScopes, c.Scopes) {
+		return ErrInvalidClientScopes
+	}
+
+	return nil
+}
+
+var (
+	ErrOAuthClientNotFound     = ValidationError{Err: errors.New("client not found")}
+	ErrOAuthConsentNotFound    = ValidationError{Err: errors.New("consent not found")}
+	ErrInvalidClientName       = ValidationError{Err: errors.New("client name should be 1 to 100 characters")}
+	ErrInvalidClientGrantTypes = ValidationError{Err: errors.New("invalid client grant types")}
+	ErrInvalidClientScopes     = ValidationError{Err: errors.New("invalid client scopes")}
+	ErrInvalidRedirectURI      = ValidationError{Err: errors.New("invalid redirect uri")}
+	ErrPublicTrustedClient     = ValidationError{Err: errors.New("trusted clients must be confidential")}
+)
+
+func ValidateOAuthClientName(name string) (string, error) {
+	if name == "" || len(name) > 100 {
+		return "", ErrInvalidClientName
+	}
+
+	return name, nil
+}
+
+// unsafeRedirectSchemes run code or read local files when the browser is sent
+// to them, they are never accepted, even as private schemes.
+var unsafeRedirectSchemes = []string{"javascript", "data", "vbscript", "file"}
+
+// ValidateRedirectURI accepts absolute https uris without a fragment. Plain http
+// is only accepted for the loopback interface, for native apps and development,
+// and native apps may also use one of the private schemes allowed by the server.
+func ValidateRedirectURI(uri string, privateSchemes []string) error {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Fragment != "" || len(uri) > 2000 {
+		return ErrInvalidRedirectURI
+	}
+
+	// url.Parse lowercases the scheme.
+	switch {
+	case contains(unsafeRedirectSchemes, u.Scheme):
+		return ErrInvalidRedirectURI
+	case u.Scheme == "https":
+		if u.Host == "" {
+			return ErrInvalidRedirectURI
+		}
+	case u.Scheme == "http":
+		host := u.Hostname()
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return ErrInvalidRedirectURI
+		}
+	case !containsFold(privateSchemes, u.Scheme):
+		return ErrInvalidRedirectURI
+	}
+
+	return nil
+}
+
+type (
+	ClientSecret       string
+	HashedClientSecret []byte
+)
+
+func RandomClientId() (string, error) {
+	return RandomStringURLSafe(18)
+}
+
+func RandomClientSecret() (ClientSecret, error) {
+	s, err := RandomStringURLSafe(36)
+	return ClientSecret(s), err
+}
+
+// Hash returns the SHA-256 of the secret, which is random enough not to need a slow hash.
+func (s ClientSecret) Hash() HashedClientSecret {
+	sum := sha256.Sum256([]byte(s))
+
+	return sum[:]
+}
+
+func (h HashedClientSecret) Match(secret ClientSecret) error {
+	if len(h) == 0 || subtle.ConstantTimeCompare(h, secret.Hash()) != 1 {
+		return ErrInvalidClient
+	}
+
+	return nil
+}
+
+// OAuthConsent is the scopes a user allowed a client to access.
+type OAuthConsent struct {
+	UserId     EntityId
+	ClientId   EntityId
+	Scopes     []string
+	UpdateTime time.Time
+}
+
+// OAuthAuthorizationCode is the single use code a client exchanges for the
+// tokens of the authorization, the tokens it is exchanged for join Family.
+type OAuthAuthorizationCode struct {
+	Id         EntityId
+	CreateTime time.Time
+	// Code is only known when it is issued, its CodeHash is stored instead.
+	Code        Token
+	CodeHash    TokenHash
+	ClientId    EntityId
+	UserId      EntityId
+	RedirectURI string
+	Scopes      []string
+	Nonce       string
+	// CodeChallenge is the S256 PKCE challenge the code verifier must match.
+	CodeChallenge string
+	Family        string
+	AuthTime      time.Time
+	ValidUntil    time.Time
+	UseTime       *time.Time
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// Passkey is a WebAuthn public key credential the user signs in with instead of a password.
+type Passkey struct {
+	Id           EntityId
+	CreateTime   time.Time
+	UserId       EntityId
+	CredentialId []byte
+	PublicKey    []byte
+	// SignCount is the last signature counter reported by the authenticator,
+	// a counter that does not increase reveals a cloned credential.
+	SignCount   uint32
+	Transports  []string
+	Name        string
+	LastUseTime *time.Time
+}
+
+// ExternalId is how the credential is identified to clients, the way browsers encode it.
+func (p Passkey) ExternalId() string {
+	return base64.RawURLEncoding.EncodeToString(p.CredentialId)
+}
+
+const (
+	PasskeySignCountFieldName   EntityFieldName = "passkey_sign_count"
+	PasskeyLastUseTimeFieldName EntityFieldName = "passkey_last_use_time"
+)
+
+type PasskeyChallengeType string
+
+const (
+	PasskeyRegistrationChallenge PasskeyChallengeType = "registration"
+	PasskeySignInChallenge       PasskeyChallengeType = "sign-in"
+)
+
+// PasskeyChallenge is a pending registration or sign in ceremony. Sign in
+// challenges are not bound to a user, the credential tells who signs in.
+type PasskeyChallenge struct {
+	Id         EntityId
+	CreateTime time.Time
+	UserId     *EntityId
+	Type       PasskeyChallengeType
+	Challenge  Token
+	ValidUntil time.Time
+	UseTime    *time.Time
+}
+
+const (
+	PasskeyChallengeLifetime = 5 * time.Minute
+	PasskeyDefaultName       = "Passkey"
+)
+
+var (
+	ErrInvalidPasskey   = ValidationError{Err: errors.New("invalid passkey")}
+	ErrPasskeyNotFound  = ValidationError{Err: errors.New("passkey not found")}
+	ErrDuplicatePasskey = ValidationError{Err: errors.New("passkey is already registered")}
+	ErrPasskeyCloned    = ValidationError{Err: errors.New("passkey signature counter did not increase, it may have been cloned")}
+)
+
+// PasskeyUserHandle is the opaque user id stored by authenticators, it must
+// not contain personal information such as the email.
+func PasskeyUserHandle(userId EntityId) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(userId))
+
+	return b
+}
+
+func ValidatePasskeyName(name string) (string, error) {
+	if name == "" {
+		return PasskeyDefaultName, nil
+	}
+	if len(name) > 100 {
+		return "", ValidationError{Err: errors.New("passkey name is too long")}
+	}
+
+	return name, nil
+}
@@ -1,47 +1,264 @@
 package domain
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type (
-	Password       []byte
+	Password []byte
+	// HashedPassword is versioned by the algorithm it names, either an argon2id
+	// hash in the PHC string format or a bcrypt hash, which a peppered hash
+	// prefixes with "$hmac-sha256".
 	HashedPassword []byte
 )
 
-var ErrShortPassword = ValidationError{Err: errors.New("password should be at least 8 characters")}
+// MaxPasswordSize bounds passwords in bytes whatever the policy.
+const MaxPasswordSize = 100
+
+var (
+	ErrPasswordTooShort = errors.New("password is too short")
+	ErrPasswordTooLong  = errors.New("password is too long")
+)
 
+// ValidatePassword only bounds the size of the password, new passwords are
+// checked against the PasswordPolicy too.
 func ValidatePassword(password string) (Password, error) {
-	if len(password) < 8 || 100 < len(password) {
-		return Password{}, ErrShortPassword
+	if len(password) == 0 {
+		return Password{}, ValidationError{Err: ErrPasswordTooShort}
+	}
+
+	if MaxPasswordSize < len(password) {
+		return Password{}, ValidationError{
+			Err: fmt.Errorf("%w, it should be at most %d bytes", ErrPasswordTooLong, MaxPasswordSize),
+		}
 	}
 
 	return Password(password), nil
 }
 
-func HashPassword(password Password) (HashedPassword, error) {
-	if len(password) < 8 || 100 < len(password) {
-		return HashedPassword{}, ErrShortPassword
+var ErrInvalidPassword = ValidationError{Err: errors.New("invalid password")}
+
+// Password hashing algorithms.
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+const (
+	_pepperedPrefix     = "$hmac-sha256"
+	_argon2idPrefix     = "$argon2id$"
+	_argon2SaltLength   = 16
+	_argon2KeyLength    = 32
+	_bcryptMaxPassword  = 72
+	_base64PasswordSize = 44
+)
+
+// Argon2Params -.
+type Argon2Params struct {
+	// Memory is in KiB.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// PasswordHasher hashes passwords by the algorithm and matches the hashes of
+// every algorithm. With a pepper, passwords are keyed by it with HMAC-SHA256
+// before being hashed, so leaked hashes cannot be cracked without it.
+type PasswordHasher struct {
+	Algorithm  string
+	Argon2     Argon2Params
+	BcryptCost int
+	Pepper     []byte
+}
+
+// DefaultPasswordHasher follows the second recommended option of RFC 9106,
+// with less parallelism.
+var DefaultPasswordHasher = PasswordHasher{
+	Algorithm:  Argon2id,
+	Argon2:     Argon2Params{Memory: 64 * 1024, Iterations: 3, Parallelism: 2},
+	BcryptCost: bcrypt.DefaultCost,
+}
+
+// Validate -.
+func (h PasswordHasher) Validate() error {
+	switch h.Algorithm {
+	case Argon2id:
+		if h.Argon2.Memory < 8*uint32(h.Argon2.Parallelism) || h.Argon2.Iterations < 1 || h.Argon2.Parallelism < 1 {
+			return fmt.Errorf("argon2id needs at least 1 iteration and thread, and 8 KiB of memory per thread")
+		}
+	case Bcrypt:
+		if h.BcryptCost < bcrypt.MinCost || bcrypt.MaxCost < h.BcryptCost {
+			return fmt.Errorf("bcrypt cost should be from %d to %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return fmt.Errorf("unknown password hashing algorithm %q", h.Algorithm)
 	}
 
-	h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
+	return nil
+}
+
+func (h PasswordHasher) Hash(password Password) (HashedPassword, error) {
+	if len(password) == 0 || MaxPasswordSize < len(password) {
+		return HashedPassword{}, InternalError{Err: errors.New("password of invalid size")}
+	}
+
+	prefix := ""
+	if len(h.Pepper) > 0 {
+		prefix = _pepperedPrefix
+		password = h.pepper(password)
+	}
+
+	if h.Algorithm == Bcrypt {
+		// bcrypt ignores the bytes past 72, peppered passwords are shorter.
+		if _bcryptMaxPassword < len(password) {
+			return HashedPassword{}, ValidationError{
+				Err: fmt.Errorf("%w, it should be at most %d bytes", ErrPasswordTooLong, _bcryptMaxPassword),
+			}
+		}
+
+		hash, err := bcrypt.GenerateFromPassword(password, h.BcryptCost)
+		if err != nil {
+			return HashedPassword{}, InternalError{Err: err}
+		}
+
+		return HashedPassword(prefix + string(hash)), nil
+	}
+
+	salt := make([]byte, _argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
 		return HashedPassword{}, InternalError{Err: err}
 	}
 
-	return h, nil
-}
+	p := h.Argon2
+	key := argon2.IDKey(password, salt, p.Iterations, p.Memory, p.Parallelism, _argon2KeyLength)
 
-var ErrInvalidPassword = ValidationError{Err: errors.New("invalid password")}
+	return HashedPassword(fmt.Sprintf(
+		"%s$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		prefix, argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	)), nil
+}
 
-func (p HashedPassword) Match(password Password) error {
-	err := bcrypt.CompareHashAndPassword(p, password)
-	if err == bcrypt.ErrMismatchedHashAndPassword {
+// Match fails for accounts without a password, like the ones created by
+// signing in with an external provider, until they reset it.
+func (h PasswordHasher) Match(hash HashedPassword, password Password) error {
+	if len(hash) == 0 {
 		return ErrInvalidPassword
-	} else if err != nil {
+	}
+
+	s := string(hash)
+	if strings.HasPrefix(s, _pepperedPrefix) {
+		if len(h.Pepper) == 0 {
+			return InternalError{Err: errors.New("password hash is peppered but no pepper is configured")}
+		}
+
+		s = strings.TrimPrefix(s, _pepperedPrefix)
+		password = h.pepper(password)
+	}
+
+	if !strings.HasPrefix(s, _argon2idPrefix) {
+		err := bcrypt.CompareHashAndPassword([]byte(s), password)
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return ErrInvalidPassword
+		} else if err != nil {
+			return InternalError{Err: err}
+		}
+
+		return nil
+	}
+
+	p, salt, key, err := parseArgon2id(s)
+	if err != nil {
 		return InternalError{Err: err}
 	}
 
+	other := argon2.IDKey(password, salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrInvalidPassword
+	}
+
 	return nil
 }
+
+// NeedsRehash tells whether the hash is not of the algorithm and parameters
+// of the hasher, or lacks its pepper.
+func (h PasswordHasher) NeedsRehash(hash HashedPassword) bool {
+	if len(hash) == 0 {
+		return false
+	}
+
+	s := string(hash)
+	if strings.HasPrefix(s, _pepperedPrefix) != (len(h.Pepper) > 0) {
+		return true
+	}
+	s = strings.TrimPrefix(s, _pepperedPrefix)
+
+	if h.Algorithm == Bcrypt {
+		cost, err := bcrypt.Cost([]byte(s))
+
+		return err != nil || cost != h.BcryptCost
+	}
+
+	if !strings.HasPrefix(s, _argon2idPrefix) {
+		return true
+	}
+
+	p, _, _, err := parseArgon2id(s)
+
+	return err != nil || p != h.Argon2
+}
+
+// pepper keys the password with the pepper, base64 encoded as bcrypt stops at
+// the first zero byte.
+func (h PasswordHasher) pepper(password Password) Password {
+	mac := hmac.New(sha256.New, h.Pepper)
+	mac.Write(password)
+
+	peppered := make([]byte, _base64PasswordSize)
+	base64.StdEncoding.Encode(peppered, mac.Sum(nil))
+
+	return peppered
+}
+
+// parseArgon2id parses "$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>".
+func parseArgon2id(s string) (p Argon2Params, salt, key []byte, err error) {
+	parts := strings.Split(s, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, errors.New("malformed argon2id hash")
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2id version %q", parts[2])
+	}
+
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism)
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("malformed argon2id parameters: %w", err)
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("malformed argon2id salt: %w", err)
+	}
+
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("malformed argon2id key: %w", err)
+	}
+	if len(key) == 0 {
+		return p, nil, nil, errors.New("empty argon2id key")
+	}
+
+	return p, salt, key, nil
+}
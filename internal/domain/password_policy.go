@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PanziApp/backend/pkg/passwordstrength"
+)
+
+// PasswordPolicy is what new passwords are checked against.
+type PasswordPolicy struct {
+	// MinLength is in characters.
+	MinLength int
+	// MinCharacterClasses is how many of lowercase letters, uppercase letters,
+	// digits and symbols a password should mix.
+	MinCharacterClasses int
+	// MaxRepeatedChars is how many times in a row a character may appear, zero
+	// allows any.
+	MaxRepeatedChars int
+	// DisallowPersonalInfo rejects passwords containing the name of the email
+	// or a part of the full name of the user.
+	DisallowPersonalInfo bool
+	// MinStrength is the least strength score from 0 to 4, as estimated by
+	// passwordstrength.Score.
+	MinStrength int
+}
+
+// DefaultPasswordPolicy keeps the 8 characters passwords always needed.
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 8}
+
+var (
+	ErrPasswordTooFewClasses = errors.New("password does not mix enough kinds of characters")
+	ErrPasswordRepeats       = errors.New("password repeats a character too many times")
+	ErrPasswordPersonalInfo  = errors.New("password contains your email or name")
+	ErrPasswordTooWeak       = errors.New("password is too easy to guess")
+	ErrPasswordBreached      = errors.New("password appeared in a data breach")
+)
+
+// _minPersonalInfoLength is the length from which a part of the email or name
+// may not appear in the password, shorter ones appear by chance.
+const _minPersonalInfoLength = 3
+
+// Check returns a ValidationError wrapping the error of the first rule the
+// password breaks, the email and fullname are of its user.
+func (p PasswordPolicy) Check(password Password, email Email, fullname Fullname) error {
+	s := string(password)
+
+	if length := utf8.RuneCountInString(s); length < p.MinLength {
+		return ValidationError{
+			Err: fmt.Errorf("%w, it should be at least %d characters", ErrPasswordTooShort, p.MinLength),
+		}
+	}
+
+	if classes := characterClasses(s); classes < p.MinCharacterClasses {
+		return ValidationError{
+			Err: fmt.Errorf(
+				"%w, it should mix %d of lowercase letters, uppercase letters, digits and symbols",
+				ErrPasswordTooFewClasses, p.MinCharacterClasses,
+			),
+		}
+	}
+
+	if p.MaxRepeatedChars > 0 && maxRepeatedChars(s) > p.MaxRepeatedChars {
+		return ValidationError{
+			Err: fmt.Errorf("%w, at most %d in a row are allowed", ErrPasswordRepeats, p.MaxRepeatedChars),
+		}
+	}
+
+	personalInfo := personalInfo(email, fullname)
+	if p.DisallowPersonalInfo {
+		lower := strings.ToLower(s)
+		for _, info := range personalInfo {
+			if strings.Contains(lower, info) {
+				return ValidationError{Err: ErrPasswordPersonalInfo}
+			}
+		}
+	}
+
+	if p.MinStrength > 0 && passwordstrength.Score(s, personalInfo...) < p.MinStrength {
+		return ValidationError{Err: ErrPasswordTooWeak}
+	}
+
+	return nil
+}
+
+func characterClasses(s string) int {
+	var lower, upper, digit, symbol int
+	for _, c := range s {
+		switch {
+		case unicode.IsLower(c):
+			lower = 1
+		case unicode.IsUpper(c):
+			upper = 1
+		case unicode.IsDigit(c):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+
+	return lower + upper + digit + symbol
+}
+
+func maxRepeatedChars(s string) int {
+	var (
+		longest, run int
+		last         rune
+	)
+	for i, c := range s {
+		if i > 0 && c == last {
+			run++
+		} else {
+			run = 1
+		}
+		last = c
+
+		if run > longest {
+			longest = run
+		}
+	}
+
+	return longest
+}
+
+// personalInfo returns the lowercase name of the email and the parts of it
+// and of the full name.
+func personalInfo(email Email, fullname Fullname) []string {
+	name := strings.ToLower(string(email))
+	if i := strings.LastIndexByte(name, '@'); i >= 0 {
+		name = name[:i]
+	}
+
+	parts := strings.FieldsFunc(name, func(c rune) bool { return strings.ContainsRune("._+-", c) })
+	parts = append(parts, name)
+	parts = append(parts, strings.Fields(strings.ToLower(string(fullname)))...)
+
+	info := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if utf8.RuneCountInString(part) >= _minPersonalInfoLength {
+			info = append(info, part)
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Permission allows an action on a resource, checked by the use cases.
+type Permission string
+
+const (
+	PermissionReadUsers   Permission = "users:read"
+	PermissionManageUsers Permission = "users:manage"
+	PermissionReadRoles   Permission = "roles:read"
+	PermissionManageRoles Permission = "roles:manage"
+	PermissionReadAudit   Permission = "audit:read"
+	// PermissionManageOAuthClients allows registering trusted and client credentials clients.
+	PermissionManageOAuthClients Permission = "oauth_clients:manage"
+)
+
+// AllPermissions are the permissions roles can grant, the admin role has them all.
+var AllPermissions = []Permission{
+	PermissionReadUsers,
+	PermissionManageUsers,
+	PermissionReadRoles,
+	PermissionManageRoles,
+	PermissionReadAudit,
+	PermissionManageOAuthClients,
+}
+
+// Role is a named set of permissions assigned to users, a user has the
+// permissions of all of their roles.
+type Role struct {
+	Id          EntityId
+	CreateTime  time.Time
+	Name        string
+	Description string
+	Permissions []Permission
+}
+
+// AdminRoleName is the role bootstrapped for the first administrator. It
+// always has every permission and cannot be changed or deleted.
+const AdminRoleName = "admin"
+
+func (r Role) Builtin() bool {
+	return r.Name == AdminRoleName
+}
+
+const (
+	RoleDescriptionFieldName EntityFieldName = "role_description"
+	RolePermissionsFieldName EntityFieldName = "role_permissions"
+)
+
+var (
+	ErrRoleNotFound           = ValidationError{Err: errors.New("role not found")}
+	ErrDuplicateRole          = ValidationError{Err: errors.New("role already exists")}
+	ErrInvalidRoleName        = ValidationError{Err: errors.New("role name must be 1 to 64 lowercase letters, digits, - or _")}
+	ErrBuiltinRole            = ValidationError{Err: errors.New("the admin role cannot be changed")}
+	ErrLastAdmin              = ValidationError{Err: errors.New("the last admin cannot lose the admin role")}
+	ErrInvalidRoleDescription = ValidationError{Err: errors.New("role description must be at most 255 characters")}
+)
+
+var roleNameRegexp = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+func ValidateRoleName(name string) (string, error) {
+	if !roleNameRegexp.MatchString(name) {
+		return "", ErrInvalidRoleName
+	}
+
+	return name, nil
+}
+
+func ValidateRoleDescription(description string) (string, error) {
+	if len(description) > 255 {
+		return "", ErrInvalidRoleDescription
+	}
+
+	return description, nil
+}
+
+// ValidatePermissions checks every permission is known, and returns them sorted without duplicates.
+func ValidatePermissions(permissions []string) ([]Permission, error) {
+	known := make(map[Permission]bool, len(AllPermissions))
+	for _, p := range AllPermissions {
+		known[p] = true
+	}
+
+	seen := make(map[Permission]bool, len(permissions))
+	valid := make([]Permission, 0, len(permissions))
+	for _, p := range permissions {
+		permission := Permission(p)
+		if !known[permission] {
+			return nil, ValidationError{Err: fmt.Errorf("unknown permission %q", p)}
+		}
+		if !seen[permission] {
+			seen[permission] = true
+			valid = append(valid, permission)
+		}
+	}
+
+	sort.Slice(valid, func(i, j int) bool { return valid[i] < valid[j] })
+	return valid, nil
+}
+
+// RolesPermissions returns the permissions granted by any of the roles, sorted.
+func RolesPermissions(roles []Role) []Permission {
+	seen := make(map[Permission]bool)
+	permissions := make([]Permission, 0)
+	for _, r := range roles {
+		for _, p := range r.Permissions {
+			if !seen[p] {
+				seen[p] = true
+				permissions = append(permissions, p)
+			}
+		}
+	}
+
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i] < permissions[j] })
+	return permissions
+}
+
+// HasPermissions tells whether granted contains every one of the permissions.
+func HasPermissions(granted []Permission, permissions ...Permission) bool {
+	for _, p := range permissions {
+		found := false
+		for _, g := range granted {
+			if g == p {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
@@ -1,46 +1,138 @@
 package domain
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
 	"errors"
 	"time"
+	"unicode/utf8"
 )
 
 type Session struct {
 	Id         EntityId
 	CreateTime time.Time
-	UserId     EntityId
-	Type       TokenType
+	// UserId is zero for the tokens a client is issued for itself with client credentials.
+	UserId EntityId
+	Type   TokenType
+	// Token is only known when the session is created, sessions store its
+	// TokenHash so the tokens cannot be read back from the database.
 	Token      Token
+	TokenHash  TokenHash
 	ValidUntil *time.Time
+	// Family groups the access and refresh tokens issued by one sign in.
+	Family string
+	// AuthTime is when the user signed in to create the session family.
+	AuthTime time.Time
+	// UseTime is when a single use token, like a refresh token, was used.
+	UseTime *time.Time
+	// Attempts counts the failed attempts to complete the challenge of the session.
+	Attempts int
+	// Code is the hash of the one-time code mailed for a sign in code session,
+	// the session is looked up by user since codes are not unique.
+	Code string
+	// ClientId is the OAuth client the tokens were issued to, nil for first-party sessions.
+	ClientId *EntityId
+	// Scope is the space separated scopes granted to the OAuth client.
+	Scope string
+	Meta  SessionMeta
+}
+
+// Scopes returns the scopes the token grants, first-party sessions have the default scope.
+func (s Session) Scopes() []string {
+	if s.ClientId == nil {
+		return []string{DefaultAccessTokenScope}
+	}
+
+	return ParseScope(s.Scope)
+}
+
+// SessionMeta describes the client a session was created for.
+type SessionMeta struct {
+	UserAgent  string
+	IP         string
+	DeviceName string
+}
+
+func NewSessionMeta(userAgent, ip, deviceName string) SessionMeta {
+	return SessionMeta{
+		UserAgent:  truncate(userAgent, 255),
+		IP:         truncate(ip, 45),
+		DeviceName: truncate(deviceName, 100),
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	// Do not cut a multi-byte character in half.
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
 }
 
 const (
 	SessionValidUntilFieldName EntityFieldName = "session_valid_until"
+	SessionUseTimeFieldName    EntityFieldName = "session_use_time"
 )
 
 type Token string
 
+// Hash returns the SHA-256 digest of the token. Tokens are random, so a fast
+// unsalted hash is enough to keep them from being usable if the database leaks.
+func (t Token) Hash() TokenHash {
+	sum := sha256.Sum256([]byte(t))
+	return sum[:]
+}
+
+type TokenHash []byte
+
+// Match compares the hash of the token with h in constant time.
+func (h TokenHash) Match(t Token) bool {
+	return subtle.ConstantTimeCompare(h, t.Hash()) == 1
+}
+
 type TokenType string
 
 const (
 	GeneralToken           TokenType = "general"
 	EmailVerificationToken TokenType = "email-verification"
 	ResetPasswordToken     TokenType = "reset-password"
+	RefreshToken           TokenType = "refresh"
+	MfaChallengeToken      TokenType = "mfa-challenge"
+	SignInLinkToken        TokenType = "sign-in-link"
+	SignInCodeToken        TokenType = "sign-in-code"
+	EmailChangeToken       TokenType = "email-change"
+	EmailChangeCancelToken TokenType = "email-change-cancel"
+	// AccountDeletionCancelToken is valid for the grace period of the deletion.
+	AccountDeletionCancelToken TokenType = "account-deletion-cancel"
 )
 
-var (
-	ErrInvalidToken = InternalError{Err: errors.New("invalid token")}
+const (
+	ResetPasswordTokenLifetime     = time.Hour
+	EmailVerificationTokenLifetime = 48 * time.Hour
+	EmailVerificationResendDelay   = 5 * time.Minute
+	SignInLinkTokenLifetime        = 15 * time.Minute
+	SignInCodeTokenLifetime        = 10 * time.Minute
+	SignInCodeMaxAttempts          = 5
+	EmailChangeTokenLifetime       = 24 * time.Hour
 )
 
-func RandomToken() (Token, error) {
-	s, err := RandomStringURLSafe(36)
-	return Token(s), err
-}
+// Every sign in code gets a few attempts, so the failed codes of an email are
+// also counted across codes. Past SignInCodeMaxFailures within
+// SignInCodeLockout, codes of the email are locked for SignInCodeLockout.
+const (
+	SignInCodeMaxFailures = 10
+	SignInCodeLockout     = time.Hour
+)
 
-func ValidateToken(t string) (Token, error) {
-	if len(t) > 100 {
-		return "", ErrInvalidToken
-	}
+var (
+	ErrInvalidToken    = InternalError{Err: errors.New("invalid token")}
+	ErrSessionNotFound = ValidationError{Err: errors.New("session not found")}
+)
 
-	return Token(t), nil
+func RandomFamily() (string, error) {
+	return RandomStringURLSafe(18)
 }
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// SignInFailures counts the recent failed password sign ins with an email or
+// from an ip address, which Key tells apart. Emails are counted whether they are
+// registered or not, so throttling does not reveal which ones are.
+type SignInFailures struct {
+	Key             string
+	Failures        int
+	LastFailureTime time.Time
+	// LockUntil is set when the failures reached the lockout threshold.
+	LockUntil *time.Time
+}
+
+const (
+	SignInBackoffBase = time.Second
+	SignInBackoffMax  = 5 * time.Minute
+)
+
+var (
+	ErrSignInThrottled     = errors.New("too many failed sign in attempts")
+	ErrSignInCodeThrottled = errors.New("too many failed sign in codes")
+)
+
+func EmailSignInFailuresKey(email Email) string {
+	return "email:" + string(email)
+}
+
+func IPSignInFailuresKey(ip string) string {
+	return "ip:" + ip
+}
+
+// SignInCodeFailuresKey counts the failed sign in codes of the email, apart
+// from its failed password sign ins.
+func SignInCodeFailuresKey(email Email) string {
+	return "sign-in-code:" + string(email)
+}
+
+// LockedFor returns how long sign in stays locked at now.
+func (f SignInFailures) LockedFor(now time.Time) time.Duration {
+	if f.LockUntil == nil || !f.LockUntil.After(now) {
+		return 0
+	}
+
+	return f.LockUntil.Sub(now)
+}
+
+// BackoffFor returns how long to wait after the last failure before trying
+// again at now, the wait doubles with every failure.
+func (f SignInFailures) BackoffFor(now time.Time) time.Duration {
+	if f.Failures == 0 {
+		return 0
+	}
+
+	backoff := SignInBackoffMax
+	if f.Failures <= 16 {
+		backoff = SignInBackoffBase << (f.Failures - 1)
+		if backoff > SignInBackoffMax {
+			backoff = SignInBackoffMax
+		}
+	}
+
+	if wait := f.LastFailureTime.Add(backoff).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
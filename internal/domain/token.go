@@ -0,0 +1,159 @@
+package domain
+
+import (
+	"hash/crc32"
+	"strings"
+)
+
+// Tokens look like "pz_reset_" followed by random base62 characters and a
+// CRC32 checksum of the rest in 6 base62 characters. The prefix tells the type
+// of the token, so secret scanners can spot leaked tokens, and malformed or
+// mistyped tokens are rejected without looking them up.
+const (
+	_tokenNamespace      = "pz_"
+	_tokenRandomLength   = 40
+	_tokenChecksumLength = 6
+	_base62Alphabet      = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// _legacyTokenLength is the length of the bare base64 tokens issued before
+	// the prefixed format, they stay valid until they expire.
+	_legacyTokenLength = 48
+)
+
+// Tokens handed out outside of sessions, they share the format of session tokens.
+const (
+	OAuthCodeToken        TokenType = "oauth-code"
+	PasskeyChallengeToken TokenType = "passkey-challenge"
+	ExternalStateToken    TokenType = "external-state"
+	ExternalLinkToken     TokenType = "external-link"
+	DataExportToken       TokenType = "data-export"
+)
+
+var tokenPrefixes = map[TokenType]string{
+	GeneralToken:               "sess",
+	RefreshToken:               "refresh",
+	EmailVerificationToken:     "verify",
+	ResetPasswordToken:         "reset",
+	MfaChallengeToken:          "mfa",
+	SignInLinkToken:            "link",
+	SignInCodeToken:            "code",
+	EmailChangeToken:           "email",
+	EmailChangeCancelToken:     "emailcancel",
+	AccountDeletionCancelToken: "delcancel",
+	OAuthCodeToken:             "oauthcode",
+	PasskeyChallengeToken:      "passkey",
+	ExternalStateToken:         "state",
+	ExternalLinkToken:          "extlink",
+	DataExportToken:            "export",
+}
+
+// RandomToken returns a new token of the type.
+func RandomToken(tokenType TokenType) (Token, error) {
+	prefix, ok := tokenPrefixes[tokenType]
+	if !ok {
+		return "", InternalError{Err: ErrInvalidToken}
+	}
+
+	random, err := randomBase62(_tokenRandomLength)
+	if err != nil {
+		return "", err
+	}
+
+	body := _tokenNamespace + prefix + "_" + random
+	return Token(body + tokenChecksum(body)), nil
+}
+
+// ValidateToken checks the format of the token and that it is of one of the
+// types. Tokens of the legacy format carry no type, they are only checked when
+// they are looked up.
+func ValidateToken(t string, tokenTypes ...TokenType) (Token, error) {
+	if len(t) == 0 || len(t) > 100 {
+		return "", ErrInvalidToken
+	}
+
+	if !strings.HasPrefix(t, _tokenNamespace) && isLegacyToken(t) {
+		return Token(t), nil
+	}
+
+	tokenType, ok := parseToken(t)
+	if !ok {
+		return "", ErrInvalidToken
+	}
+
+	for _, expected := range tokenTypes {
+		if tokenType == expected {
+			return Token(t), nil
+		}
+	}
+
+	return "", ErrInvalidToken
+}
+
+func parseToken(t string) (TokenType, bool) {
+	rest := strings.TrimPrefix(t, _tokenNamespace)
+	i := strings.IndexByte(rest, '_')
+	if i < 0 || len(t) != len(_tokenNamespace)+i+1+_tokenRandomLength+_tokenChecksumLength {
+		return "", false
+	}
+
+	for _, c := range rest[i+1:] {
+		if !strings.ContainsRune(_base62Alphabet, c) {
+			return "", false
+		}
+	}
+
+	body, checksum := t[:len(t)-_tokenChecksumLength], t[len(t)-_tokenChecksumLength:]
+	if tokenChecksum(body) != checksum {
+		return "", false
+	}
+
+	for tokenType, prefix := range tokenPrefixes {
+		if prefix == rest[:i] {
+			return tokenType, true
+		}
+	}
+	return "", false
+}
+
+func isLegacyToken(t string) bool {
+	if len(t) != _legacyTokenLength {
+		return false
+	}
+
+	for _, c := range t {
+		if !strings.ContainsRune(_base62Alphabet, c) && c != '-' && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+func tokenChecksum(body string) string {
+	n := crc32.ChecksumIEEE([]byte(body))
+
+	checksum := make([]byte, _tokenChecksumLength)
+	for i := len(checksum) - 1; i >= 0; i-- {
+		checksum[i] = _base62Alphabet[n%62]
+		n /= 62
+	}
+	return string(checksum)
+}
+
+// randomBase62 returns n uniformly distributed base62 characters.
+func randomBase62(n int) (string, error) {
+	s := make([]byte, 0, n)
+	for len(s) < n {
+		b, err := RandomBytes(n)
+		if err != nil {
+			return "", err
+		}
+
+		for _, c := range b {
+			// 248 is the largest multiple of 62 in a byte, rejecting the
+			// bytes above it keeps the characters unbiased.
+			if c < 248 && len(s) < n {
+				s = append(s, _base62Alphabet[c%62])
+			}
+		}
+	}
+	return string(s), nil
+}
@@ -1,20 +1,92 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 type User struct {
 	Id              EntityId
 	CreateTime      time.Time
 	Email           Email
 	EmailVerifyTime *time.Time
-	HashedPassword  HashedPassword
-	Fullname        Fullname
-	Avatar          string
+	// PendingEmail is the address the user asked to change their email to,
+	// it replaces Email once confirmed from the new address.
+	PendingEmail   Email
+	HashedPassword HashedPassword
+	Fullname       Fullname
+	Avatar         string
+	// TotpSecret is set when enrolling two-factor authentication, which is
+	// enabled once the enrollment is confirmed at TotpEnableTime.
+	TotpSecret     TotpSecret
+	TotpEnableTime *time.Time
+	// TotpLastStep is the period of the last accepted code, to prevent replays.
+	TotpLastStep int64
+	// DeleteTime is set while the account is pending deletion, the account
+	// is purged once it passes.
+	DeleteTime *time.Time
+	// DisableTime is set while an admin has disabled the account, it cannot
+	// sign in until it is enabled again.
+	DisableTime *time.Time
+}
+
+func (u User) MfaEnabled() bool {
+	return u.TotpEnableTime != nil
+}
+
+func (u User) PendingDeletion() bool {
+	return u.DeleteTime != nil
+}
+
+func (u User) Disabled() bool {
+	return u.DisableTime != nil
 }
 
 const (
+	UserEmailFieldName           EntityFieldName = "user_email"
 	UserEmailVerifyTimeFieldName EntityFieldName = "user_email_verify_time"
+	UserPendingEmailFieldName    EntityFieldName = "user_pending_email"
 	UserHashedPasswordFieldName  EntityFieldName = "user_hashed_password"
 	UserFullnameFieldName        EntityFieldName = "user_fullname"
 	UserAvatarFieldName          EntityFieldName = "user_avatar"
+	UserTotpSecretFieldName      EntityFieldName = "user_totp_secret"
+	UserTotpEnableTimeFieldName  EntityFieldName = "user_totp_enable_time"
+	UserTotpLastStepFieldName    EntityFieldName = "user_totp_last_step"
+	UserDeleteTimeFieldName      EntityFieldName = "user_delete_time"
+	UserDisableTimeFieldName     EntityFieldName = "user_disable_time"
+)
+
+var (
+	ErrUserNotFound   = ValidationError{Err: errors.New("user not found")}
+	ErrDuplicateEmail = ValidationError{Err: errors.New("email is already registered")}
+
+	ErrEmailAlreadyVerified = ValidationError{Err: errors.New("email is already verified")}
+	// ErrVerificationEmailTooSoon is returned in a RateLimitError with the
+	// time left until the link can be resent.
+	ErrVerificationEmailTooSoon = errors.New("verification email was sent recently")
+
+	ErrEmailUnchanged = ValidationError{Err: errors.New("new email is the current email")}
+
+	ErrAccountPendingDeletion = ValidationError{Err: errors.New("account is pending deletion")}
+
+	ErrAccountDisabled    = ValidationError{Err: errors.New("account is disabled")}
+	ErrAccountNotDisabled = ValidationError{Err: errors.New("account is not disabled")}
+	ErrCannotDisableSelf  = ValidationError{Err: errors.New("you cannot disable your own account")}
+	ErrInvalidUserSearch  = ValidationError{Err: errors.New("created_after must be before created_before")}
 )
+
+const (
+	DefaultUserPageSize = 20
+	MaxUserPageSize     = 100
+)
+
+// UserSearch filters and pages the users, by id from oldest to newest.
+type UserSearch struct {
+	// Query matches a part of the email or the full name, case insensitively.
+	Query         string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// AfterId is the id of the last user of the previous page.
+	AfterId EntityId
+	Limit   uint64
+}
@@ -0,0 +1,140 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PanziApp/backend/internal/domain"
+)
+
+const _purgeBatchSize = 100
+
+// DeleteAccount schedules the account of the user for deletion after the grace
+// period, signs out every session and mails a link to cancel the deletion. The
+// account cannot sign in until the deletion is canceled.
+func (uc UserUseCase) DeleteAccount(
+	ctx context.Context,
+	principal Principal,
+	password string,
+) error {
+	u := principal.User
+	if err := uc.matchPassword(ctx, u, password); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	deleteTime := now.Add(uc.deletionGracePeriod)
+	err := uc.repo.user.Update(ctx, u.Id, domain.EntityUpdate{domain.UserDeleteTimeFieldName: deleteTime})
+	if err != nil {
+		return err
+	}
+
+	err = uc.repo.session.RevokeByUserId(
+		ctx,
+		u.Id,
+		[]domain.TokenType{
+			domain.GeneralToken,
+			domain.RefreshToken,
+			domain.EmailVerificationToken,
+			domain.ResetPasswordToken,
+			domain.MfaChallengeToken,
+			domain.SignInLinkToken,
+			domain.SignInCodeToken,
+			domain.EmailChangeToken,
+			domain.EmailChangeCancelToken,
+		},
+		domain.Session{},
+		now,
+	)
+	if err != nil {
+		return err
+	}
+
+	session, err := uc.createSupersedingSession(ctx, domain.Session{
+		UserId: u.Id,
+		Type:   domain.AccountDeletionCancelToken,
+	}, uc.deletionGracePeriod)
+	if err != nil {
+		return err
+	}
+
+	return uc.mailer.Send(
+		ctx,
+		string(u.Email),
+		string(u.Fullname),
+		"Your Account Is Scheduled for Deletion",
+		domain.AccountDeletionEmailMessage(string(session.Token), deleteTime),
+	)
+}
+
+// CancelAccountDeletion restores an account pending deletion with the cancel
+// link mailed when it was deleted, the user can then sign in again.
+func (uc UserUseCase) CancelAccountDeletion(
+	ctx context.Context,
+	token string,
+) error {
+	validToken, err := domain.ValidateToken(token, domain.AccountDeletionCancelToken)
+	if err != nil {
+		return err
+	}
+
+	s, err := uc.repo.session.Redeem(ctx, validToken, domain.AccountDeletionCancelToken, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return uc.repo.user.Update(ctx, s.UserId, domain.EntityUpdate{
+		domain.UserDeleteTimeFieldName: (*time.Time)(nil),
+	})
+}
+
+// PurgeDeletedAccounts permanently removes the accounts whose grace period has
+// passed, with their avatar, data exports and every other row of theirs, and
+// returns how many were purged.
+func (uc UserUseCase) PurgeDeletedAccounts(ctx context.Context) (purged int, err error) {
+	for {
+		now := time.Now()
+		users, err := uc.repo.user.ListDeleted(ctx, now, _purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, u := range users {
+			exports, err := uc.repo.dataExport.ListByUserId(ctx, u.Id)
+			if err != nil {
+				return purged, err
+			}
+
+			// The row goes first, so the files of an account whose deletion was
+			// canceled meanwhile are kept.
+			err = uc.repo.user.DeleteDue(ctx, u.Id, now)
+			if errors.Is(err, domain.ErrUserNotFound) {
+				continue
+			} else if err != nil {
+				return purged, err
+			}
+			purged++
+
+			files := make([]string, 0, len(exports)+1)
+			if u.Avatar != "" {
+				files = append(files, u.Avatar)
+			}
+			for _, e := range exports {
+				if e.Filename != "" {
+					files = append(files, string(e.Filename))
+				}
+			}
+			for _, f := range files {
+				err = uc.storage.Remove(ctx, f)
+				if err != nil {
+					return purged, domain.ServiceError{Name: "storage", Err: err}
+				}
+			}
+		}
+
+		if len(users) < _purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PanziApp/backend/internal/domain"
+)
+
+// audit records the action the principal took on the target user, zero for
+// actions not taken on a user.
+func (uc UserUseCase) audit(
+	ctx context.Context,
+	principal Principal,
+	action domain.AuditAction,
+	targetUserId domain.EntityId,
+	details string,
+) error {
+	return uc.repo.audit.Create(ctx, domain.AuditEntry{
+		CreateTime:   time.Now(),
+		ActorId:      principal.User.Id,
+		Action:       action,
+		TargetUserId: targetUserId,
+		Details:      details,
+	})
+}
+
+// audited takes the action with fn and records it in the same transaction, so
+// neither is kept without the other. fn returns the details of the entry.
+func (uc UserUseCase) audited(
+	ctx context.Context,
+	principal Principal,
+	action domain.AuditAction,
+	targetUserId domain.EntityId,
+	fn func(ctx context.Context) (details string, err error),
+) error {
+	return uc.transactor.InTx(ctx, func(ctx context.Context) error {
+		details, err := fn(ctx)
+		if err != nil {
+			return err
+		}
+
+		return uc.audit(ctx, principal, action, targetUserId, details)
+	})
+}
+
+type UserPageDTO struct {
+	Users []domain.User
+	// NextCursor is the id to search after for the next page, zero on the last page.
+	NextCursor domain.EntityId
+}
+
+// SearchUsers pages through the users matching the search by id.
+func (uc UserUseCase) SearchUsers(
+	ctx context.Context,
+	principal Principal,
+	search domain.UserSearch,
+) (p UserPageDTO, err error) {
+	if err = principal.Authorize(domain.PermissionReadUsers); err != nil {
+		return p, err
+	}
+
+	if search.CreatedAfter != nil && search.CreatedBefore != nil && !search.CreatedAfter.Before(*search.CreatedBefore) {
+		return p, domain.ErrInvalidUserSearch
+	}
+
+	if search.Limit == 0 {
+		search.Limit = domain.DefaultUserPageSize
+	} else if search.Limit > domain.MaxUserPageSize {
+		search.Limit = domain.MaxUserPageSize
+	}
+
+	// One more user tells whether there is a next page.
+	limit := search.Limit
+	search.Limit++
+	p.Users, err = uc.repo.user.Search(ctx, search)
+	if err != nil {
+		return p, err
+	}
+
+	if uint64(len(p.Users)) > limit {
+		p.Users = p.Users[:limit]
+		p.NextCursor = p.Users[limit-1].Id
+	}
+
+	// The search itself may hold an email, the users it returned are recorded.
+	ids := make([]string, 0, len(p.Users))
+	for _, u := range p.Users {
+		ids = append(ids, strconv.FormatInt(int64(u.Id), 10))
+	}
+	err = uc.audit(ctx, principal, domain.AuditUserSearch, 0, fmt.Sprintf("users [%s]", strings.Join(ids, ", ")))
+	if err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
+
+type AdminUserDTO struct {
+	User  domain.User
+	Roles []domain.Role
+	// Sessions are the active sessions of the user.
+	Sessions []SessionDTO
+}
+
+// GetUser returns the user with their roles and active sessions.
+func (uc UserUseCase) GetUser(
+	ctx context.Context,
+	principal Principal,
+	userId domain.EntityId,
+) (r AdminUserDTO, err error) {
+	if err = principal.Authorize(domain.PermissionReadUsers); err != nil {
+		return r, err
+	}
+
+	r.User, err = uc.repo.user.Get(ctx, userId)
+	if err != nil {
+		return r, err
+	}
+
+	r.Roles, err = uc.repo.role.ListByUserId(ctx, userId)
+	if err != nil {
+		return r, err
+	}
+
+	sessions, err := uc.repo.session.ListByUserId(ctx, userId, domain.RefreshToken, time.Now())
+	if err != nil {
+		return r, err
+	}
+	r.Sessions = newSessionDTOs(sessions, "")
+
+	err = uc.audit(ctx, principal, domain.AuditUserView, userId, "")
+	if err != nil {
+		return r, err
+	}
+
+	return r, nil
+}
+
+// ForceVerifyEmail marks the email of the user as verified, for users who
+// cannot receive the verification link, and revokes the pending links.
+func (uc UserUseCase) ForceVerifyEmail(
+	ctx context.Context,
+	principal Principal,
+	userId domain.EntityId,
+) error {
+	if err := principal.Authorize(domain.PermissionManageUsers); err != nil {
+		return err
+	}
+
+	u, err := uc.repo.user.Get(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if u.EmailVerifyTime != nil {
+		return domain.ErrEmailAlreadyVerified
+	}
+
+	return uc.audited(ctx, principal, domain.AuditUserVerifyEmail, u.Id, func(ctx context.Context) (string, error) {
+		now := time.Now()
+		err := uc.repo.user.Update(ctx, u.Id, domain.EntityUpdate{domain.UserEmailVerifyTimeFieldName: now})
+		if err != nil {
+			return "", err
+		}
+
+		return "", uc.repo.session.RevokeByUserId(
+			ctx, u.Id, []domain.TokenType{domain.EmailVerificationToken}, domain.Session{}, now)
+	})
+}
+
+// SendUserResetPasswordLink mails the user a link to reset their password, as
+// if they asked for it.
+func (uc UserUseCase) SendUserResetPasswordLink(
+	ctx context.Context,
+	principal Principal,
+	userId domain.EntityId,
+) error {
+	if err := principal.Authorize(domain.PermissionManageUsers); err != nil {
+		return err
+	}
+
+	u, err := uc.repo.user.Get(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	// The link is mailed once it is recorded.
+	var session domain.Session
+	err = uc.audited(ctx, principal, domain.AuditUserResetPassword, u.Id, func(ctx context.Context) (string, error) {
+		session, err = uc.createSupersedingSession(ctx, domain.Session{
+			UserId: u.Id,
+			Type:   domain.ResetPasswordToken,
+		}, domain.ResetPasswordTokenLifetime)
+		return "", err
+	})
+	if err != nil {
+		return err
+	}
+
+	return uc.mailResetPasswordLink(ctx, u, session.Token)
+}
+
+// DisableUser stops the user from signing in and signs out all of their
+// sessions, until EnableUser is called. Admins cannot disable themselves.
+func (uc UserUseCase) DisableUser(
+	ctx context.Context,
+	principal Principal,
+	userId domain.EntityId,
+	reason string,
+) error {
+	if err := principal.Authorize(domain.PermissionManageUsers); err != nil {
+		return err
+	}
+
+	if userId == principal.User.Id {
+		return domain.ErrCannotDisableSelf
+	}
+
+	u, err := uc.repo.user.Get(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if u.Disabled() {
+		return domain.ErrAccountDisabled
+	}
+
+	return uc.audited(ctx, principal, domain.AuditUserDisable, u.Id, func(ctx context.Context) (string, error) {
+		now := time.Now()
+		err := uc.repo.user.Update(ctx, u.Id, domain.EntityUpdate{domain.UserDisableTimeFieldName: now})
+		if err != nil {
+			return "", err
+		}
+
+		revoked, err := uc.repo.session.RevokeAllByUserId(ctx, u.Id, now)
+		if err != nil {
+			return "", err
+		}
+
+		details := fmt.Sprintf("%d sessions revoked", revoked)
+		if reason != "" {
+			details += ": " + reason
+		}
+
+		return details, nil
+	})
+}
+
+// EnableUser lets a disabled user sign in again.
+func (uc UserUseCase) EnableUser(
+	ctx context.Context,
+	principal Principal,
+	userId domain.EntityId,
+) error {
+	if err := principal.Authorize(domain.PermissionManageUsers); err != nil {
+		return err
+	}
+
+	u, err := uc.repo.user.Get(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if !u.Disabled() {
+		return domain.ErrAccountNotDisabled
+	}
+
+	return uc.audited(ctx, principal, domain.AuditUserEnable, u.Id, func(ctx context.Context) (string, error) {
+		return "", uc.repo.user.Update(ctx, u.Id, domain.EntityUpdate{domain.UserDisableTimeFieldName: (*time.Time)(nil)})
+	})
+}
+
+// RevokeUserSessions signs the user out everywhere and revokes every link
+// mailed to them, and returns how many sessions were revoked.
+func (uc UserUseCase) RevokeUserSessions(
+	ctx context.Context,
+	principal Principal,
+	userId domain.EntityId,
+) (int, error) {
+	if err := principal.Authorize(domain.PermissionManageUsers); err != nil {
+		return 0, err
+	}
+
+	u, err := uc.repo.user.Get(ctx, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	var revoked int
+	err = uc.audited(ctx, principal, domain.AuditUserRevokeSessions, u.Id, func(ctx context.Context) (string, error) {
+		revoked, err = uc.repo.session.RevokeAllByUserId(ctx, u.Id, time.Now())
+		return fmt.Sprintf("%d sessions revoked", revoked), err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return revoked, nil
+}
+
+type AuditPageDTO struct {
+	Entries []domain.AuditEntry
+	// NextCursor is the id to search before for the next page, zero on the last page.
+	NextCursor domain.EntityId
+}
+
+// ListAuditLog pages through the actions admins took, newest first.
+func (uc UserUseCase) ListAuditLog(
+	ctx context.Context,
+	principal Principal,
+	search domain.AuditSearch,
+) (p AuditPageDTO, err error) {
+	if err = principal.Authorize(domain.PermissionReadAudit); err != nil {
+		return p, err
+	}
+
+	if search.Limit == 0 {
+		search.Limit = domain.DefaultAuditPageSize
+	} else if search.Limit > domain.MaxAuditPageSize {
+		search.Limit = domain.MaxAuditPageSize
+	}
+
+	limit := search.Limit
+	search.Limit++
+	p.Entries, err = uc.repo.audit.List(ctx, search)
+	if err != nil {
+		return p, err
+	}
+
+	if uint64(len(p.Entries)) > limit {
+		p.Entries = p.Entries[:limit]
+		p.NextCursor = p.Entries[limit-1].Id
+	}
+
+	return p, nil
+}
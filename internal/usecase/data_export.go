@@ -0,0 +1,435 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/PanziApp/backend/internal/domain"
+)
+
+const _dataExportBatchSize = 10
+
+// RequestDataExport queues an export of the personal data of the user, the
+// user is mailed a download link once it is built by ProcessDataExports. A
+// user may request an export once per domain.DataExportRequestInterval.
+func (uc UserUseCase) RequestDataExport(
+	ctx context.Context,
+	principal Principal,
+) error {
+	u := principal.User
+
+	last, err := uc.repo.dataExport.GetLastByUserId(ctx, u.Id)
+	if err != nil && !errors.Is(err, domain.ErrDataExportNotFound) {
+		return err
+	}
+	if err == nil {
+		if wait := time.Until(last.CreateTime.Add(domain.DataExportRequestInterval)); wait > 0 {
+			return domain.RateLimitError{Err: domain.ErrDataExportTooSoon, RetryAfter: wait}
+		}
+	}
+
+	_, err = uc.repo.dataExport.Create(ctx, domain.DataExport{
+		CreateTime: time.Now(),
+		UserId:     u.Id,
+	})
+	return err
+}
+
+// DownloadDataExport opens the archive of the export with the token of its download link.
+func (uc UserUseCase) DownloadDataExport(
+	ctx context.Context,
+	token string,
+) (io.ReadCloser, error) {
+	validToken, err := domain.ValidateToken(token, domain.DataExportToken)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := uc.repo.dataExport.GetByToken(ctx, validToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.ValidUntil == nil || e.ValidUntil.Before(time.Now()) {
+		return nil, domain.ErrInvalidToken
+	}
+
+	content, err := uc.storage.Open(ctx, string(e.Filename))
+	if err != nil {
+		return nil, domain.ServiceError{Name: "storage", Err: err}
+	}
+
+	return content, nil
+}
+
+// ProcessDataExports builds the archives of the pending exports and mails their
+// download links, then removes the archives that expired. It returns how many
+// exports were built. An export that fails is retried later, without holding up
+// the others, and the failures are returned together once every export is done.
+func (uc UserUseCase) ProcessDataExports(ctx context.Context) (built int, err error) {
+	var failures []string
+	for {
+		exports, err := uc.repo.dataExport.ListPending(ctx, time.Now(), domain.DataExportMaxAttempts, _dataExportBatchSize)
+		if err != nil {
+			return built, err
+		}
+
+		for _, e := range exports {
+			err = uc.buildDataExport(ctx, e)
+			if err == nil {
+				built++
+				continue
+			}
+
+			failures = append(failures, fmt.Sprintf("export %d: %v", e.Id, err))
+			retryTime := time.Now().Add(time.Duration(e.Attempts+1) * domain.DataExportRetryDelay)
+			if err = uc.repo.dataExport.RecordFailure(ctx, e.Id, retryTime); err != nil {
+				return built, err
+			}
+		}
+
+		if len(exports) < _dataExportBatchSize {
+			break
+		}
+	}
+
+	for {
+		exports, err := uc.repo.dataExport.ListExpired(ctx, time.Now(), _dataExportBatchSize)
+		if err != nil {
+			return built, err
+		}
+
+		for _, e := range exports {
+			err = uc.removeDataExport(ctx, e)
+			if err != nil {
+				return built, err
+			}
+		}
+
+		if len(exports) < _dataExportBatchSize {
+			break
+		}
+	}
+
+	if len(failures) > 0 {
+		return built, fmt.Errorf("%d data exports failed: %s", len(failures), strings.Join(failures, "; "))
+	}
+
+	return built, nil
+}
+
+func (uc UserUseCase) buildDataExport(ctx context.Context, e domain.DataExport) error {
+	u, err := uc.repo.user.Get(ctx, e.UserId)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return uc.repo.dataExport.Delete(ctx, e.Id)
+	} else if err != nil {
+		return err
+	}
+
+	// The archive of an export whose mail failed is kept, a new link is mailed.
+	filename := e.Filename
+	if filename == "" {
+		archive, err := uc.dataExportArchive(ctx, u)
+		if err != nil {
+			return err
+		}
+
+		filename, err = domain.RandomFilename()
+		if err != nil {
+			return err
+		}
+
+		err = uc.storage.Save(ctx, string(filename), bytes.NewReader(archive))
+		if err != nil {
+			return domain.ServiceError{Name: "storage", Err: err}
+		}
+	}
+
+	token, err := domain.RandomToken(domain.DataExportToken)
+	if err != nil {
+		return err
+	}
+
+	validUntil := time.Now().Add(domain.DataExportLifetime)
+	err = uc.repo.dataExport.Update(ctx, e.Id, domain.EntityUpdate{
+		domain.DataExportFilenameFieldName:   filename,
+		domain.DataExportTokenHashFieldName:  token.Hash(),
+		domain.DataExportValidUntilFieldName: validUntil,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = uc.mailer.Send(
+		ctx,
+		string(u.Email),
+		string(u.Fullname),
+		"Your Data Export Is Ready",
+		domain.DataExportEmailMessage(string(token), validUntil),
+	)
+	if err != nil {
+		return err
+	}
+
+	return uc.repo.dataExport.Update(ctx, e.Id, domain.EntityUpdate{
+		domain.DataExportCompleteTimeFieldName: time.Now(),
+	})
+}
+
+func (uc UserUseCase) removeDataExport(ctx context.Context, e domain.DataExport) error {
+	if e.Filename != "" {
+		err := uc.storage.Remove(ctx, string(e.Filename))
+		if err != nil {
+			return domain.ServiceError{Name: "storage", Err: err}
+		}
+	}
+
+	return uc.repo.dataExport.Delete(ctx, e.Id)
+}
+
+type (
+	exportedProfile struct {
+		Id              domain.EntityId `json:"id"`
+		CreateTime      time.Time       `json:"create_time"`
+		Email           domain.Email    `json:"email"`
+		EmailVerifyTime *time.Time      `json:"email_verify_time"`
+		PendingEmail    domain.Email    `json:"pending_email"`
+		Fullname        domain.Fullname `json:"fullname"`
+		Avatar          string          `json:"avatar"`
+		MfaEnableTime   *time.Time      `json:"mfa_enable_time"`
+		DeleteTime      *time.Time      `json:"delete_time"`
+	}
+
+	exportedSession struct {
+		CreateTime time.Time        `json:"create_time"`
+		Type       domain.TokenType `json:"type"`
+		Family     string           `json:"family"`
+		AuthTime   time.Time        `json:"auth_time"`
+		ValidUntil *time.Time       `json:"valid_until"`
+		UseTime    *time.Time       `json:"use_time"`
+		Scope      string           `json:"scope"`
+		UserAgent  string           `json:"user_agent"`
+		IP         string           `json:"ip"`
+		DeviceName string           `json:"device_name"`
+	}
+
+	exportedPasskey struct {
+		Id          string     `json:"id"`
+		CreateTime  time.Time  `json:"create_time"`
+		Name        string     `json:"name"`
+		LastUseTime *time.Time `json:"last_use_time"`
+	}
+
+	exportedExternalIdentity struct {
+		CreateTime  time.Time  `json:"create_time"`
+		Provider    string     `json:"provider"`
+		Subject     string     `json:"subject"`
+		Email       string     `json:"email"`
+		ConfirmTime *time.Time `json:"confirm_time"`
+		LastUseTime *time.Time `json:"last_use_time"`
+	}
+
+	exportedAuditEntry struct {
+		CreateTime time.Time          `json:"create_time"`
+		Action     domain.AuditAction `json:"action"`
+		Details    string             `json:"details"`
+	}
+
+	exportedOAuthClient struct {
+		ClientId     string    `json:"client_id"`
+		CreateTime   time.Time `json:"create_time"`
+		Name         string    `json:"name"`
+		RedirectURIs []string  `json:"redirect_uris"`
+		GrantTypes   []string  `json:"grant_types"`
+		Scopes       []string  `json:"scopes"`
+	}
+)
+
+// dataExportArchive zips everything stored about the user as JSON files, with
+// the avatar image. Secrets like password hashes and tokens are left out.
+func (uc UserUseCase) dataExportArchive(ctx context.Context, u domain.User) ([]byte, error) {
+	sessions, err := uc.repo.session.ListAllByUserId(ctx, u.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	passkeys, err := uc.repo.passkey.ListByUserId(ctx, u.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	identities, err := uc.repo.external.ListByUserId(ctx, u.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	clients, err := uc.repo.oauth.ListClientsByOwnerId(ctx, u.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	auditEntries, err := uc.listAllAuditEntries(ctx, u.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := exportedProfile{
+		Id:              u.Id,
+		CreateTime:      u.CreateTime,
+		Email:           u.Email,
+		EmailVerifyTime: u.EmailVerifyTime,
+		PendingEmail:    u.PendingEmail,
+		Fullname:        u.Fullname,
+		Avatar:          u.Avatar,
+		MfaEnableTime:   u.TotpEnableTime,
+		DeleteTime:      u.DeleteTime,
+	}
+
+	exportedSessions := make([]exportedSession, 0, len(sessions))
+	for _, s := range sessions {
+		exportedSessions = append(exportedSessions, exportedSession{
+			CreateTime: s.CreateTime,
+			Type:       s.Type,
+			Family:     s.Family,
+			AuthTime:   s.AuthTime,
+			ValidUntil: s.ValidUntil,
+			UseTime:    s.UseTime,
+			Scope:      s.Scope,
+			UserAgent:  s.Meta.UserAgent,
+			IP:         s.Meta.IP,
+			DeviceName: s.Meta.DeviceName,
+		})
+	}
+
+	exportedPasskeys := make([]exportedPasskey, 0, len(passkeys))
+	for _, p := range passkeys {
+		exportedPasskeys = append(exportedPasskeys, exportedPasskey{
+			Id:          p.ExternalId(),
+			CreateTime:  p.CreateTime,
+			Name:        p.Name,
+			LastUseTime: p.LastUseTime,
+		})
+	}
+
+	exportedIdentities := make([]exportedExternalIdentity, 0, len(identities))
+	for _, i := range identities {
+		exportedIdentities = append(exportedIdentities, exportedExternalIdentity{
+			CreateTime:  i.CreateTime,
+			Provider:    i.Provider,
+			Subject:     i.Subject,
+			Email:       i.Email,
+			ConfirmTime: i.ConfirmTime,
+			LastUseTime: i.LastUseTime,
+		})
+	}
+
+	exportedClients := make([]exportedOAuthClient, 0, len(clients))
+	for _, c := range clients {
+		exportedClients = append(exportedClients, exportedOAuthClient{
+			ClientId:     c.ClientId,
+			CreateTime:   c.CreateTime,
+			Name:         c.Name,
+			RedirectURIs: c.RedirectURIs,
+			GrantTypes:   c.GrantTypes,
+			Scopes:       c.Scopes,
+		})
+	}
+
+	// The admins who took the actions are left out, they are not the user's data.
+	exportedAuditEntries := make([]exportedAuditEntry, 0, len(auditEntries))
+	for _, e := range auditEntries {
+		exportedAuditEntries = append(exportedAuditEntries, exportedAuditEntry{
+			CreateTime: e.CreateTime,
+			Action:     e.Action,
+			Details:    e.Details,
+		})
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+
+	for _, file := range []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", profile},
+		{"sessions.json", exportedSessions},
+		{"passkeys.json", exportedPasskeys},
+		{"external_identities.json", exportedIdentities},
+		{"oauth_clients.json", exportedClients},
+		{"audit_log.json", exportedAuditEntries},
+	} {
+		f, err := w.Create(file.name)
+		if err != nil {
+			return nil, domain.InternalError{Err: err}
+		}
+
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err = encoder.Encode(file.data); err != nil {
+			return nil, domain.InternalError{Err: err}
+		}
+	}
+
+	if u.Avatar != "" {
+		err = uc.addAvatarToArchive(ctx, w, u.Avatar)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = w.Close(); err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// listAllAuditEntries pages through the audit log for every action taken on the user.
+func (uc UserUseCase) listAllAuditEntries(ctx context.Context, userId domain.EntityId) ([]domain.AuditEntry, error) {
+	var entries []domain.AuditEntry
+	search := domain.AuditSearch{TargetUserId: userId, Limit: domain.MaxAuditPageSize}
+	for {
+		page, err := uc.repo.audit.List(ctx, search)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, page...)
+
+		if uint64(len(page)) < search.Limit {
+			return entries, nil
+		}
+		search.BeforeId = page[len(page)-1].Id
+	}
+}
+
+func (uc UserUseCase) addAvatarToArchive(ctx context.Context, w *zip.Writer, avatar string) error {
+	content, err := uc.storage.Open(ctx, avatar)
+	if errors.Is(err, fs.ErrNotExist) {
+		// The profile still names the avatar, but there is nothing to add.
+		return nil
+	} else if err != nil {
+		return domain.ServiceError{Name: "storage", Err: err}
+	}
+	defer content.Close()
+
+	f, err := w.Create("avatar/" + avatar)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	_, err = io.Copy(f, io.LimitReader(content, domain.MaxAvatarSize))
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	return nil
+}
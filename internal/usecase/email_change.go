@@ -0,0 +1,179 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PanziApp/backend/internal/domain"
+)
+
+// RequestEmailChange stores the new address as pending and mails a confirmation
+// link to it, and an alert with a cancel link to the current address. The email
+// only changes once ConfirmEmailChange is called with the confirmation token. A
+// new request supersedes the pending one.
+func (uc UserUseCase) RequestEmailChange(
+	ctx context.Context,
+	principal Principal,
+	password, newEmail string,
+) error {
+	u := principal.User
+	if err := uc.matchPassword(ctx, u, password); err != nil {
+		return err
+	}
+
+	validEmail, err := domain.ValidateEmail(newEmail)
+	if err != nil {
+		return err
+	}
+
+	if validEmail == u.Email {
+		return domain.ErrEmailUnchanged
+	}
+
+	_, err = uc.repo.user.GetByEmail(ctx, validEmail)
+	if err == nil {
+		return domain.ErrDuplicateEmail
+	} else if !errors.Is(err, domain.ErrUserNotFound) {
+		return err
+	}
+
+	err = uc.repo.user.Update(ctx, u.Id, domain.EntityUpdate{domain.UserPendingEmailFieldName: validEmail})
+	if err != nil {
+		return err
+	}
+
+	// The tokens share a family, so using either one revokes the other.
+	family, err := domain.RandomFamily()
+	if err != nil {
+		return err
+	}
+
+	confirmation, err := uc.createSupersedingSession(ctx, domain.Session{
+		UserId: u.Id,
+		Type:   domain.EmailChangeToken,
+		Family: family,
+	}, domain.EmailChangeTokenLifetime)
+	if err != nil {
+		return err
+	}
+
+	cancellation, err := uc.createSupersedingSession(ctx, domain.Session{
+		UserId: u.Id,
+		Type:   domain.EmailChangeCancelToken,
+		Family: family,
+	}, domain.EmailChangeTokenLifetime)
+	if err != nil {
+		return err
+	}
+
+	err = uc.mailer.Send(
+		ctx,
+		string(validEmail),
+		string(u.Fullname),
+		"Confirm Your New Email",
+		domain.EmailChangeConfirmationMessage(string(confirmation.Token)),
+	)
+	if err != nil {
+		return err
+	}
+
+	return uc.mailer.Send(
+		ctx,
+		string(u.Email),
+		string(u.Fullname),
+		"Email Change Requested",
+		domain.EmailChangeAlertMessage(string(validEmail), string(cancellation.Token)),
+	)
+}
+
+// ConfirmEmailChange swaps the email of the user to the pending address. The
+// address is verified by the confirmation, and the links mailed to the old
+// address are revoked.
+func (uc UserUseCase) ConfirmEmailChange(
+	ctx context.Context,
+	token string,
+) error {
+	validToken, err := domain.ValidateToken(token, domain.EmailChangeToken)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	s, err := uc.repo.session.Redeem(ctx, validToken, domain.EmailChangeToken, now)
+	if err != nil {
+		return err
+	}
+
+	err = uc.repo.session.RevokeFamily(ctx, s.Family, now)
+	if err != nil {
+		return err
+	}
+
+	u, err := uc.repo.user.Get(ctx, s.UserId)
+	if err != nil {
+		return err
+	}
+
+	if u.PendingEmail == "" {
+		return domain.ErrInvalidToken
+	}
+
+	// The address may have been registered since the change was requested, the
+	// unique index of the email catches the ones registered concurrently.
+	other, err := uc.repo.user.GetByEmail(ctx, u.PendingEmail)
+	if err == nil && other.Id != u.Id {
+		return domain.ErrDuplicateEmail
+	} else if err != nil && !errors.Is(err, domain.ErrUserNotFound) {
+		return err
+	}
+
+	err = uc.repo.user.Update(ctx, u.Id, domain.EntityUpdate{
+		domain.UserEmailFieldName:           u.PendingEmail,
+		domain.UserPendingEmailFieldName:    domain.Email(""),
+		domain.UserEmailVerifyTimeFieldName: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	return uc.repo.session.RevokeByUserId(
+		ctx,
+		u.Id,
+		[]domain.TokenType{
+			domain.EmailVerificationToken,
+			domain.ResetPasswordToken,
+			domain.SignInLinkToken,
+			domain.SignInCodeToken,
+		},
+		domain.Session{},
+		now,
+	)
+}
+
+// CancelEmailChange drops the pending address with the cancel link mailed to
+// the current address.
+func (uc UserUseCase) CancelEmailChange(
+	ctx context.Context,
+	token string,
+) error {
+	validToken, err := domain.ValidateToken(token, domain.EmailChangeCancelToken)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	s, err := uc.repo.session.Redeem(ctx, validToken, domain.EmailChangeCancelToken, now)
+	if err != nil {
+		return err
+	}
+
+	err = uc.repo.session.RevokeFamily(ctx, s.Family, now)
+	if err != nil {
+		return err
+	}
+
+	return uc.repo.user.Update(ctx, s.UserId, domain.EntityUpdate{
+		domain.UserPendingEmailFieldName: domain.Email(""),
+	})
+}
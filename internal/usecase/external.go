@@ -0,0 +1,351 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/PanziApp/backend/internal/domain"
+	"github.com/PanziApp/backend/pkg/oidc"
+)
+
+// ExternalSignInDTO holds the outcome of an external sign in. Either the user
+// signed in, the provider was linked to the signed in user, or the provider
+// account matches the email of an existing user, who is mailed a link to
+// confirm linking it with ConfirmExternalLink.
+type ExternalSignInDTO struct {
+	SignIn      SignInDTO
+	Linked      bool
+	LinkPending bool
+	LinkEmail   string
+}
+
+type ExternalIdentityDTO struct {
+	Id          string
+	Provider    string
+	Email       string
+	LinkTime    time.Time
+	LastUseTime *time.Time
+}
+
+// BeginExternalSignIn returns the URL of the provider to send the user to.
+func (uc UserUseCase) BeginExternalSignIn(
+	ctx context.Context,
+	provider string,
+) (string, error) {
+	return uc.beginExternalSignIn(ctx, provider, nil)
+}
+
+// BeginExternalLink returns the URL of the provider to send the user to, the
+// provider account is linked to the user when they come back.
+func (uc UserUseCase) BeginExternalLink(
+	ctx context.Context,
+	principal Principal,
+	provider string,
+) (string, error) {
+	return uc.beginExternalSignIn(ctx, provider, &principal.User.Id)
+}
+
+func (uc UserUseCase) beginExternalSignIn(
+	ctx context.Context,
+	provider string,
+	userId *domain.EntityId,
+) (string, error) {
+	p, ok := uc.externalProviders[provider]
+	if !ok {
+		return "", domain.ErrUnknownProvider
+	}
+
+	state, err := domain.RandomToken(domain.ExternalStateToken)
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := domain.RandomStringURLSafe(24)
+	if err != nil {
+		return "", err
+	}
+
+	codeVerifier, err := domain.RandomStringURLSafe(48)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, string(state), nonce, oidc.CodeChallenge(codeVerifier))
+	if err != nil {
+		return "", domain.ServiceError{Name: "oidc", Err: err}
+	}
+
+	now := time.Now()
+
+	err = uc.repo.external.CreateSignIn(ctx, domain.ExternalSignIn{
+		CreateTime:   now,
+		Provider:     provider,
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		UserId:       userId,
+		ValidUntil:   now.Add(domain.ExternalSignInLifetime),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return authURL, nil
+}
+
+// FinishExternalSignIn completes the authorization the provider redirected back
+// from with the code and state. Provider accounts are matched by their subject,
+// a new user is created for an unknown account with an unregistered email.
+func (uc UserUseCase) FinishExternalSignIn(
+	ctx context.Context,
+	provider, state, code string,
+	meta domain.SessionMeta,
+) (r ExternalSignInDTO, err error) {
+	p, ok := uc.externalProviders[provider]
+	if !ok {
+		return r, domain.ErrUnknownProvider
+	}
+
+	validState, err := domain.ValidateToken(state, domain.ExternalStateToken)
+	if err != nil {
+		return r, err
+	}
+
+	now := time.Now()
+
+	s, err := uc.repo.external.RedeemSignIn(ctx, validState, now)
+	if err != nil {
+		return r, err
+	}
+
+	if s.Provider != provider {
+		return r, domain.ErrInvalidToken
+	}
+
+	identity, err := p.Exchange(ctx, code, s.CodeVerifier, s.Nonce)
+	if errors.Is(err, oidc.ErrDiscovery) {
+		return r, domain.ServiceError{Name: "oidc", Err: err}
+	} else if err != nil {
+		return r, domain.ErrExternalSignInFailed
+	}
+
+	existing, err := uc.repo.external.GetByProviderSubject(ctx, provider, identity.Subject)
+	if err != nil && !errors.Is(err, domain.ErrExternalIdentityNotFound) {
+		return r, err
+	}
+	linked := err == nil && existing.Confirmed()
+
+	if s.UserId != nil {
+		r.Linked = true
+
+		if linked {
+			if existing.UserId != *s.UserId {
+				return r, domain.ErrExternalIdentityInUse
+			}
+
+			return r, nil
+		}
+
+		_, err = uc.repo.external.Link(ctx, domain.ExternalIdentity{
+			CreateTime:  now,
+			UserId:      *s.UserId,
+			Provider:    provider,
+			Subject:     identity.Subject,
+			Email:       identity.Email,
+			ConfirmTime: &now,
+		})
+		return r, err
+	}
+
+	if linked {
+		user, err := uc.repo.user.Get(ctx, existing.UserId)
+		if err != nil {
+			return r, err
+		}
+
+		err = uc.repo.external.Update(ctx, existing.Id, domain.EntityUpdate{
+			domain.ExternalIdentityLastUseTimeFieldName: now,
+		})
+		if err != nil {
+			return r, err
+		}
+
+		r.SignIn, err = uc.completeSignIn(ctx, user, meta)
+		return r, err
+	}
+
+	return uc.signInNewExternalIdentity(ctx, provider, identity, meta)
+}
+
+// signInNewExternalIdentity mails the user with the email of the provider account
+// a link to confirm linking it, or signs up a new user. The link is only mailed,
+// so holding a provider account with the email of a user is not enough to
+// sign in as them.
+func (uc UserUseCase) signInNewExternalIdentity(
+	ctx context.Context,
+	provider string,
+	identity oidc.Identity,
+	meta domain.SessionMeta,
+) (r ExternalSignInDTO, err error) {
+	if identity.Email == "" {
+		return r, domain.ErrExternalEmailRequired
+	}
+
+	validEmail, err := domain.ValidateEmail(identity.Email)
+	if err != nil {
+		return r, err
+	}
+
+	now := time.Now()
+
+	user, err := uc.repo.user.GetByEmail(ctx, validEmail)
+	if err == nil {
+		// Linking by email is safe only when the provider verified the email,
+		// and the user still has to confirm it.
+		if !identity.EmailVerified {
+			return r, domain.ErrExternalEmailNotVerified
+		}
+
+		token, err := domain.RandomToken(domain.ExternalLinkToken)
+		if err != nil {
+			return r, err
+		}
+
+		validUntil := now.Add(domain.ExternalLinkConfirmLifetime)
+
+		_, err = uc.repo.external.Link(ctx, domain.ExternalIdentity{
+			CreateTime:        now,
+			UserId:            user.Id,
+			Provider:          provider,
+			Subject:           identity.Subject,
+			Email:             identity.Email,
+			ConfirmToken:      &token,
+			ConfirmValidUntil: &validUntil,
+		})
+		if err != nil {
+			return r, err
+		}
+
+		err = uc.mailer.Send(
+			ctx,
+			string(user.Email),
+			string(user.Fullname),
+			"Link Your Account",
+			domain.ExternalLinkEmailMessage(provider, string(token)),
+		)
+		if err != nil {
+			return r, err
+		}
+
+		r.LinkPending = true
+		r.LinkEmail = string(user.Email)
+
+		return r, nil
+	} else if !errors.Is(err, domain.ErrUserNotFound) {
+		return r, err
+	}
+
+	user = domain.User{
+		CreateTime: now,
+		Email:      validEmail,
+		// The user has no password until they reset it.
+		HashedPassword: domain.HashedPassword{},
+	}
+	if identity.EmailVerified {
+		user.EmailVerifyTime = &now
+	}
+	if fullname, err := domain.ValidateFullname(identity.Name); err == nil {
+		user.Fullname = fullname
+	}
+
+	user.Id, err = uc.repo.user.Create(ctx, user)
+	if err != nil {
+		return r, err
+	}
+
+	_, err = uc.repo.external.Link(ctx, domain.ExternalIdentity{
+		CreateTime:  now,
+		UserId:      user.Id,
+		Provider:    provider,
+		Subject:     identity.Subject,
+		Email:       identity.Email,
+		ConfirmTime: &now,
+	})
+	if err != nil {
+		return r, err
+	}
+
+	if user.EmailVerifyTime == nil {
+		err = uc.sendEmailVerification(ctx, user)
+		if err != nil {
+			return r, err
+		}
+	}
+
+	r.SignIn.Tokens, err = uc.startSession(ctx, user, meta)
+	return r, err
+}
+
+// ConfirmExternalLink links the provider account to the user with the same email
+// with the token mailed to them, and signs them in. Two-factor authentication is
+// still asked for when it is enabled.
+func (uc UserUseCase) ConfirmExternalLink(
+	ctx context.Context,
+	linkToken string,
+	meta domain.SessionMeta,
+) (r SignInDTO, err error) {
+	validToken, err := domain.ValidateToken(linkToken, domain.ExternalLinkToken)
+	if err != nil {
+		return r, err
+	}
+
+	identity, err := uc.repo.external.Confirm(ctx, validToken, time.Now())
+	if err != nil {
+		return r, err
+	}
+
+	user, err := uc.repo.user.Get(ctx, identity.UserId)
+	if err != nil {
+		return r, err
+	}
+
+	return uc.completeSignIn(ctx, user, meta)
+}
+
+func (uc UserUseCase) ListExternalIdentities(
+	ctx context.Context,
+	principal Principal,
+) ([]ExternalIdentityDTO, error) {
+	identities, err := uc.repo.external.ListByUserId(ctx, principal.User.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]ExternalIdentityDTO, 0, len(identities))
+	for _, i := range identities {
+		dtos = append(dtos, ExternalIdentityDTO{
+			Id:          strconv.FormatInt(int64(i.Id), 10),
+			Provider:    i.Provider,
+			Email:       i.Email,
+			LinkTime:    *i.ConfirmTime,
+			LastUseTime: i.LastUseTime,
+		})
+	}
+
+	return dtos, nil
+}
+
+func (uc UserUseCase) UnlinkExternalIdentity(
+	ctx context.Context,
+	principal Principal,
+	identityId string,
+) error {
+	id, err := strconv.ParseInt(identityId, 10, 64)
+	if err != nil {
+		return domain.ErrExternalIdentityNotFound
+	}
+
+	return uc.repo.external.Delete(ctx, principal.User.Id, domain.EntityId(id))
+}
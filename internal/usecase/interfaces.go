@@ -3,6 +3,10 @@ package usecase
 import (
 	"context"
 	"github.com/PanziApp/backend/internal/domain"
+	"github.com/PanziApp/backend/pkg/oidc"
+	"github.com/PanziApp/backend/pkg/webauthn"
+	"io"
+	"time"
 )
 
 type (
@@ -13,14 +17,136 @@ type (
 		GetByEmail(ctx context.Context, email domain.Email) (domain.User, error)
 
 		Update(ctx context.Context, userId domain.EntityId, updates domain.EntityUpdate) error
+		UseTotpStep(ctx context.Context, userId domain.EntityId, step int64) error
+		ListDeleted(ctx context.Context, now time.Time, limit uint64) ([]domain.User, error)
+		Search(ctx context.Context, search domain.UserSearch) ([]domain.User, error)
+		DeleteDue(ctx context.Context, userId domain.EntityId, now time.Time) error
 	}
 
 	SessionRepository interface {
 		Create(ctx context.Context, session domain.Session) (sessionId domain.EntityId, err error)
 
+		Get(ctx context.Context, sessionId domain.EntityId) (domain.Session, error)
 		GetByToken(ctx context.Context, token domain.Token) (domain.Session, error)
+		GetLastByUserId(ctx context.Context, userId domain.EntityId, tokenType domain.TokenType) (domain.Session, error)
+		ListByUserId(
+			ctx context.Context,
+			userId domain.EntityId,
+			tokenType domain.TokenType,
+			validAt time.Time,
+		) ([]domain.Session, error)
+		ListAllByUserId(ctx context.Context, userId domain.EntityId) ([]domain.Session, error)
 
 		Update(ctx context.Context, sessionId domain.EntityId, updates domain.EntityUpdate) error
+		Use(ctx context.Context, sessionId domain.EntityId, useTime time.Time) error
+		AddAttempt(ctx context.Context, sessionId domain.EntityId, maxAttempts int, attemptTime time.Time) error
+		Redeem(
+			ctx context.Context,
+			token domain.Token,
+			tokenType domain.TokenType,
+			useTime time.Time,
+		) (domain.Session, error)
+		RevokeFamily(ctx context.Context, family string, revokeTime time.Time) error
+		RevokeByUserId(
+			ctx context.Context,
+			userId domain.EntityId,
+			tokenTypes []domain.TokenType,
+			except domain.Session,
+			revokeTime time.Time,
+		) error
+		RevokeAllByUserId(ctx context.Context, userId domain.EntityId, revokeTime time.Time) (revoked int, err error)
+	}
+
+	RecoveryCodeRepository interface {
+		Replace(ctx context.Context, userId domain.EntityId, codes []domain.HashedRecoveryCode) error
+		Use(ctx context.Context, userId domain.EntityId, code domain.HashedRecoveryCode, useTime time.Time) error
+	}
+
+	PasskeyRepository interface {
+		Create(ctx context.Context, p domain.Passkey) (domain.EntityId, error)
+		GetByCredentialId(ctx context.Context, credentialId []byte) (domain.Passkey, error)
+		ListByUserId(ctx context.Context, userId domain.EntityId) ([]domain.Passkey, error)
+		Update(ctx context.Context, passkeyId domain.EntityId, updates domain.EntityUpdate) error
+		Delete(ctx context.Context, userId domain.EntityId, credentialId []byte) error
+		CreateChallenge(ctx context.Context, c domain.PasskeyChallenge) error
+		RedeemChallenge(
+			ctx context.Context,
+			challenge domain.Token,
+			challengeType domain.PasskeyChallengeType,
+			useTime time.Time,
+		) (domain.PasskeyChallenge, error)
+	}
+
+	ExternalIdentityRepository interface {
+		Link(ctx context.Context, i domain.ExternalIdentity) (domain.EntityId, error)
+		GetByProviderSubject(ctx context.Context, provider, subject string) (domain.ExternalIdentity, error)
+		ListByUserId(ctx context.Context, userId domain.EntityId) ([]domain.ExternalIdentity, error)
+		Confirm(ctx context.Context, token domain.Token, confirmTime time.Time) (domain.ExternalIdentity, error)
+		Update(ctx context.Context, identityId domain.EntityId, updates domain.EntityUpdate) error
+		Delete(ctx context.Context, userId domain.EntityId, identityId domain.EntityId) error
+		CreateSignIn(ctx context.Context, s domain.ExternalSignIn) error
+		RedeemSignIn(ctx context.Context, state domain.Token, useTime time.Time) (domain.ExternalSignIn, error)
+	}
+
+	OAuthRepository interface {
+		CreateClient(ctx context.Context, c domain.OAuthClient) (domain.EntityId, error)
+		GetClient(ctx context.Context, clientId domain.EntityId) (domain.OAuthClient, error)
+		GetClientByClientId(ctx context.Context, clientId string) (domain.OAuthClient, error)
+		ListClientsByOwnerId(ctx context.Context, ownerId domain.EntityId) ([]domain.OAuthClient, error)
+		DeleteClient(ctx context.Context, ownerId domain.EntityId, clientId string) error
+		GetConsent(ctx context.Context, userId, clientId domain.EntityId) (domain.OAuthConsent, error)
+		SaveConsent(ctx context.Context, c domain.OAuthConsent) error
+		CreateAuthorizationCode(ctx context.Context, c domain.OAuthAuthorizationCode) error
+		GetAuthorizationCode(ctx context.Context, code domain.Token) (domain.OAuthAuthorizationCode, error)
+		UseAuthorizationCode(
+			ctx context.Context,
+			code domain.Token,
+			useTime time.Time,
+		) (domain.OAuthAuthorizationCode, error)
+	}
+
+	DataExportRepository interface {
+		Create(ctx context.Context, e domain.DataExport) (domain.EntityId, error)
+		GetByToken(ctx context.Context, token domain.Token) (domain.DataExport, error)
+		GetLastByUserId(ctx context.Context, userId domain.EntityId) (domain.DataExport, error)
+		ListByUserId(ctx context.Context, userId domain.EntityId) ([]domain.DataExport, error)
+		ListPending(ctx context.Context, now time.Time, maxAttempts int, limit uint64) ([]domain.DataExport, error)
+		ListExpired(ctx context.Context, now time.Time, limit uint64) ([]domain.DataExport, error)
+		Update(ctx context.Context, exportId domain.EntityId, updates domain.EntityUpdate) error
+		RecordFailure(ctx context.Context, exportId domain.EntityId, retryTime time.Time) error
+		Delete(ctx context.Context, exportId domain.EntityId) error
+	}
+
+	SignInFailureRepository interface {
+		Get(ctx context.Context, key string) (domain.SignInFailures, error)
+		GetForUpdate(ctx context.Context, key string, now time.Time) (domain.SignInFailures, error)
+		Record(ctx context.Context, key string, now, since time.Time) (domain.SignInFailures, error)
+		Lock(ctx context.Context, key string, until time.Time) error
+		Clear(ctx context.Context, key string) error
+	}
+
+	RoleRepository interface {
+		Create(ctx context.Context, role domain.Role) (domain.EntityId, error)
+		Get(ctx context.Context, roleId domain.EntityId) (domain.Role, error)
+		GetByName(ctx context.Context, name string) (domain.Role, error)
+		List(ctx context.Context) ([]domain.Role, error)
+		ListByUserId(ctx context.Context, userId domain.EntityId) ([]domain.Role, error)
+		Update(ctx context.Context, roleId domain.EntityId, updates domain.EntityUpdate) error
+		Delete(ctx context.Context, roleId domain.EntityId) error
+		Assign(ctx context.Context, userId, roleId domain.EntityId, assignTime time.Time) error
+		Unassign(ctx context.Context, userId, roleId domain.EntityId) error
+		CountUsers(ctx context.Context, roleId domain.EntityId) (int, error)
+	}
+
+	AuditRepository interface {
+		Create(ctx context.Context, e domain.AuditEntry) error
+		List(ctx context.Context, search domain.AuditSearch) ([]domain.AuditEntry, error)
+	}
+
+	// Transactor runs fn in a transaction, which the repositories called with
+	// the context passed to fn join.
+	Transactor interface {
+		InTx(ctx context.Context, fn func(ctx context.Context) error) error
 	}
 )
 
@@ -28,4 +154,32 @@ type (
 	Mailer interface {
 		Send(ctx context.Context, receiver, name, subject, messageInHtml string) error
 	}
+
+	// BreachedPasswords tells whether passwords appeared in data breaches.
+	BreachedPasswords interface {
+		Contains(ctx context.Context, password []byte) (bool, error)
+	}
+
+	TokenSigner interface {
+		Sign(claims interface{}) (string, error)
+		Verify(token string, claims interface{}) error
+	}
+
+	RelyingParty interface {
+		CreationOptions(challenge []byte, user webauthn.User, exclude []webauthn.CredentialDescriptor) webauthn.CreationOptions
+		RequestOptions(challenge []byte, allow []webauthn.CredentialDescriptor) webauthn.RequestOptions
+		VerifyRegistration(challenge []byte, r webauthn.AttestationResponse) (webauthn.Credential, error)
+		VerifyAssertion(challenge []byte, r webauthn.AssertionResponse, c webauthn.Credential) (uint32, error)
+	}
+
+	ExternalProvider interface {
+		AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+		Exchange(ctx context.Context, code, codeVerifier, nonce string) (oidc.Identity, error)
+	}
+
+	FileStorage interface {
+		Save(ctx context.Context, name string, content io.Reader) error
+		Open(ctx context.Context, name string) (io.ReadCloser, error)
+		Remove(ctx context.Context, name string) error
+	}
 )
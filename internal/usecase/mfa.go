@@ -0,0 +1,230 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PanziApp/backend/internal/domain"
+)
+
+type TotpEnrollmentDTO struct {
+	Secret string
+	URI    string
+}
+
+// BeginTotpEnrollment generates the secret of the authenticator app to add,
+// two-factor authentication is enabled once ConfirmTotpEnrollment gets a code of it.
+func (uc UserUseCase) BeginTotpEnrollment(
+	ctx context.Context,
+	principal Principal,
+) (e TotpEnrollmentDTO, err error) {
+	u := principal.User
+	if u.MfaEnabled() {
+		return e, domain.ErrMfaAlreadyEnabled
+	}
+
+	secret, err := domain.RandomTotpSecret()
+	if err != nil {
+		return e, err
+	}
+
+	err = uc.repo.user.Update(ctx, u.Id, domain.EntityUpdate{domain.UserTotpSecretFieldName: secret})
+	if err != nil {
+		return e, err
+	}
+
+	return TotpEnrollmentDTO{
+		Secret: string(secret),
+		URI:    secret.URI(uc.totpIssuer, u.Email),
+	}, nil
+}
+
+// ConfirmTotpEnrollment enables two-factor authentication and returns the recovery codes.
+func (uc UserUseCase) ConfirmTotpEnrollment(
+	ctx context.Context,
+	principal Principal,
+	code string,
+) ([]string, error) {
+	u := principal.User
+	if u.MfaEnabled() {
+		return nil, domain.ErrMfaAlreadyEnabled
+	}
+	if u.TotpSecret == "" {
+		return nil, domain.ErrMfaNotBeingEnabled
+	}
+
+	now := time.Now()
+
+	step, err := u.TotpSecret.Verify(code, now, u.TotpLastStep)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.repo.user.Update(ctx, u.Id, domain.EntityUpdate{
+		domain.UserTotpEnableTimeFieldName: now,
+		domain.UserTotpLastStepFieldName:   step,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.replaceRecoveryCodes(ctx, u.Id)
+}
+
+// RegenerateRecoveryCodes invalidates the recovery codes of the user and returns new ones.
+func (uc UserUseCase) RegenerateRecoveryCodes(
+	ctx context.Context,
+	principal Principal,
+	password string,
+) ([]string, error) {
+	u := principal.User
+	if !u.MfaEnabled() {
+		return nil, domain.ErrMfaNotEnabled
+	}
+
+	if err := uc.matchPassword(ctx, u, password); err != nil {
+		return nil, err
+	}
+
+	return uc.replaceRecoveryCodes(ctx, u.Id)
+}
+
+func (uc UserUseCase) DisableMfa(
+	ctx context.Context,
+	principal Principal,
+	password string,
+) error {
+	u := principal.User
+	if !u.MfaEnabled() {
+		return domain.ErrMfaNotEnabled
+	}
+
+	if err := uc.matchPassword(ctx, u, password); err != nil {
+		return err
+	}
+
+	err := uc.repo.user.Update(ctx, u.Id, domain.EntityUpdate{
+		domain.UserTotpSecretFieldName:     domain.TotpSecret(""),
+		domain.UserTotpEnableTimeFieldName: nil,
+		domain.UserTotpLastStepFieldName:   int64(0),
+	})
+	if err != nil {
+		return err
+	}
+
+	return uc.repo.recoveryCode.Replace(ctx, u.Id, nil)
+}
+
+// VerifyMfa completes the sign in challenge with an authenticator app code or
+// a recovery code. The challenge is revoked after too many attempts, and each
+// authenticator app code is accepted once.
+func (uc UserUseCase) VerifyMfa(
+	ctx context.Context,
+	mfaToken, code string,
+) (t TokensDTO, err error) {
+	validToken, err := domain.ValidateToken(mfaToken, domain.MfaChallengeToken)
+	if err != nil {
+		return t, err
+	}
+
+	s, err := uc.repo.session.GetByToken(ctx, validToken)
+	if err != nil {
+		return t, err
+	}
+
+	now := time.Now()
+	if s.Type != domain.MfaChallengeToken || s.UseTime != nil || (s.ValidUntil != nil && s.ValidUntil.Before(now)) {
+		return t, domain.ErrInvalidToken
+	}
+
+	err = uc.repo.session.AddAttempt(ctx, s.Id, domain.MfaChallengeMaxAttempts, now)
+	if err != nil {
+		return t, err
+	}
+
+	u, err := uc.repo.user.Get(ctx, s.UserId)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return t, domain.ErrInvalidToken
+	} else if err != nil {
+		return t, err
+	}
+
+	if !u.MfaEnabled() {
+		return t, domain.ErrInvalidToken
+	}
+
+	step, err := u.TotpSecret.Verify(code, now, u.TotpLastStep)
+	if err == nil {
+		err = uc.repo.user.UseTotpStep(ctx, u.Id, step)
+	}
+	if errors.Is(err, domain.ErrInvalidMfaCode) {
+		err = uc.repo.recoveryCode.Use(ctx, u.Id, domain.RecoveryCode(code).Hash(), now)
+	}
+	if err != nil {
+		return t, err
+	}
+
+	_, err = uc.repo.session.Redeem(ctx, validToken, domain.MfaChallengeToken, now)
+	if err != nil {
+		return t, err
+	}
+
+	return uc.startSession(ctx, u, s.Meta)
+}
+
+func (uc UserUseCase) createMfaChallenge(
+	ctx context.Context,
+	userId domain.EntityId,
+	meta domain.SessionMeta,
+) (string, error) {
+	validUntil := time.Now().Add(domain.MfaChallengeTokenLifetime)
+
+	s, err := uc.createSession(ctx, domain.Session{
+		UserId:     userId,
+		Type:       domain.MfaChallengeToken,
+		ValidUntil: &validUntil,
+		Meta:       meta,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(s.Token), nil
+}
+
+func (uc UserUseCase) replaceRecoveryCodes(ctx context.Context, userId domain.EntityId) ([]string, error) {
+	codes := make([]string, 0, domain.RecoveryCodeCount)
+	hashes := make([]domain.HashedRecoveryCode, 0, domain.RecoveryCodeCount)
+
+	for i := 0; i < domain.RecoveryCodeCount; i++ {
+		code, err := domain.RandomRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, string(code))
+		hashes = append(hashes, code.Hash())
+	}
+
+	err := uc.repo.recoveryCode.Replace(ctx, userId, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// matchPassword checks the password the user re-entered to confirm a sensitive
+// operation. Failures count towards the sign in lockout of the email, so a stolen
+// session cannot be used to guess the password either.
+func (uc UserUseCase) matchPassword(ctx context.Context, u domain.User, password string) error {
+	return uc.matchThrottled(ctx, u.Email, "", func(ctx context.Context) (*domain.User, error) {
+		validPassword, err := domain.ValidatePassword(password)
+		if err != nil {
+			return &u, domain.ErrInvalidPassword
+		}
+
+		return &u, uc.passwordHasher.Match(u.HashedPassword, validPassword)
+	})
+}
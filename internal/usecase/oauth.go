@@ -0,0 +1,801 @@
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/PanziApp/backend/internal/domain"
+	"github.com/PanziApp/backend/pkg/oidc"
+)
+
+type OAuthClientDTO struct {
+	ClientId string
+	// ClientSecret is only returned when the client is registered.
+	ClientSecret string
+	Name         string
+	Confidential bool
+	RedirectURIs []string
+	GrantTypes   []string
+	Scopes       []string
+	Trusted      bool
+	CreateTime   time.Time
+}
+
+func newOAuthClientDTO(c domain.OAuthClient) OAuthClientDTO {
+	return OAuthClientDTO{
+		ClientId:     c.ClientId,
+		Name:         c.Name,
+		Confidential: c.Confidential(),
+		RedirectURIs: c.RedirectURIs,
+		GrantTypes:   c.GrantTypes,
+		Scopes:       c.Scopes,
+		Trusted:      c.Trusted,
+		CreateTime:   c.CreateTime,
+	}
+}
+
+// RegisterOAuthClient registers a client owned by the user. Confidential clients
+// get a secret, which is only returned now. Trusted clients, which may introspect
+// tokens, and client credentials clients, which act on their own, need the
+// oauth_clients:manage permission.
+func (uc UserUseCase) RegisterOAuthClient(
+	ctx context.Context,
+	principal Principal,
+	name string,
+	confidential, trusted bool,
+	redirectURIs, grantTypes, scopes []string,
+) (r OAuthClientDTO, err error) {
+	validName, err := domain.ValidateOAuthClientName(name)
+	if err != nil {
+		return r, err
+	}
+
+	client := domain.OAuthClient{
+		CreateTime:   time.Now(),
+		OwnerId:      principal.User.Id,
+		Name:         validName,
+		RedirectURIs: redirectURIs,
+		GrantTypes:   domain.ParseScope(domain.FormatScope(grantTypes)),
+		Scopes:       domain.ParseScope(domain.FormatScope(scopes)),
+		Trusted:      trusted,
+	}
+	if client.RedirectURIs == nil {
+		client.RedirectURIs = []string{}
+	}
+
+	if client.Trusted || client.AllowsGrant(domain.ClientCredentialsGrant) {
+		if err = principal.Authorize(domain.PermissionManageOAuthClients); err != nil {
+			return r, err
+		}
+	}
+
+	client.ClientId, err = domain.RandomClientId()
+	if err != nil {
+		return r, err
+	}
+
+	var secret domain.ClientSecret
+	if confidential {
+		secret, err = domain.RandomClientSecret()
+		if err != nil {
+			return r, err
+		}
+		client.HashedSecret = secret.Hash()
+	}
+
+	if err = client.Validate(uc.redirectSchemes); err != nil {
+		return r, err
+	}
+
+	client.Id, err = uc.repo.oauth.CreateClient(ctx, client)
+	if err != nil {
+		return r, err
+	}
+
+	r = newOAuthClientDTO(client)
+	r.ClientSecret = string(secret)
+
+	return r, nil
+}
+
+func (uc UserUseCase) ListOAuthClients(
+	ctx context.Context,
+	principal Principal,
+) ([]OAuthClientDTO, error) {
+	clients, err := uc.repo.oauth.ListClientsByOwnerId(ctx, principal.User.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]OAuthClientDTO, 0, len(clients))
+	for _, c := range clients {
+		dtos = append(dtos, newOAuthClientDTO(c))
+	}
+
+	return dtos, nil
+}
+
+// DeleteOAuthClient deletes a client of the user, revoking every token issued to it.
+func (uc UserUseCase) DeleteOAuthClient(
+	ctx context.Context,
+	principal Principal,
+	clientId string,
+) error {
+	return uc.repo.oauth.DeleteClient(ctx, principal.User.Id, clientId)
+}
+
+// AuthorizationRequestDTO holds the parameters of an OAuth authorization
+// request, RFC 6749 section 4.1.1 and RFC 7636 section 4.3.
+type AuthorizationRequestDTO struct {
+	ResponseType        string
+	ClientId            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// OAuthConsentDTO is what the user is asked to authorize.
+type OAuthConsentDTO struct {
+	ClientId   string
+	ClientName string
+	Scopes     []string
+	// Granted tells the user already consented to the scopes, so the client
+	// can be authorized without asking again.
+	Granted bool
+}
+
+// validateAuthorizationRequest returns the client and requested scopes of a
+// valid request, and the uri to redirect the user back to.
+func (uc UserUseCase) validateAuthorizationRequest(
+	ctx context.Context,
+	req AuthorizationRequestDTO,
+) (client domain.OAuthClient, scopes []string, redirectURI string, err error) {
+	client, err = uc.repo.oauth.GetClientByClientId(ctx, req.ClientId)
+	if err != nil {
+		return client, nil, "", err
+	}
+
+	redirectURI = req.RedirectURI
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !client.AllowsRedirectURI(redirectURI) {
+		return client, nil, "", domain.ErrInvalidRedirectURI
+	}
+	// Clients registered before a scheme was disallowed may still have the uri.
+	if err = domain.ValidateRedirectURI(redirectURI, uc.redirectSchemes); err != nil {
+		return client, nil, "", err
+	}
+
+	if req.ResponseType != "code" {
+		return client, nil, "", domain.ErrUnsupportedResponseType
+	}
+	if !client.AllowsGrant(domain.AuthorizationCodeGrant) {
+		return client, nil, "", domain.ErrUnauthorizedClient
+	}
+
+	scopes = domain.ParseScope(req.Scope)
+	if len(scopes) == 0 || !domain.ScopeContains(client.Scopes, scopes) {
+		return client, nil, "", domain.ErrInvalidScope
+	}
+	// ID tokens are signed with the access token keys.
+	if uc.signer == nil && domain.ScopeContains(scopes, []string{domain.OpenIdScope}) {
+		return client, nil, "", domain.ErrInvalidScope
+	}
+
+	if req.CodeChallenge == "" {
+		if !client.Confidential() {
+			return client, nil, "", domain.ErrInvalidRequest
+		}
+	} else if req.CodeChallengeMethod != domain.PkceMethod || len(req.CodeChallenge) != 43 {
+		return client, nil, "", domain.ErrInvalidRequest
+	}
+
+	if len(req.Nonce) > 255 || len(req.State) > 1000 {
+		return client, nil, "", domain.ErrInvalidRequest
+	}
+
+	return client, scopes, redirectURI, nil
+}
+
+// GetOAuthConsent validates an authorization request and returns what the user
+// is asked to authorize. Invalid requests are not redirected back to the client.
+func (uc UserUseCase) GetOAuthConsent(
+	ctx context.Context,
+	principal Principal,
+	req AuthorizationRequestDTO,
+) (r OAuthConsentDTO, err error) {
+	client, scopes, _, err := uc.validateAuthorizationRequest(ctx, req)
+	if err != nil {
+		return r, err
+	}
+
+	r = OAuthConsentDTO{
+		ClientId:   client.ClientId,
+		ClientName: client.Name,
+		Scopes:     scopes,
+	}
+
+	consent, err := uc.repo.oauth.GetConsent(ctx, principal.User.Id, client.Id)
+	if err == nil {
+		r.Granted = domain.ScopeContains(consent.Scopes, scopes)
+	} else if !errors.Is(err, domain.ErrOAuthConsentNotFound) {
+		return r, err
+	}
+
+	return r, nil
+}
+
+// AuthorizeOAuthClient records the decision of the user and returns the uri to
+// redirect them back to the client with, carrying the authorization code when
+// they approved it.
+func (uc UserUseCase) AuthorizeOAuthClient(
+	ctx context.Context,
+	principal Principal,
+	req AuthorizationRequestDTO,
+	approve bool,
+) (string, error) {
+	client, scopes, redirectURI, err := uc.validateAuthorizationRequest(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	if uc.issuer != "" {
+		// RFC 9207, lets clients talking to several servers tell which one answered.
+		params.Set("iss", uc.issuer)
+	}
+
+	if !approve {
+		params.Set("error", domain.ErrAccessDenied.Code)
+		params.Set("error_description", domain.ErrAccessDenied.Description)
+
+		return redirectWithParams(redirectURI, params), nil
+	}
+
+	now := time.Now()
+
+	consent, err := uc.repo.oauth.GetConsent(ctx, principal.User.Id, client.Id)
+	if err != nil && !errors.Is(err, domain.ErrOAuthConsentNotFound) {
+		return "", err
+	}
+
+	err = uc.repo.oauth.SaveConsent(ctx, domain.OAuthConsent{
+		UserId:     principal.User.Id,
+		ClientId:   client.Id,
+		Scopes:     domain.MergeScopes(consent.Scopes, scopes),
+		UpdateTime: now,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	code := domain.OAuthAuthorizationCode{
+		CreateTime:    now,
+		ClientId:      client.Id,
+		UserId:        principal.User.Id,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        scopes,
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		AuthTime:      principal.Session.AuthTime,
+		ValidUntil:    now.Add(domain.OAuthAuthorizationCodeLifetime),
+	}
+
+	code.Code, err = domain.RandomToken(domain.OAuthCodeToken)
+	if err != nil {
+		return "", err
+	}
+
+	code.Family, err = domain.RandomFamily()
+	if err != nil {
+		return "", err
+	}
+
+	err = uc.repo.oauth.CreateAuthorizationCode(ctx, code)
+	if err != nil {
+		return "", err
+	}
+
+	params.Set("code", string(code.Code))
+
+	return redirectWithParams(redirectURI, params), nil
+}
+
+func redirectWithParams(redirectURI string, params url.Values) string {
+	// Redirect uris are validated when the client is registered.
+	u, _ := url.Parse(redirectURI)
+
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+// TokenRequestDTO holds the parameters of a token request, RFC 6749 sections 4.1.3, 4.4.2 and 6.
+type TokenRequestDTO struct {
+	GrantType    string
+	ClientId     string
+	ClientSecret string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	Scope        string
+}
+
+// ExchangeOAuthToken authenticates the client and issues the tokens of the grant.
+func (uc UserUseCase) ExchangeOAuthToken(
+	ctx context.Context,
+	req TokenRequestDTO,
+	meta domain.SessionMeta,
+) (t TokensDTO, err error) {
+	client, err := uc.authenticateOAuthClient(ctx, req.ClientId, req.ClientSecret)
+	if err != nil {
+		return t, err
+	}
+
+	switch req.GrantType {
+	case domain.AuthorizationCodeGrant:
+		return uc.exchangeAuthorizationCode(ctx, client, req, meta)
+	case domain.RefreshTokenGrant:
+		return uc.exchangeRefreshToken(ctx, client, req, meta)
+	case domain.ClientCredentialsGrant:
+		return uc.exchangeClientCredentials(ctx, client, req, meta)
+	default:
+		return t, domain.ErrUnsupportedGrantType
+	}
+}
+
+// authenticateOAuthClient checks the secret of confidential clients, public
+// clients are only identified, their codes are bound to them with PKCE.
+func (uc UserUseCase) authenticateOAuthClient(
+	ctx context.Context,
+	clientId, clientSecret string,
+) (domain.OAuthClient, error) {
+	client, err := uc.repo.oauth.GetClientByClientId(ctx, clientId)
+	if errors.Is(err, domain.ErrOAuthClientNotFound) {
+		return client, domain.ErrInvalidClient
+	} else if err != nil {
+		return client, err
+	}
+
+	if client.Confidential() {
+		return client, client.HashedSecret.Match(domain.ClientSecret(clientSecret))
+	}
+
+	if clientSecret != "" {
+		return client, domain.ErrInvalidClient
+	}
+
+	return client, nil
+}
+
+func (uc UserUseCase) exchangeAuthorizationCode(
+	ctx context.Context,
+	client domain.OAuthClient,
+	req TokenRequestDTO,
+	meta domain.SessionMeta,
+) (t TokensDTO, err error) {
+	if !client.AllowsGrant(domain.AuthorizationCodeGrant) {
+		return t, domain.ErrUnauthorizedClient
+	}
+
+	validCode, err := domain.ValidateToken(req.Code, domain.OAuthCodeToken)
+	if err != nil {
+		return t, domain.ErrInvalidGrant
+	}
+
+	now := time.Now()
+
+	// The code is only used once it is bound to the client, the redirect uri
+	// and the verifier, so other clients cannot burn it.
+	code, err := uc.repo.oauth.GetAuthorizationCode(ctx, validCode)
+	if err != nil {
+		return t, err
+	}
+
+	if code.ClientId != client.Id || code.RedirectURI != req.RedirectURI {
+		return t, domain.ErrInvalidGrant
+	}
+
+	if code.CodeChallenge != "" {
+		if len(req.CodeVerifier) < 43 || len(req.CodeVerifier) > 128 ||
+			subtle.ConstantTimeCompare([]byte(oidc.CodeChallenge(req.CodeVerifier)), []byte(code.CodeChallenge)) != 1 {
+			return t, domain.ErrInvalidGrant
+		}
+	} else if req.CodeVerifier != "" {
+		return t, domain.ErrInvalidGrant
+	}
+
+	if code.UseTime != nil {
+		return t, uc.revokeReusedCode(ctx, code, now)
+	}
+
+	_, err = uc.repo.oauth.UseAuthorizationCode(ctx, validCode, now)
+	if errors.Is(err, domain.ErrInvalidGrant) {
+		// Expired, or used by a concurrent exchange.
+		code, err = uc.repo.oauth.GetAuthorizationCode(ctx, validCode)
+		if err != nil {
+			return t, err
+		}
+
+		return t, uc.revokeReusedCode(ctx, code, now)
+	} else if err != nil {
+		return t, err
+	}
+
+	user, err := uc.repo.user.Get(ctx, code.UserId)
+	if errors.Is(err, domain.ErrUserNotFound) || (err == nil && (user.PendingDeletion() || user.Disabled())) {
+		return t, domain.ErrInvalidGrant
+	} else if err != nil {
+		return t, err
+	}
+
+	t, err = uc.issueTokens(ctx, domain.Session{
+		UserId:   user.Id,
+		Family:   code.Family,
+		AuthTime: code.AuthTime,
+		Scope:    domain.FormatScope(code.Scopes),
+		Meta:     meta,
+	}, &client)
+	if err != nil {
+		return t, err
+	}
+
+	if domain.ScopeContains(code.Scopes, []string{domain.OpenIdScope}) {
+		t.IdToken, err = uc.signIdToken(user, client, code.Scopes, code.Nonce, code.AuthTime, t.AccessTokenValidUntil)
+	}
+
+	return t, err
+}
+
+// revokeReusedCode revokes the tokens a used code was exchanged for when its
+// client presents it again, as it has leaked, RFC 6749 section 4.1.2.
+func (uc UserUseCase) revokeReusedCode(
+	ctx context.Context,
+	code domain.OAuthAuthorizationCode,
+	now time.Time,
+) error {
+	if code.UseTime != nil {
+		err := uc.repo.session.RevokeFamily(ctx, code.Family, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return domain.ErrInvalidGrant
+}
+
+func (uc UserUseCase) exchangeRefreshToken(
+	ctx context.Context,
+	client domain.OAuthClient,
+	req TokenRequestDTO,
+	meta domain.SessionMeta,
+) (t TokensDTO, err error) {
+	if !client.AllowsGrant(domain.RefreshTokenGrant) {
+		return t, domain.ErrUnauthorizedClient
+	}
+
+	s, err := uc.useRefreshToken(ctx, req.RefreshToken, &client.Id)
+	if errors.Is(err, domain.ErrInvalidToken) || errors.Is(err, domain.ErrAccountPendingDeletion) ||
+		errors.Is(err, domain.ErrAccountDisabled) {
+		return t, domain.ErrInvalidGrant
+	} else if err != nil {
+		return t, err
+	}
+
+	// The scope may be narrowed, but not widened beyond what the user consented to.
+	scopes := s.Scopes()
+	if req.Scope != "" {
+		requested := domain.ParseScope(req.Scope)
+		if !domain.ScopeContains(scopes, requested) {
+			return t, domain.ErrInvalidScope
+		}
+		scopes = requested
+	}
+
+	if meta.DeviceName == "" {
+		meta.DeviceName = s.Meta.DeviceName
+	}
+
+	t, err = uc.issueTokens(ctx, domain.Session{
+		UserId:   s.UserId,
+		Family:   s.Family,
+		AuthTime: s.AuthTime,
+		Scope:    domain.FormatScope(scopes),
+		Meta:     meta,
+	}, &client)
+	if err != nil {
+		return t, err
+	}
+
+	if domain.ScopeContains(scopes, []string{domain.OpenIdScope}) {
+		user, err := uc.repo.user.Get(ctx, s.UserId)
+		if err != nil {
+			return t, err
+		}
+
+		t.IdToken, err = uc.signIdToken(user, client, scopes, "", s.AuthTime, t.AccessTokenValidUntil)
+		if err != nil {
+			return t, err
+		}
+	}
+
+	return t, nil
+}
+
+// exchangeClientCredentials issues an access token to the client for itself,
+// for services calling other services.
+func (uc UserUseCase) exchangeClientCredentials(
+	ctx context.Context,
+	client domain.OAuthClient,
+	req TokenRequestDTO,
+	meta domain.SessionMeta,
+) (t TokensDTO, err error) {
+	if !client.AllowsGrant(domain.ClientCredentialsGrant) {
+		return t, domain.ErrUnauthorizedClient
+	}
+
+	scopes := domain.ParseScope(req.Scope)
+	if !domain.ScopeContains(client.Scopes, scopes) || domain.ScopeContains(scopes, []string{domain.OpenIdScope}) {
+		return t, domain.ErrInvalidScope
+	}
+
+	family, err := domain.RandomFamily()
+	if err != nil {
+		return t, err
+	}
+
+	return uc.issueTokens(ctx, domain.Session{
+		Family:   family,
+		AuthTime: time.Now(),
+		Scope:    domain.FormatScope(scopes),
+		Meta:     meta,
+	}, &client)
+}
+
+// RevokeOAuthToken revokes an access or refresh token of the client, RFC 7009.
+// Revoking a refresh token revokes the whole grant. Unknown tokens and tokens of
+// other clients are ignored, as the client could not do anything about them.
+func (uc UserUseCase) RevokeOAuthToken(
+	ctx context.Context,
+	clientId, clientSecret, token string,
+) error {
+	client, err := uc.authenticateOAuthClient(ctx, clientId, clientSecret)
+	if err != nil {
+		return err
+	}
+
+	s, err := uc.tokenSession(ctx, token)
+	if errors.Is(err, domain.ErrInvalidToken) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if !sameEntityId(s.ClientId, &client.Id) {
+		return nil
+	}
+
+	now := time.Now()
+	if s.Type == domain.RefreshToken {
+		return uc.repo.session.RevokeFamily(ctx, s.Family, now)
+	}
+
+	if s.ValidUntil != nil && s.ValidUntil.Before(now) {
+		return nil
+	}
+
+	return uc.repo.session.Update(ctx, s.Id, domain.EntityUpdate{domain.SessionValidUntilFieldName: now})
+}
+
+// TokenIntrospectionDTO describes a token to the service that was presented it.
+// Only Active is set for tokens that are not active.
+type TokenIntrospectionDTO struct {
+	Active bool
+	// UserId is empty for the tokens a client is issued for itself.
+	UserId string
+	// ClientId is the OAuth client the token was issued to, empty for first-party tokens.
+	ClientId string
+	// TokenType is access_token or refresh_token, as in token type hints.
+	TokenType  string
+	Scopes     []string
+	IssueTime  time.Time
+	ValidUntil *time.Time
+	// Permissions are the permissions of the user of a first-party token.
+	Permissions []domain.Permission
+}
+
+// IntrospectToken tells a service whether a token is active and what it grants,
+// RFC 7662. Only trusted clients, registered by admins, may introspect. Access
+// tokens are described to any service, refresh tokens only to the client they
+// were issued to. Tokens of disabled users and accounts pending deletion are
+// inactive.
+func (uc UserUseCase) IntrospectToken(
+	ctx context.Context,
+	clientId, clientSecret, token string,
+) (r TokenIntrospectionDTO, err error) {
+	client, err := uc.authenticateOAuthClient(ctx, clientId, clientSecret)
+	if err != nil {
+		return r, err
+	}
+
+	if !client.Trusted || !client.Confidential() {
+		return r, domain.ErrUnauthorizedClient
+	}
+
+	s, err := uc.tokenSession(ctx, token)
+	if errors.Is(err, domain.ErrInvalidToken) {
+		return r, nil
+	} else if err != nil {
+		return r, err
+	}
+
+	now := time.Now()
+	if s.ValidUntil != nil && s.ValidUntil.Before(now) {
+		return r, nil
+	}
+
+	var tokenType string
+	switch s.Type {
+	case domain.GeneralToken:
+		tokenType = "access_token"
+	case domain.RefreshToken:
+		if s.UseTime != nil || !sameEntityId(s.ClientId, &client.Id) {
+			return r, nil
+		}
+		tokenType = "refresh_token"
+	default:
+		return r, nil
+	}
+
+	// Tokens of users who cannot sign in are inactive, in case they were not revoked.
+	if s.UserId != 0 {
+		u, err := uc.repo.user.Get(ctx, s.UserId)
+		if errors.Is(err, domain.ErrUserNotFound) || (err == nil && (u.PendingDeletion() || u.Disabled())) {
+			return r, nil
+		} else if err != nil {
+			return r, err
+		}
+	}
+
+	r = TokenIntrospectionDTO{
+		Active:     true,
+		TokenType:  tokenType,
+		Scopes:     s.Scopes(),
+		IssueTime:  s.CreateTime,
+		ValidUntil: s.ValidUntil,
+	}
+	if s.UserId != 0 {
+		r.UserId = strconv.FormatInt(int64(s.UserId), 10)
+	}
+	if s.UserId != 0 && s.ClientId == nil {
+		r.Permissions, err = uc.userPermissions(ctx, s.UserId)
+		if err != nil {
+			return TokenIntrospectionDTO{}, err
+		}
+	}
+
+	if s.ClientId != nil {
+		issuedTo := client
+		if issuedTo.Id != *s.ClientId {
+			issuedTo, err = uc.repo.oauth.GetClient(ctx, *s.ClientId)
+			if err != nil {
+				return TokenIntrospectionDTO{}, err
+			}
+		}
+		r.ClientId = issuedTo.ClientId
+	}
+
+	return r, nil
+}
+
+// UserInfoDTO holds the claims about the user the scopes give access to.
+type UserInfoDTO struct {
+	Subject       string
+	Email         string
+	EmailVerified *bool
+	Name          string
+}
+
+func newUserInfoDTO(u domain.User, scopes []string) UserInfoDTO {
+	r := UserInfoDTO{Subject: strconv.FormatInt(int64(u.Id), 10)}
+
+	if domain.ScopeContains(scopes, []string{domain.EmailScope}) {
+		verified := u.EmailVerifyTime != nil
+		r.Email = string(u.Email)
+		r.EmailVerified = &verified
+	}
+
+	if domain.ScopeContains(scopes, []string{domain.ProfileScope}) {
+		r.Name = string(u.Fullname)
+	}
+
+	return r
+}
+
+// GetUserInfo returns the claims about the user of an access token with the openid scope.
+func (uc UserUseCase) GetUserInfo(
+	ctx context.Context,
+	accessToken string,
+) (r UserInfoDTO, err error) {
+	s, err := uc.accessTokenSession(ctx, accessToken)
+	if err != nil {
+		return r, err
+	}
+
+	scopes := s.Scopes()
+	if s.UserId == 0 || !domain.ScopeContains(scopes, []string{domain.OpenIdScope}) {
+		return r, domain.ErrInvalidToken
+	}
+
+	// Tokens of users who cannot sign in are refused, in case they were not revoked.
+	u, err := uc.repo.user.Get(ctx, s.UserId)
+	if errors.Is(err, domain.ErrUserNotFound) || (err == nil && (u.PendingDeletion() || u.Disabled())) {
+		return r, domain.ErrInvalidToken
+	} else if err != nil {
+		return r, err
+	}
+
+	return newUserInfoDTO(u, scopes), nil
+}
+
+func (uc UserUseCase) signIdToken(
+	u domain.User,
+	client domain.OAuthClient,
+	scopes []string,
+	nonce string,
+	authTime, validUntil time.Time,
+) (string, error) {
+	info := newUserInfoDTO(u, scopes)
+
+	token, err := uc.signer.Sign(domain.IdTokenClaims{
+		Issuer:        uc.issuer,
+		Subject:       info.Subject,
+		Audience:      client.ClientId,
+		IssuedAt:      time.Now().Unix(),
+		ExpiresAt:     validUntil.Unix(),
+		AuthTime:      authTime.Unix(),
+		Nonce:         nonce,
+		Email:         info.Email,
+		EmailVerified: info.EmailVerified,
+		Name:          info.Name,
+	})
+	if err != nil {
+		return "", domain.InternalError{Err: err}
+	}
+
+	return token, nil
+}
+
+// OAuthServerMetadataDTO describes the authorization server to clients, its
+// endpoints are under the issuer.
+type OAuthServerMetadataDTO struct {
+	Issuer            string
+	AuthorizationPage string
+	Scopes            []string
+	GrantTypes        []string
+}
+
+func (uc UserUseCase) OAuthServerMetadata() OAuthServerMetadataDTO {
+	return OAuthServerMetadataDTO{
+		Issuer:            uc.issuer,
+		AuthorizationPage: uc.authorizationPage,
+		Scopes:            domain.OAuthScopes,
+		GrantTypes:        domain.OAuthGrantTypes,
+	}
+}
@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"time"
+
+	"github.com/PanziApp/backend/internal/domain"
+)
+
+const (
+	_defaultAccessTokenLifetime      = 15 * time.Minute
+	_defaultRefreshTokenIdleLifetime = 30 * 24 * time.Hour
+	_defaultSessionAbsoluteLifetime  = 90 * 24 * time.Hour
+	_defaultTotpIssuer               = "Panzi"
+	_defaultDeletionGracePeriod      = 30 * 24 * time.Hour
+	_defaultSignInLockoutThreshold   = 5
+	_defaultSignInLockoutIPThreshold = 50
+	_defaultSignInLockoutDuration    = 15 * time.Minute
+)
+
+// Option -.
+type Option func(*UserUseCase)
+
+// AccessTokenLifetime -.
+func AccessTokenLifetime(lifetime time.Duration) Option {
+	return func(uc *UserUseCase) {
+		uc.lifetime.accessToken = lifetime
+	}
+}
+
+// RefreshTokenIdleLifetime is how long a session may stay unused before its refresh token expires.
+func RefreshTokenIdleLifetime(lifetime time.Duration) Option {
+	return func(uc *UserUseCase) {
+		uc.lifetime.refreshTokenIdle = lifetime
+	}
+}
+
+// SessionAbsoluteLifetime is how long a session may be refreshed after signing in.
+func SessionAbsoluteLifetime(lifetime time.Duration) Option {
+	return func(uc *UserUseCase) {
+		uc.lifetime.sessionAbsolute = lifetime
+	}
+}
+
+// MaxSessionsPerUser caps the concurrent sessions of a user, signing in past
+// the cap revokes the oldest session. Zero means no cap.
+func MaxSessionsPerUser(max int) Option {
+	return func(uc *UserUseCase) {
+		uc.maxSessionsPerUser = max
+	}
+}
+
+// SignedAccessTokens issues access tokens signed by signer instead of opaque ones,
+// so other services can verify them without querying the sessions.
+func SignedAccessTokens(signer TokenSigner, issuer string) Option {
+	return func(uc *UserUseCase) {
+		uc.signer = signer
+		uc.issuer = issuer
+	}
+}
+
+// TotpIssuer is the account issuer shown by authenticator apps.
+func TotpIssuer(issuer string) Option {
+	return func(uc *UserUseCase) {
+		uc.totpIssuer = issuer
+	}
+}
+
+// Passkeys enables WebAuthn registration and passwordless sign in.
+func Passkeys(rp RelyingParty) Option {
+	return func(uc *UserUseCase) {
+		uc.relyingParty = rp
+	}
+}
+
+// ExternalProviders enables signing in with the OpenID providers, by name.
+func ExternalProviders(providers map[string]ExternalProvider) Option {
+	return func(uc *UserUseCase) {
+		uc.externalProviders = providers
+	}
+}
+
+// AuthorizationPage is the url of the page asking users to authorize OAuth
+// clients, which is advertised as the authorization endpoint.
+func AuthorizationPage(url string) Option {
+	return func(uc *UserUseCase) {
+		uc.authorizationPage = url
+	}
+}
+
+// RedirectSchemes are the private-use schemes native OAuth clients may redirect
+// to, besides https and loopback http.
+func RedirectSchemes(schemes []string) Option {
+	return func(uc *UserUseCase) {
+		uc.redirectSchemes = schemes
+	}
+}
+
+// DeletionGracePeriod is how long a deleted account can be restored before it is purged.
+func DeletionGracePeriod(period time.Duration) Option {
+	return func(uc *UserUseCase) {
+		uc.deletionGracePeriod = period
+	}
+}
+
+// SignInLockout locks password sign in with an email for the duration after
+// threshold failures, and from an ip address after ipThreshold failures. Failures
+// are forgotten once none happened for the duration. A zero threshold disables
+// the lockout, and the back-off for emails.
+func SignInLockout(threshold, ipThreshold int, duration time.Duration) Option {
+	return func(uc *UserUseCase) {
+		uc.signInLockout.threshold = threshold
+		uc.signInLockout.ipThreshold = ipThreshold
+		uc.signInLockout.duration = duration
+	}
+}
+
+// SignInCodeKey is the server secret keying the stored hashes of sign in codes.
+func SignInCodeKey(key []byte) Option {
+	return func(uc *UserUseCase) {
+		uc.signInCodeKey = key
+	}
+}
+
+// PasswordPolicy is what new passwords are checked against.
+func PasswordPolicy(policy domain.PasswordPolicy) Option {
+	return func(uc *UserUseCase) {
+		uc.passwordPolicy = policy
+	}
+}
+
+// RejectBreachedPasswords rejects new passwords that appeared in data breaches.
+func RejectBreachedPasswords(breached BreachedPasswords) Option {
+	return func(uc *UserUseCase) {
+		uc.breachedPasswords = breached
+	}
+}
+
+// PasswordHashing is how new passwords are hashed, older hashes are upgraded
+// to it when their users sign in.
+func PasswordHashing(hasher domain.PasswordHasher) Option {
+	return func(uc *UserUseCase) {
+		uc.passwordHasher = hasher
+	}
+}
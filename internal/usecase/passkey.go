@@ -0,0 +1,282 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/PanziApp/backend/internal/domain"
+	"github.com/PanziApp/backend/pkg/webauthn"
+)
+
+type PasskeyDTO struct {
+	Id          string
+	Name        string
+	CreateTime  time.Time
+	LastUseTime *time.Time
+}
+
+func newPasskeyDTO(p domain.Passkey) PasskeyDTO {
+	return PasskeyDTO{
+		Id:          p.ExternalId(),
+		Name:        p.Name,
+		CreateTime:  p.CreateTime,
+		LastUseTime: p.LastUseTime,
+	}
+}
+
+// BeginPasskeyRegistration starts a registration ceremony, the options are passed
+// to navigator.credentials.create and its response to FinishPasskeyRegistration.
+func (uc UserUseCase) BeginPasskeyRegistration(
+	ctx context.Context,
+	principal Principal,
+) (o webauthn.CreationOptions, err error) {
+	u := principal.User
+
+	passkeys, err := uc.repo.passkey.ListByUserId(ctx, u.Id)
+	if err != nil {
+		return o, err
+	}
+
+	challenge, err := uc.createPasskeyChallenge(ctx, &u.Id, domain.PasskeyRegistrationChallenge)
+	if err != nil {
+		return o, err
+	}
+
+	displayName := string(u.Fullname)
+	if displayName == "" {
+		displayName = string(u.Email)
+	}
+
+	return uc.relyingParty.CreationOptions(
+		[]byte(challenge),
+		webauthn.User{Id: domain.PasskeyUserHandle(u.Id), Name: string(u.Email), DisplayName: displayName},
+		credentialDescriptors(passkeys),
+	), nil
+}
+
+// FinishPasskeyRegistration verifies the response of the authenticator and stores the passkey.
+func (uc UserUseCase) FinishPasskeyRegistration(
+	ctx context.Context,
+	principal Principal,
+	name string,
+	r webauthn.AttestationResponse,
+) (PasskeyDTO, error) {
+	validName, err := domain.ValidatePasskeyName(name)
+	if err != nil {
+		return PasskeyDTO{}, err
+	}
+
+	now := time.Now()
+
+	c, err := uc.redeemPasskeyChallenge(ctx, r.Challenge, domain.PasskeyRegistrationChallenge, now)
+	if err != nil {
+		return PasskeyDTO{}, err
+	}
+
+	if c.UserId == nil || *c.UserId != principal.User.Id {
+		return PasskeyDTO{}, domain.ErrInvalidToken
+	}
+
+	credential, err := uc.relyingParty.VerifyRegistration([]byte(c.Challenge), r)
+	if err != nil {
+		return PasskeyDTO{}, domain.ErrInvalidPasskey
+	}
+
+	p := domain.Passkey{
+		CreateTime:   now,
+		UserId:       principal.User.Id,
+		CredentialId: credential.Id,
+		PublicKey:    credential.PublicKey,
+		SignCount:    credential.SignCount,
+		Transports:   credential.Transports,
+		Name:         validName,
+	}
+
+	p.Id, err = uc.repo.passkey.Create(ctx, p)
+	if err != nil {
+		return PasskeyDTO{}, err
+	}
+
+	return newPasskeyDTO(p), nil
+}
+
+// BeginPasskeySignIn starts an authentication ceremony, the options are passed
+// to navigator.credentials.get and its response to FinishPasskeySignIn. Without
+// an email the client offers the discoverable credentials it has, with one it
+// offers the passkeys of the account. An unknown email is not revealed.
+func (uc UserUseCase) BeginPasskeySignIn(
+	ctx context.Context,
+	email string,
+) (o webauthn.RequestOptions, err error) {
+	var allow []webauthn.CredentialDescriptor
+
+	if email != "" {
+		validEmail, err := domain.ValidateEmail(email)
+		if err != nil {
+			return o, err
+		}
+
+		u, err := uc.repo.user.GetByEmail(ctx, validEmail)
+		if err != nil && !errors.Is(err, domain.ErrUserNotFound) {
+			return o, err
+		}
+
+		if err == nil {
+			passkeys, err := uc.repo.passkey.ListByUserId(ctx, u.Id)
+			if err != nil {
+				return o, err
+			}
+
+			allow = credentialDescriptors(passkeys)
+		}
+	}
+
+	challenge, err := uc.createPasskeyChallenge(ctx, nil, domain.PasskeySignInChallenge)
+	if err != nil {
+		return o, err
+	}
+
+	return uc.relyingParty.RequestOptions([]byte(challenge), allow), nil
+}
+
+// FinishPasskeySignIn verifies the assertion of the authenticator and starts a session.
+// The user is verified by the authenticator, so there is no second factor to ask for.
+func (uc UserUseCase) FinishPasskeySignIn(
+	ctx context.Context,
+	r webauthn.AssertionResponse,
+	meta domain.SessionMeta,
+) (t TokensDTO, err error) {
+	now := time.Now()
+
+	c, err := uc.redeemPasskeyChallenge(ctx, r.Challenge, domain.PasskeySignInChallenge, now)
+	if err != nil {
+		return t, err
+	}
+
+	p, err := uc.repo.passkey.GetByCredentialId(ctx, r.RawId)
+	if errors.Is(err, domain.ErrPasskeyNotFound) {
+		return t, domain.ErrInvalidPasskey
+	} else if err != nil {
+		return t, err
+	}
+
+	userHandle := r.Response.UserHandle
+	if len(userHandle) > 0 && !bytes.Equal(userHandle, domain.PasskeyUserHandle(p.UserId)) {
+		return t, domain.ErrInvalidPasskey
+	}
+
+	signCount, err := uc.relyingParty.VerifyAssertion([]byte(c.Challenge), r, webauthn.Credential{
+		Id:        p.CredentialId,
+		PublicKey: p.PublicKey,
+		SignCount: p.SignCount,
+	})
+	if errors.Is(err, webauthn.ErrSignCount) {
+		return t, domain.ErrPasskeyCloned
+	} else if err != nil {
+		return t, domain.ErrInvalidPasskey
+	}
+
+	err = uc.repo.passkey.Update(ctx, p.Id, domain.EntityUpdate{
+		domain.PasskeySignCountFieldName:   signCount,
+		domain.PasskeyLastUseTimeFieldName: now,
+	})
+	if err != nil {
+		return t, err
+	}
+
+	u, err := uc.repo.user.Get(ctx, p.UserId)
+	if err != nil {
+		return t, err
+	}
+
+	return uc.startSession(ctx, u, meta)
+}
+
+func (uc UserUseCase) ListPasskeys(
+	ctx context.Context,
+	principal Principal,
+) ([]PasskeyDTO, error) {
+	passkeys, err := uc.repo.passkey.ListByUserId(ctx, principal.User.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]PasskeyDTO, 0, len(passkeys))
+	for _, p := range passkeys {
+		dtos = append(dtos, newPasskeyDTO(p))
+	}
+
+	return dtos, nil
+}
+
+func (uc UserUseCase) DeletePasskey(
+	ctx context.Context,
+	principal Principal,
+	passkeyId string,
+) error {
+	credentialId, err := base64.RawURLEncoding.DecodeString(passkeyId)
+	if err != nil || len(credentialId) == 0 {
+		return domain.ErrPasskeyNotFound
+	}
+
+	return uc.repo.passkey.Delete(ctx, principal.User.Id, credentialId)
+}
+
+func (uc UserUseCase) createPasskeyChallenge(
+	ctx context.Context,
+	userId *domain.EntityId,
+	challengeType domain.PasskeyChallengeType,
+) (domain.Token, error) {
+	challenge, err := domain.RandomToken(domain.PasskeyChallengeToken)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	err = uc.repo.passkey.CreateChallenge(ctx, domain.PasskeyChallenge{
+		CreateTime: now,
+		UserId:     userId,
+		Type:       challengeType,
+		Challenge:  challenge,
+		ValidUntil: now.Add(domain.PasskeyChallengeLifetime),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return challenge, nil
+}
+
+// redeemPasskeyChallenge looks up the ceremony the client answers by the
+// challenge it signed, each challenge can be answered only once.
+func (uc UserUseCase) redeemPasskeyChallenge(
+	ctx context.Context,
+	clientChallenge func() ([]byte, error),
+	challengeType domain.PasskeyChallengeType,
+	now time.Time,
+) (domain.PasskeyChallenge, error) {
+	challenge, err := clientChallenge()
+	if err != nil {
+		return domain.PasskeyChallenge{}, domain.ErrInvalidPasskey
+	}
+
+	validChallenge, err := domain.ValidateToken(string(challenge), domain.PasskeyChallengeToken)
+	if err != nil {
+		return domain.PasskeyChallenge{}, err
+	}
+
+	return uc.repo.passkey.RedeemChallenge(ctx, validChallenge, challengeType, now)
+}
+
+func credentialDescriptors(passkeys []domain.Passkey) []webauthn.CredentialDescriptor {
+	descriptors := make([]webauthn.CredentialDescriptor, 0, len(passkeys))
+	for _, p := range passkeys {
+		descriptors = append(descriptors, webauthn.NewCredentialDescriptor(p.CredentialId, p.Transports))
+	}
+
+	return descriptors
+}
@@ -0,0 +1,35 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/PanziApp/backend/internal/domain"
+)
+
+// checkNewPassword checks a password the user is setting against the policy,
+// and against the breached passwords when they are configured.
+func (uc UserUseCase) checkNewPassword(
+	ctx context.Context,
+	password domain.Password,
+	email domain.Email,
+	fullname domain.Fullname,
+) error {
+	err := uc.passwordPolicy.Check(password, email, fullname)
+	if err != nil {
+		return err
+	}
+
+	if uc.breachedPasswords == nil {
+		return nil
+	}
+
+	breached, err := uc.breachedPasswords.Contains(ctx, password)
+	if err != nil {
+		return domain.ServiceError{Name: "breached-passwords", Err: err}
+	}
+	if breached {
+		return domain.ValidationError{Err: domain.ErrPasswordBreached}
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PanziApp/backend/internal/domain"
+)
+
+// SendSignInLink mails a single use sign in link, superseding the previous one.
+func (uc UserUseCase) SendSignInLink(
+	ctx context.Context,
+	email string,
+) error {
+	validEmail, err := domain.ValidateEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	user, err := uc.repo.user.GetByEmail(ctx, validEmail)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		// Do not reveal which emails are registered.
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	session, err := uc.createSupersedingSession(ctx, domain.Session{
+		UserId: user.Id,
+		Type:   domain.SignInLinkToken,
+	}, domain.SignInLinkTokenLifetime)
+	if err != nil {
+		return err
+	}
+
+	return uc.mailer.Send(
+		ctx,
+		string(user.Email),
+		string(user.Fullname),
+		"Sign In",
+		domain.SignInLinkEmailMessage(string(session.Token)),
+	)
+}
+
+// RedeemSignInLink signs the user in with the token of a sign in link. Two-factor
+// authentication is still asked for when it is enabled.
+func (uc UserUseCase) RedeemSignInLink(
+	ctx context.Context,
+	token string,
+	meta domain.SessionMeta,
+) (r SignInDTO, err error) {
+	validToken, err := domain.ValidateToken(token, domain.SignInLinkToken)
+	if err != nil {
+		return r, err
+	}
+
+	s, err := uc.repo.session.Redeem(ctx, validToken, domain.SignInLinkToken, time.Now())
+	if err != nil {
+		return r, err
+	}
+
+	return uc.completeEmailSignIn(ctx, s.UserId, meta)
+}
+
+// SendSignInCode mails a single use numeric sign in code, superseding the previous
+// one, for clients that cannot open links.
+func (uc UserUseCase) SendSignInCode(
+	ctx context.Context,
+	email string,
+) error {
+	validEmail, err := domain.ValidateEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	user, err := uc.repo.user.GetByEmail(ctx, validEmail)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		// Do not reveal which emails are registered.
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	code, err := domain.RandomSignInCode()
+	if err != nil {
+		return err
+	}
+
+	_, err = uc.createSupersedingSession(ctx, domain.Session{
+		UserId: user.Id,
+		Type:   domain.SignInCodeToken,
+		Code:   domain.HashSignInCode(uc.signInCodeKey, code),
+	}, domain.SignInCodeTokenLifetime)
+	if err != nil {
+		return err
+	}
+
+	return uc.mailer.Send(
+		ctx,
+		string(user.Email),
+		string(user.Fullname),
+		"Sign In Code",
+		domain.SignInCodeEmailMessage(code),
+	)
+}
+
+// RedeemSignInCode signs the user in with the last code mailed to them. The code
+// is revoked after too many failed attempts, and the codes of the email are
+// locked after too many failed codes, so they cannot be guessed by asking for
+// new codes either.
+func (uc UserUseCase) RedeemSignInCode(
+	ctx context.Context,
+	email, code string,
+	meta domain.SessionMeta,
+) (r SignInDTO, err error) {
+	validEmail, err := domain.ValidateEmail(email)
+	if err != nil {
+		return r, domain.ErrInvalidSignInCode
+	}
+
+	now := time.Now()
+	failuresKey := domain.SignInCodeFailuresKey(validEmail)
+
+	f, err := uc.repo.signInFailure.Get(ctx, failuresKey)
+	if err != nil {
+		return r, err
+	}
+	if wait := f.LockedFor(now); wait > 0 {
+		return r, domain.RateLimitError{Err: domain.ErrSignInCodeThrottled, RetryAfter: wait}
+	}
+
+	user, err := uc.repo.user.GetByEmail(ctx, validEmail)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return r, domain.ErrInvalidSignInCode
+	} else if err != nil {
+		return r, err
+	}
+
+	s, err := uc.repo.session.GetLastByUserId(ctx, user.Id, domain.SignInCodeToken)
+	if errors.Is(err, domain.ErrSessionNotFound) {
+		return r, domain.ErrInvalidSignInCode
+	} else if err != nil {
+		return r, err
+	}
+
+	if s.UseTime != nil || (s.ValidUntil != nil && s.ValidUntil.Before(now)) {
+		return r, domain.ErrInvalidSignInCode
+	}
+
+	err = uc.repo.session.AddAttempt(ctx, s.Id, domain.SignInCodeMaxAttempts, now)
+	if errors.Is(err, domain.ErrInvalidToken) {
+		return r, domain.ErrInvalidSignInCode
+	} else if err != nil {
+		return r, err
+	}
+
+	if err = domain.MatchSignInCode(uc.signInCodeKey, s.Code, code); err != nil {
+		f, err = uc.repo.signInFailure.Record(ctx, failuresKey, now, now.Add(-domain.SignInCodeLockout))
+		if err != nil {
+			return r, err
+		}
+
+		if f.Failures >= domain.SignInCodeMaxFailures {
+			err = uc.repo.signInFailure.Lock(ctx, failuresKey, now.Add(domain.SignInCodeLockout))
+			if err != nil {
+				return r, err
+			}
+		}
+
+		return r, domain.ErrInvalidSignInCode
+	}
+
+	err = uc.repo.session.Use(ctx, s.Id, now)
+	if errors.Is(err, domain.ErrInvalidToken) {
+		return r, domain.ErrInvalidSignInCode
+	} else if err != nil {
+		return r, err
+	}
+
+	err = uc.repo.signInFailure.Clear(ctx, failuresKey)
+	if err != nil {
+		return r, err
+	}
+
+	return uc.completeEmailSignIn(ctx, user.Id, meta)
+}
+
+// completeEmailSignIn signs in the user who proved they own their email,
+// verifying the email if it was not already.
+func (uc UserUseCase) completeEmailSignIn(
+	ctx context.Context,
+	userId domain.EntityId,
+	meta domain.SessionMeta,
+) (r SignInDTO, err error) {
+	user, err := uc.repo.user.Get(ctx, userId)
+	if err != nil {
+		return r, err
+	}
+
+	if user.EmailVerifyTime == nil {
+		now := time.Now()
+
+		err = uc.repo.user.Update(ctx, user.Id, domain.EntityUpdate{domain.UserEmailVerifyTimeFieldName: now})
+		if err != nil {
+			return r, err
+		}
+
+		user.EmailVerifyTime = &now
+	}
+
+	return uc.completeSignIn(ctx, user, meta)
+}
@@ -0,0 +1,85 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/PanziApp/backend/internal/domain"
+	"github.com/PanziApp/backend/pkg/postgres"
+)
+
+const _auditColumns = "id, create_time, COALESCE(actor_id, 0), action, COALESCE(target_user_id, 0), details"
+
+type AuditRepository struct {
+	*postgres.Postgres
+}
+
+func NewAuditRepository(pg *postgres.Postgres) AuditRepository {
+	return AuditRepository{pg}
+}
+
+func scanAuditEntry(row pgx.Row) (e domain.AuditEntry, err error) {
+	err = row.Scan(&e.Id, &e.CreateTime, &e.ActorId, &e.Action, &e.TargetUserId, &e.Details)
+	return e, err
+}
+
+func (r AuditRepository) Create(ctx context.Context, e domain.AuditEntry) error {
+	sql, args, err := r.Builder.
+		Insert("audit_log").
+		Columns("create_time, actor_id, action, target_user_id, details").
+		Values(e.CreateTime, squirrel.Expr("NULLIF(?::bigint, 0)", e.ActorId), e.Action,
+			squirrel.Expr("NULLIF(?::bigint, 0)", e.TargetUserId), e.Details).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	_, err = r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	return nil
+}
+
+// List returns up to search.Limit entries matching the search, newest first.
+func (r AuditRepository) List(ctx context.Context, search domain.AuditSearch) ([]domain.AuditEntry, error) {
+	q := r.Builder.
+		Select(_auditColumns).
+		From("audit_log").
+		OrderBy("id DESC").
+		Limit(search.Limit)
+	if search.TargetUserId != 0 {
+		q = q.Where("target_user_id = ?", search.TargetUserId)
+	}
+	if search.BeforeId != 0 {
+		q = q.Where("id < ?", search.BeforeId)
+	}
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	rows, err := r.Conn(ctx).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+	defer rows.Close()
+
+	entries := make([]domain.AuditEntry, 0)
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, domain.InternalError{Err: err}
+		}
+		entries = append(entries, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,230 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/PanziApp/backend/internal/domain"
+	"github.com/PanziApp/backend/pkg/postgres"
+)
+
+const _dataExportColumns = "id, create_time, user_id, complete_time, filename, token_hash, valid_until, attempts, retry_time"
+
+type DataExportRepository struct {
+	*postgres.Postgres
+}
+
+func NewDataExportRepository(pg *postgres.Postgres) DataExportRepository {
+	return DataExportRepository{pg}
+}
+
+func scanDataExport(row pgx.Row) (e domain.DataExport, err error) {
+	var tokenHash []byte
+	err = row.Scan(
+		&e.Id, &e.CreateTime, &e.UserId, &e.CompleteTime, &e.Filename, &tokenHash, &e.ValidUntil, &e.Attempts, &e.RetryTime,
+	)
+	e.TokenHash = tokenHash
+	return e, err
+}
+
+func (r DataExportRepository) Create(ctx context.Context, e domain.DataExport) (domain.EntityId, error) {
+	sql, args, err := r.Builder.
+		Insert("data_exports").
+		Columns("create_time, user_id").
+		Values(e.CreateTime, e.UserId).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return 0, domain.InternalError{Err: err}
+	}
+
+	err = r.Conn(ctx).QueryRow(ctx, sql, args...).Scan(&e.Id)
+	if err != nil {
+		return 0, domain.InternalError{Err: err}
+	}
+	return e.Id, nil
+}
+
+func (r DataExportRepository) GetByToken(ctx context.Context, token domain.Token) (e domain.DataExport, err error) {
+	sql, args, err := r.Builder.
+		Select(_dataExportColumns).
+		From("data_exports").
+		Where("token_hash = ?", []byte(token.Hash())).
+		ToSql()
+	if err != nil {
+		return e, domain.InternalError{Err: err}
+	}
+
+	e, err = scanDataExport(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return e, domain.ErrInvalidToken
+	} else if err != nil {
+		return e, domain.InternalError{Err: err}
+	}
+	return e, nil
+}
+
+func (r DataExportRepository) GetLastByUserId(
+	ctx context.Context,
+	userId domain.EntityId,
+) (e domain.DataExport, err error) {
+	sql, args, err := r.Builder.
+		Select(_dataExportColumns).
+		From("data_exports").
+		Where("user_id = ?", userId).
+		OrderBy("create_time DESC").
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return e, domain.InternalError{Err: err}
+	}
+
+	e, err = scanDataExport(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return e, domain.ErrDataExportNotFound
+	} else if err != nil {
+		return e, domain.InternalError{Err: err}
+	}
+	return e, nil
+}
+
+func (r DataExportRepository) ListByUserId(ctx context.Context, userId domain.EntityId) ([]domain.DataExport, error) {
+	return r.list(ctx, r.Builder.
+		Select(_dataExportColumns).
+		From("data_exports").
+		Where("user_id = ?", userId).
+		OrderBy("create_time, id"))
+}
+
+// ListPending returns up to limit exports still to be built or mailed at now,
+// the oldest request first. Exports failed maxAttempts times are left out.
+func (r DataExportRepository) ListPending(
+	ctx context.Context,
+	now time.Time,
+	maxAttempts int,
+	limit uint64,
+) ([]domain.DataExport, error) {
+	return r.list(ctx, r.Builder.
+		Select(_dataExportColumns).
+		From("data_exports").
+		Where("complete_time IS NULL AND attempts < ? AND (retry_time IS NULL OR retry_time <= ?)", maxAttempts, now).
+		OrderBy("create_time, id").
+		Limit(limit))
+}
+
+// ListExpired returns up to limit exports that could no longer be downloaded at now.
+func (r DataExportRepository) ListExpired(ctx context.Context, now time.Time, limit uint64) ([]domain.DataExport, error) {
+	return r.list(ctx, r.Builder.
+		Select(_dataExportColumns).
+		From("data_exports").
+		Where("valid_until <= ?", now).
+		OrderBy("valid_until, id").
+		Limit(limit))
+}
+
+func (r DataExportRepository) list(ctx context.Context, q squirrel.SelectBuilder) ([]domain.DataExport, error) {
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	rows, err := r.Conn(ctx).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+	defer rows.Close()
+
+	exports := make([]domain.DataExport, 0)
+	for rows.Next() {
+		e, err := scanDataExport(rows)
+		if err != nil {
+			return nil, domain.InternalError{Err: err}
+		}
+		exports = append(exports, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	return exports, nil
+}
+
+func (r DataExportRepository) Update(ctx context.Context, exportId domain.EntityId, updates domain.EntityUpdate) error {
+	q := r.Builder.Update("data_exports").
+		Where("id = ?", exportId)
+
+	haveUpdate := false
+	if completeTime, ok := updates[domain.DataExportCompleteTimeFieldName]; ok {
+		q = q.Set("complete_time", completeTime)
+		haveUpdate = true
+	}
+	if filename, ok := updates[domain.DataExportFilenameFieldName]; ok {
+		q = q.Set("filename", filename)
+		haveUpdate = true
+	}
+	if tokenHash, ok := updates[domain.DataExportTokenHashFieldName]; ok {
+		q = q.Set("token_hash", []byte(tokenHash.(domain.TokenHash)))
+		haveUpdate = true
+	}
+	if validUntil, ok := updates[domain.DataExportValidUntilFieldName]; ok {
+		q = q.Set("valid_until", validUntil)
+		haveUpdate = true
+	}
+
+	if !haveUpdate {
+		return nil
+	}
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	_, err = r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	return nil
+}
+
+// RecordFailure counts a failed attempt of the export, which is not retried before retryTime.
+func (r DataExportRepository) RecordFailure(ctx context.Context, exportId domain.EntityId, retryTime time.Time) error {
+	sql, args, err := r.Builder.
+		Update("data_exports").
+		Set("attempts", squirrel.Expr("attempts + 1")).
+		Set("retry_time", retryTime).
+		Where("id = ?", exportId).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	_, err = r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	return nil
+}
+
+func (r DataExportRepository) Delete(ctx context.Context, exportId domain.EntityId) error {
+	sql, args, err := r.Builder.
+		Delete("data_exports").
+		Where("id = ?", exportId).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	_, err = r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,254 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/PanziApp/backend/internal/domain"
+	"github.com/PanziApp/backend/pkg/postgres"
+)
+
+const (
+	_externalIdentityColumns = "id, create_time, user_id, provider, subject, email, confirm_time, confirm_token_hash, " +
+		"confirm_valid_until, last_use_time"
+	_externalSignInColumns = "id, create_time, provider, state, nonce, code_verifier, user_id, valid_until, use_time"
+)
+
+type ExternalIdentityRepository struct {
+	*postgres.Postgres
+}
+
+func NewExternalIdentityRepository(pg *postgres.Postgres) ExternalIdentityRepository {
+	return ExternalIdentityRepository{pg}
+}
+
+func scanExternalIdentity(row pgx.Row) (i domain.ExternalIdentity, err error) {
+	var confirmTokenHash []byte
+	err = row.Scan(
+		&i.Id, &i.CreateTime, &i.UserId, &i.Provider, &i.Subject, &i.Email, &i.ConfirmTime, &confirmTokenHash,
+		&i.ConfirmValidUntil, &i.LastUseTime,
+	)
+	i.ConfirmTokenHash = confirmTokenHash
+	return i, err
+}
+
+// Link stores the identity, replacing a pending link of the same provider account.
+// It fails with domain.ErrExternalIdentityInUse when the account is already linked.
+func (r ExternalIdentityRepository) Link(ctx context.Context, i domain.ExternalIdentity) (domain.EntityId, error) {
+	var confirmTokenHash []byte
+	if i.ConfirmToken != nil {
+		confirmTokenHash = i.ConfirmToken.Hash()
+	}
+
+	sql, args, err := r.Builder.
+		Insert("external_identities").
+		Columns("create_time, user_id, provider, subject, email, confirm_time, confirm_token_hash, confirm_valid_until").
+		Values(i.CreateTime, i.UserId, i.Provider, i.Subject, i.Email, i.ConfirmTime, confirmTokenHash, i.ConfirmValidUntil).
+		Suffix("ON CONFLICT (provider, subject) DO UPDATE SET " +
+			"create_time = EXCLUDED.create_time, user_id = EXCLUDED.user_id, email = EXCLUDED.email, " +
+			"confirm_time = EXCLUDED.confirm_time, confirm_token_hash = EXCLUDED.confirm_token_hash, " +
+			"confirm_valid_until = EXCLUDED.confirm_valid_until " +
+			"WHERE external_identities.confirm_time IS NULL " +
+			"RETURNING id").
+		ToSql()
+	if err != nil {
+		return 0, domain.InternalError{Err: err}
+	}
+
+	err = r.Conn(ctx).QueryRow(ctx, sql, args...).Scan(&i.Id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, domain.ErrExternalIdentityInUse
+	} else if err != nil {
+		return 0, domain.InternalError{Err: err}
+	}
+	return i.Id, nil
+}
+
+func (r ExternalIdentityRepository) GetByProviderSubject(
+	ctx context.Context,
+	provider, subject string,
+) (i domain.ExternalIdentity, err error) {
+	sql, args, err := r.Builder.
+		Select(_externalIdentityColumns).
+		From("external_identities").
+		Where("provider = ? AND subject = ?", provider, subject).
+		ToSql()
+	if err != nil {
+		return i, domain.InternalError{Err: err}
+	}
+
+	i, err = scanExternalIdentity(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return i, domain.ErrExternalIdentityNotFound
+	} else if err != nil {
+		return i, domain.InternalError{Err: err}
+	}
+	return i, nil
+}
+
+// ListByUserId returns the confirmed identities of the user.
+func (r ExternalIdentityRepository) ListByUserId(
+	ctx context.Context,
+	userId domain.EntityId,
+) ([]domain.ExternalIdentity, error) {
+	sql, args, err := r.Builder.
+		Select(_externalIdentityColumns).
+		From("external_identities").
+		Where("user_id = ? AND confirm_time IS NOT NULL", userId).
+		OrderBy("confirm_time, id").
+		ToSql()
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	rows, err := r.Conn(ctx).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+	defer rows.Close()
+
+	identities := make([]domain.ExternalIdentity, 0)
+	for rows.Next() {
+		i, err := scanExternalIdentity(rows)
+		if err != nil {
+			return nil, domain.InternalError{Err: err}
+		}
+		identities = append(identities, i)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	return identities, nil
+}
+
+// Confirm confirms the pending link with the unexpired token, a token can be used only once.
+func (r ExternalIdentityRepository) Confirm(
+	ctx context.Context,
+	token domain.Token,
+	confirmTime time.Time,
+) (i domain.ExternalIdentity, err error) {
+	sql, args, err := r.Builder.
+		Update("external_identities").
+		Set("confirm_time", confirmTime).
+		Set("confirm_token_hash", nil).
+		Set("confirm_valid_until", nil).
+		Where("confirm_token_hash = ? AND confirm_time IS NULL AND confirm_valid_until > ?",
+			[]byte(token.Hash()), confirmTime).
+		Suffix("RETURNING " + _externalIdentityColumns).
+		ToSql()
+	if err != nil {
+		return i, domain.InternalError{Err: err}
+	}
+
+	i, err = scanExternalIdentity(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return i, domain.ErrInvalidToken
+	} else if err != nil {
+		return i, domain.InternalError{Err: err}
+	}
+	return i, nil
+}
+
+func (r ExternalIdentityRepository) Update(
+	ctx context.Context,
+	identityId domain.EntityId,
+	updates domain.EntityUpdate,
+) error {
+	q := r.Builder.Update("external_identities").
+		Where("id = ?", identityId)
+
+	haveUpdate := false
+	if lastUseTime, ok := updates[domain.ExternalIdentityLastUseTimeFieldName]; ok {
+		q = q.Set("last_use_time", lastUseTime)
+		haveUpdate = true
+	}
+
+	if !haveUpdate {
+		return nil
+	}
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	_, err = r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+	return nil
+}
+
+// Delete unlinks the confirmed identity of the user, it fails with
+// domain.ErrExternalIdentityNotFound when the user has no such identity.
+func (r ExternalIdentityRepository) Delete(
+	ctx context.Context,
+	userId domain.EntityId,
+	identityId domain.EntityId,
+) error {
+	sql, args, err := r.Builder.
+		Delete("external_identities").
+		Where("id = ? AND user_id = ? AND confirm_time IS NOT NULL", identityId, userId).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	tag, err := r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrExternalIdentityNotFound
+	}
+	return nil
+}
+
+func (r ExternalIdentityRepository) CreateSignIn(ctx context.Context, s domain.ExternalSignIn) error {
+	sql, args, err := r.Builder.
+		Insert("external_sign_ins").
+		Columns("create_time, provider, state, nonce, code_verifier, user_id, valid_until").
+		Values(s.CreateTime, s.Provider, s.State, s.Nonce, s.CodeVerifier, s.UserId, s.ValidUntil).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	_, err = r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+	return nil
+}
+
+// RedeemSignIn marks the unused and unexpired sign in with the state as used and
+// returns it, so an authorization response cannot be replayed.
+func (r ExternalIdentityRepository) RedeemSignIn(
+	ctx context.Context,
+	state domain.Token,
+	useTime time.Time,
+) (s domain.ExternalSignIn, err error) {
+	sql, args, err := r.Builder.
+		Update("external_sign_ins").
+		Set("use_time", useTime).
+		Where("state = ? AND use_time IS NULL AND valid_until > ?", state, useTime).
+		Suffix("RETURNING " + _externalSignInColumns).
+		ToSql()
+	if err != nil {
+		return s, domain.InternalError{Err: err}
+	}
+
+	err = r.Conn(ctx).QueryRow(ctx, sql, args...).Scan(
+		&s.Id, &s.CreateTime, &s.Provider, &s.State, &s.Nonce, &s.CodeVerifier, &s.UserId, &s.ValidUntil, &s.UseTime,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, domain.ErrInvalidToken
+	} else if err != nil {
+		return s, domain.InternalError{Err: err}
+	}
+	return s, nil
+}
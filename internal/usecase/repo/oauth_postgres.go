@@ -0,0 +1,269 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/PanziApp/backend/internal/domain"
+	"github.com/PanziApp/backend/pkg/postgres"
+)
+
+const (
+	_oauthClientColumns = "id, create_time, owner_id, client_id, hashed_secret, name, redirect_uris, grant_types, scopes, " +
+		"trusted"
+	_oauthCodeColumns = "id, create_time, code_hash, client_id, user_id, redirect_uri, scopes, nonce, code_challenge, " +
+		"family, auth_time, valid_until, use_time"
+)
+
+type OAuthRepository struct {
+	*postgres.Postgres
+}
+
+func NewOAuthRepository(pg *postgres.Postgres) OAuthRepository {
+	return OAuthRepository{pg}
+}
+
+func scanOAuthClient(row pgx.Row) (c domain.OAuthClient, err error) {
+	err = row.Scan(
+		&c.Id, &c.CreateTime, &c.OwnerId, &c.ClientId, &c.HashedSecret, &c.Name, &c.RedirectURIs, &c.GrantTypes,
+		&c.Scopes, &c.Trusted,
+	)
+	return c, err
+}
+
+func scanOAuthCode(row pgx.Row) (c domain.OAuthAuthorizationCode, err error) {
+	var codeHash []byte
+	err = row.Scan(
+		&c.Id, &c.CreateTime, &codeHash, &c.ClientId, &c.UserId, &c.RedirectURI, &c.Scopes, &c.Nonce, &c.CodeChallenge,
+		&c.Family, &c.AuthTime, &c.ValidUntil, &c.UseTime,
+	)
+	c.CodeHash = codeHash
+	return c, err
+}
+
+func (r OAuthRepository) CreateClient(ctx context.Context, c domain.OAuthClient) (domain.EntityId, error) {
+	sql, args, err := r.Builder.
+		Insert("oauth_clients").
+		Columns("create_time, owner_id, client_id, hashed_secret, name, redirect_uris, grant_types, scopes, trusted").
+		Values(c.CreateTime, c.OwnerId, c.ClientId, []byte(c.HashedSecret), c.Name, c.RedirectURIs, c.GrantTypes,
+			c.Scopes, c.Trusted).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return 0, domain.InternalError{Err: err}
+	}
+
+	err = r.Conn(ctx).QueryRow(ctx, sql, args...).Scan(&c.Id)
+	if err != nil {
+		return 0, domain.InternalError{Err: err}
+	}
+	return c.Id, nil
+}
+
+func (r OAuthRepository) GetClient(ctx context.Context, clientId domain.EntityId) (c domain.OAuthClient, err error) {
+	sql, args, err := r.Builder.
+		Select(_oauthClientColumns).
+		From("oauth_clients").
+		Where("id = ?", clientId).
+		ToSql()
+	if err != nil {
+		return c, domain.InternalError{Err: err}
+	}
+
+	c, err = scanOAuthClient(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, domain.ErrOAuthClientNotFound
+	} else if err != nil {
+		return c, domain.InternalError{Err: err}
+	}
+	return c, nil
+}
+
+func (r OAuthRepository) GetClientByClientId(ctx context.Context, clientId string) (c domain.OAuthClient, err error) {
+	sql, args, err := r.Builder.
+		Select(_oauthClientColumns).
+		From("oauth_clients").
+		Where("client_id = ?", clientId).
+		ToSql()
+	if err != nil {
+		return c, domain.InternalError{Err: err}
+	}
+
+	c, err = scanOAuthClient(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, domain.ErrOAuthClientNotFound
+	} else if err != nil {
+		return c, domain.InternalError{Err: err}
+	}
+	return c, nil
+}
+
+func (r OAuthRepository) ListClientsByOwnerId(
+	ctx context.Context,
+	ownerId domain.EntityId,
+) ([]domain.OAuthClient, error) {
+	sql, args, err := r.Builder.
+		Select(_oauthClientColumns).
+		From("oauth_clients").
+		Where("owner_id = ?", ownerId).
+		OrderBy("create_time, id").
+		ToSql()
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	rows, err := r.Conn(ctx).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+	defer rows.Close()
+
+	clients := make([]domain.OAuthClient, 0)
+	for rows.Next() {
+		c, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, domain.InternalError{Err: err}
+		}
+		clients = append(clients, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	return clients, nil
+}
+
+// DeleteClient deletes the client of the owner, the tokens issued to it go with it.
+func (r OAuthRepository) DeleteClient(ctx context.Context, ownerId domain.EntityId, clientId string) error {
+	sql, args, err := r.Builder.
+		Delete("oauth_clients").
+		Where("owner_id = ? AND client_id = ?", ownerId, clientId).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	tag, err := r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrOAuthClientNotFound
+	}
+
+	return nil
+}
+
+func (r OAuthRepository) GetConsent(
+	ctx context.Context,
+	userId, clientId domain.EntityId,
+) (c domain.OAuthConsent, err error) {
+	sql, args, err := r.Builder.
+		Select("user_id, client_id, scopes, update_time").
+		From("oauth_consents").
+		Where("user_id = ? AND client_id = ?", userId, clientId).
+		ToSql()
+	if err != nil {
+		return c, domain.InternalError{Err: err}
+	}
+
+	err = r.Conn(ctx).QueryRow(ctx, sql, args...).Scan(&c.UserId, &c.ClientId, &c.Scopes, &c.UpdateTime)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, domain.ErrOAuthConsentNotFound
+	} else if err != nil {
+		return c, domain.InternalError{Err: err}
+	}
+	return c, nil
+}
+
+// SaveConsent stores the consent, replacing the previous consent of the user to the client.
+func (r OAuthRepository) SaveConsent(ctx context.Context, c domain.OAuthConsent) error {
+	sql, args, err := r.Builder.
+		Insert("oauth_consents").
+		Columns("user_id, client_id, scopes, update_time").
+		Values(c.UserId, c.ClientId, c.Scopes, c.UpdateTime).
+		Suffix("ON CONFLICT (user_id, client_id) DO UPDATE SET " +
+			"scopes = EXCLUDED.scopes, update_time = EXCLUDED.update_time").
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	_, err = r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	return nil
+}
+
+func (r OAuthRepository) CreateAuthorizationCode(ctx context.Context, c domain.OAuthAuthorizationCode) error {
+	sql, args, err := r.Builder.
+		Insert("oauth_authorization_codes").
+		Columns("create_time, code_hash, client_id, user_id, redirect_uri, scopes, nonce, code_challenge, "+
+			"family, auth_time, valid_until").
+		Values(c.CreateTime, []byte(c.Code.Hash()), c.ClientId, c.UserId, c.RedirectURI, c.Scopes, c.Nonce, c.CodeChallenge,
+			c.Family, c.AuthTime, c.ValidUntil).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	_, err = r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	return nil
+}
+
+func (r OAuthRepository) GetAuthorizationCode(
+	ctx context.Context,
+	code domain.Token,
+) (c domain.OAuthAuthorizationCode, err error) {
+	sql, args, err := r.Builder.
+		Select(_oauthCodeColumns).
+		From("oauth_authorization_codes").
+		Where("code_hash = ?", []byte(code.Hash())).
+		ToSql()
+	if err != nil {
+		return c, domain.InternalError{Err: err}
+	}
+
+	c, err = scanOAuthCode(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, domain.ErrInvalidGrant
+	} else if err != nil {
+		return c, domain.InternalError{Err: err}
+	}
+	return c, nil
+}
+
+// UseAuthorizationCode marks the valid and unused code as used in a single
+// statement, so of concurrent exchanges of a code only one succeeds.
+func (r OAuthRepository) UseAuthorizationCode(
+	ctx context.Context,
+	code domain.Token,
+	useTime time.Time,
+) (c domain.OAuthAuthorizationCode, err error) {
+	sql, args, err := r.Builder.
+		Update("oauth_authorization_codes").
+		Set("use_time", useTime).
+		Where("code_hash = ? AND use_time IS NULL AND valid_until > ?", []byte(code.Hash()), useTime).
+		Suffix("RETURNING " + _oauthCodeColumns).
+		ToSql()
+	if err != nil {
+		return c, domain.InternalError{Err: err}
+	}
+
+	c, err = scanOAuthCode(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, domain.ErrInvalidGrant
+	} else if err != nil {
+		return c, domain.InternalError{Err: err}
+	}
+	return c, nil
+}
@@ -0,0 +1,207 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/PanziApp/backend/internal/domain"
+	"github.com/PanziApp/backend/pkg/postgres"
+)
+
+const (
+	_passkeyColumns          = "id, create_time, user_id, credential_id, public_key, sign_count, transports, name, last_use_time"
+	_passkeyChallengeColumns = "id, create_time, user_id, type, challenge, valid_until, use_time"
+)
+
+type PasskeyRepository struct {
+	*postgres.Postgres
+}
+
+func NewPasskeyRepository(pg *postgres.Postgres) PasskeyRepository {
+	return PasskeyRepository{pg}
+}
+
+func scanPasskey(row pgx.Row) (p domain.Passkey, err error) {
+	err = row.Scan(
+		&p.Id, &p.CreateTime, &p.UserId, &p.CredentialId, &p.PublicKey, &p.SignCount,
+		&p.Transports, &p.Name, &p.LastUseTime,
+	)
+	return p, err
+}
+
+func (r PasskeyRepository) Create(ctx context.Context, p domain.Passkey) (domain.EntityId, error) {
+	if p.Transports == nil {
+		p.Transports = []string{}
+	}
+
+	sql, args, err := r.Builder.
+		Insert("passkeys").
+		Columns("create_time, user_id, credential_id, public_key, sign_count, transports, name").
+		Values(p.CreateTime, p.UserId, p.CredentialId, p.PublicKey, p.SignCount, p.Transports, p.Name).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return 0, domain.InternalError{Err: err}
+	}
+
+	err = r.Conn(ctx).QueryRow(ctx, sql, args...).Scan(&p.Id)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == _uniqueViolation {
+		return 0, domain.ErrDuplicatePasskey
+	} else if err != nil {
+		return 0, domain.InternalError{Err: err}
+	}
+	return p.Id, nil
+}
+
+func (r PasskeyRepository) GetByCredentialId(ctx context.Context, credentialId []byte) (p domain.Passkey, err error) {
+	sql, args, err := r.Builder.
+		Select(_passkeyColumns).
+		From("passkeys").
+		Where("credential_id = ?", credentialId).
+		ToSql()
+	if err != nil {
+		return p, domain.InternalError{Err: err}
+	}
+
+	p, err = scanPasskey(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return p, domain.ErrPasskeyNotFound
+	} else if err != nil {
+		return p, domain.InternalError{Err: err}
+	}
+	return p, nil
+}
+
+func (r PasskeyRepository) ListByUserId(ctx context.Context, userId domain.EntityId) ([]domain.Passkey, error) {
+	sql, args, err := r.Builder.
+		Select(_passkeyColumns).
+		From("passkeys").
+		Where("user_id = ?", userId).
+		OrderBy("create_time, id").
+		ToSql()
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	rows, err := r.Conn(ctx).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+	defer rows.Close()
+
+	passkeys := make([]domain.Passkey, 0)
+	for rows.Next() {
+		p, err := scanPasskey(rows)
+		if err != nil {
+			return nil, domain.InternalError{Err: err}
+		}
+		passkeys = append(passkeys, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	return passkeys, nil
+}
+
+func (r PasskeyRepository) Update(ctx context.Context, passkeyId domain.EntityId, updates domain.EntityUpdate) error {
+	q := r.Builder.Update("passkeys").
+		Where("id = ?", passkeyId)
+
+	haveUpdate := false
+	if signCount, ok := updates[domain.PasskeySignCountFieldName]; ok {
+		q = q.Set("sign_count", signCount)
+		haveUpdate = true
+	}
+	if lastUseTime, ok := updates[domain.PasskeyLastUseTimeFieldName]; ok {
+		q = q.Set("last_use_time", lastUseTime)
+		haveUpdate = true
+	}
+
+	if !haveUpdate {
+		return nil
+	}
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	_, err = r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+	return nil
+}
+
+// Delete removes the passkey of the user, it fails with domain.ErrPasskeyNotFound
+// when the user has no such passkey.
+func (r PasskeyRepository) Delete(ctx context.Context, userId domain.EntityId, credentialId []byte) error {
+	sql, args, err := r.Builder.
+		Delete("passkeys").
+		Where("user_id = ? AND credential_id = ?", userId, credentialId).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	tag, err := r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrPasskeyNotFound
+	}
+	return nil
+}
+
+func (r PasskeyRepository) CreateChallenge(ctx context.Context, c domain.PasskeyChallenge) error {
+	sql, args, err := r.Builder.
+		Insert("passkey_challenges").
+		Columns("create_time, user_id, type, challenge, valid_until").
+		Values(c.CreateTime, c.UserId, c.Type, c.Challenge, c.ValidUntil).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	_, err = r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+	return nil
+}
+
+// RedeemChallenge marks the unused and unexpired challenge as used and returns it,
+// a challenge can be redeemed only once.
+func (r PasskeyRepository) RedeemChallenge(
+	ctx context.Context,
+	challenge domain.Token,
+	challengeType domain.PasskeyChallengeType,
+	useTime time.Time,
+) (c domain.PasskeyChallenge, err error) {
+	sql, args, err := r.Builder.
+		Update("passkey_challenges").
+		Set("use_time", useTime).
+		Where("challenge = ? AND type = ? AND use_time IS NULL AND valid_until > ?", challenge, challengeType, useTime).
+		Suffix("RETURNING " + _passkeyChallengeColumns).
+		ToSql()
+	if err != nil {
+		return c, domain.InternalError{Err: err}
+	}
+
+	err = r.Conn(ctx).QueryRow(ctx, sql, args...).Scan(
+		&c.Id, &c.CreateTime, &c.UserId, &c.Type, &c.Challenge, &c.ValidUntil, &c.UseTime,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, domain.ErrInvalidToken
+	} else if err != nil {
+		return c, domain.InternalError{Err: err}
+	}
+	return c, nil
+}
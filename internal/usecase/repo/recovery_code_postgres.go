@@ -0,0 +1,102 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/PanziApp/backend/internal/domain"
+	"github.com/PanziApp/backend/pkg/postgres"
+)
+
+type RecoveryCodeRepository struct {
+	*postgres.Postgres
+}
+
+func NewRecoveryCodeRepository(pg *postgres.Postgres) RecoveryCodeRepository {
+	return RecoveryCodeRepository{pg}
+}
+
+// Replace deletes the recovery codes of the user and stores the new ones.
+func (r RecoveryCodeRepository) Replace(
+	ctx context.Context,
+	userId domain.EntityId,
+	codes []domain.HashedRecoveryCode,
+) (err error) {
+	tx, err := r.Conn(ctx).Begin(ctx)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	sql, args, err := r.Builder.
+		Delete("recovery_codes").
+		Where("user_id = ?", userId).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	_, err = tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	if len(codes) > 0 {
+		q := r.Builder.
+			Insert("recovery_codes").
+			Columns("create_time, user_id, hashed_code")
+		now := time.Now()
+		for _, code := range codes {
+			q = q.Values(now, userId, code)
+		}
+
+		sql, args, err = q.ToSql()
+		if err != nil {
+			return domain.InternalError{Err: err}
+		}
+
+		_, err = tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return domain.InternalError{Err: err}
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	return nil
+}
+
+// Use marks the unused recovery code of the user as used, it fails with
+// domain.ErrInvalidMfaCode when there is no such code.
+func (r RecoveryCodeRepository) Use(
+	ctx context.Context,
+	userId domain.EntityId,
+	code domain.HashedRecoveryCode,
+	useTime time.Time,
+) error {
+	sql, args, err := r.Builder.
+		Update("recovery_codes").
+		Set("use_time", useTime).
+		Where("user_id = ? AND hashed_code = ? AND use_time IS NULL", userId, code).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	tag, err := r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrInvalidMfaCode
+	}
+
+	return nil
+}
@@ -0,0 +1,263 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/PanziApp/backend/internal/domain"
+	"github.com/PanziApp/backend/pkg/postgres"
+)
+
+const _roleColumns = "roles.id, roles.create_time, roles.name, roles.description, roles.permissions"
+
+type RoleRepository struct {
+	*postgres.Postgres
+}
+
+func NewRoleRepository(pg *postgres.Postgres) RoleRepository {
+	return RoleRepository{pg}
+}
+
+func scanRole(row pgx.Row) (r domain.Role, err error) {
+	var permissions []string
+	err = row.Scan(&r.Id, &r.CreateTime, &r.Name, &r.Description, &permissions)
+	r.Permissions = make([]domain.Permission, 0, len(permissions))
+	for _, p := range permissions {
+		r.Permissions = append(r.Permissions, domain.Permission(p))
+	}
+	return r, err
+}
+
+func permissionStrings(permissions []domain.Permission) []string {
+	s := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		s = append(s, string(p))
+	}
+	return s
+}
+
+func (r RoleRepository) Create(ctx context.Context, role domain.Role) (domain.EntityId, error) {
+	sql, args, err := r.Builder.
+		Insert("roles").
+		Columns("create_time, name, description, permissions").
+		Values(role.CreateTime, role.Name, role.Description, permissionStrings(role.Permissions)).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return 0, domain.InternalError{Err: err}
+	}
+
+	err = r.Conn(ctx).QueryRow(ctx, sql, args...).Scan(&role.Id)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == _uniqueViolation {
+		return 0, domain.ErrDuplicateRole
+	} else if err != nil {
+		return 0, domain.InternalError{Err: err}
+	}
+	return role.Id, nil
+}
+
+func (r RoleRepository) Get(ctx context.Context, roleId domain.EntityId) (role domain.Role, err error) {
+	sql, args, err := r.Builder.
+		Select(_roleColumns).
+		From("roles").
+		Where("id = ?", roleId).
+		ToSql()
+	if err != nil {
+		return role, domain.InternalError{Err: err}
+	}
+
+	role, err = scanRole(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return role, domain.ErrRoleNotFound
+	} else if err != nil {
+		return role, domain.InternalError{Err: err}
+	}
+	return role, nil
+}
+
+func (r RoleRepository) GetByName(ctx context.Context, name string) (role domain.Role, err error) {
+	sql, args, err := r.Builder.
+		Select(_roleColumns).
+		From("roles").
+		Where("name = ?", name).
+		ToSql()
+	if err != nil {
+		return role, domain.InternalError{Err: err}
+	}
+
+	role, err = scanRole(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return role, domain.ErrRoleNotFound
+	} else if err != nil {
+		return role, domain.InternalError{Err: err}
+	}
+	return role, nil
+}
+
+// List returns every role by name.
+func (r RoleRepository) List(ctx context.Context) ([]domain.Role, error) {
+	sql, args, err := r.Builder.
+		Select(_roleColumns).
+		From("roles").
+		OrderBy("name").
+		ToSql()
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	return r.list(ctx, sql, args)
+}
+
+// ListByUserId returns the roles assigned to the user by name.
+func (r RoleRepository) ListByUserId(ctx context.Context, userId domain.EntityId) ([]domain.Role, error) {
+	sql, args, err := r.Builder.
+		Select(_roleColumns).
+		From("roles").
+		Join("user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userId).
+		OrderBy("roles.name").
+		ToSql()
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	return r.list(ctx, sql, args)
+}
+
+func (r RoleRepository) list(ctx context.Context, sql string, args []interface{}) ([]domain.Role, error) {
+	rows, err := r.Conn(ctx).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+	defer rows.Close()
+
+	roles := make([]domain.Role, 0)
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, domain.InternalError{Err: err}
+		}
+		roles = append(roles, role)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	return roles, nil
+}
+
+func (r RoleRepository) Update(ctx context.Context, roleId domain.EntityId, updates domain.EntityUpdate) error {
+	q := r.Builder.Update("roles").
+		Where("id = ?", roleId)
+
+	haveUpdate := false
+	if description, ok := updates[domain.RoleDescriptionFieldName]; ok {
+		q = q.Set("description", description)
+		haveUpdate = true
+	}
+	if permissions, ok := updates[domain.RolePermissionsFieldName].([]domain.Permission); ok {
+		q = q.Set("permissions", permissionStrings(permissions))
+		haveUpdate = true
+	}
+
+	if !haveUpdate {
+		return nil
+	}
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	_, err = r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	return nil
+}
+
+// Delete deletes the role, its assignments go with it.
+func (r RoleRepository) Delete(ctx context.Context, roleId domain.EntityId) error {
+	sql, args, err := r.Builder.
+		Delete("roles").
+		Where("id = ?", roleId).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	tag, err := r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrRoleNotFound
+	}
+
+	return nil
+}
+
+// Assign assigns the role to the user, assigning it again does nothing.
+func (r RoleRepository) Assign(
+	ctx context.Context,
+	userId, roleId domain.EntityId,
+	assignTime time.Time,
+) error {
+	sql, args, err := r.Builder.
+		Insert("user_roles").
+		Columns("user_id, role_id, assign_time").
+		Values(userId, roleId, assignTime).
+		Suffix("ON CONFLICT (user_id, role_id) DO NOTHING").
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	_, err = r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	return nil
+}
+
+func (r RoleRepository) Unassign(ctx context.Context, userId, roleId domain.EntityId) error {
+	sql, args, err := r.Builder.
+		Delete("user_roles").
+		Where("user_id = ? AND role_id = ?", userId, roleId).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	_, err = r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	return nil
+}
+
+// CountUsers returns how many users have the role.
+func (r RoleRepository) CountUsers(ctx context.Context, roleId domain.EntityId) (count int, err error) {
+	sql, args, err := r.Builder.
+		Select("count(*)").
+		From("user_roles").
+		Where("role_id = ?", roleId).
+		ToSql()
+	if err != nil {
+		return 0, domain.InternalError{Err: err}
+	}
+
+	err = r.Conn(ctx).QueryRow(ctx, sql, args...).Scan(&count)
+	if err != nil {
+		return 0, domain.InternalError{Err: err}
+	}
+	return count, nil
+}
@@ -2,61 +2,212 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+
 	"github.com/PanziApp/backend/internal/domain"
 	"github.com/PanziApp/backend/pkg/postgres"
 )
 
+const _sessionColumns = "id, create_time, user_id, type, token_hash, valid_until, family, auth_time, use_time, " +
+	"attempts, code, client_id, scope, user_agent, ip, device_name"
+
 type SessionRepository struct {
-	postgres.Postgres
+	*postgres.Postgres
 }
 
-func NewSessionRepository(pg postgres.Postgres) SessionRepository {
+func NewSessionRepository(pg *postgres.Postgres) SessionRepository {
 	return SessionRepository{pg}
 }
 
+func scanSession(row pgx.Row) (s domain.Session, err error) {
+	var userId *domain.EntityId
+	var tokenHash []byte
+	err = row.Scan(
+		&s.Id, &s.CreateTime, &userId, &s.Type, &tokenHash, &s.ValidUntil,
+		&s.Family, &s.AuthTime, &s.UseTime,
+		&s.Attempts, &s.Code, &s.ClientId, &s.Scope, &s.Meta.UserAgent, &s.Meta.IP, &s.Meta.DeviceName,
+	)
+	if userId != nil {
+		s.UserId = *userId
+	}
+	s.TokenHash = tokenHash
+	return s, err
+}
+
 func (r SessionRepository) Create(ctx context.Context, s domain.Session) (domain.EntityId, error) {
 	sql, args, err := r.Builder.
 		Insert("sessions").
-		Columns("create_time, user_id, type, token, valid_until").
-		Values(s.CreateTime, s.UserId, s.Type, s.Token, s.ValidUntil).
+		Columns("create_time, user_id, type, token_hash, valid_until, family, auth_time, use_time, "+
+			"code, client_id, scope, user_agent, ip, device_name").
+		Values(s.CreateTime, squirrel.Expr("NULLIF(?::bigint, 0)", s.UserId), s.Type, []byte(s.Token.Hash()), s.ValidUntil,
+			s.Family, s.AuthTime, s.UseTime, s.Code, s.ClientId, s.Scope, s.Meta.UserAgent, s.Meta.IP, s.Meta.DeviceName).
 		Suffix("RETURNING id").
 		ToSql()
 	if err != nil {
 		return 0, domain.InternalError{Err: err}
 	}
 
-	err = r.Pool.QueryRow(ctx, sql, args...).Scan(&s.Id)
+	err = r.Conn(ctx).QueryRow(ctx, sql, args...).Scan(&s.Id)
 	if err != nil {
 		return 0, domain.InternalError{Err: err}
 	}
 	return s.Id, nil
 }
 
+func (r SessionRepository) Get(ctx context.Context, sessionId domain.EntityId) (s domain.Session, err error) {
+	sql, args, err := r.Builder.
+		Select(_sessionColumns).
+		From("sessions").
+		Where("id = ?", sessionId).
+		ToSql()
+	if err != nil {
+		return s, domain.InternalError{Err: err}
+	}
+
+	s, err = scanSession(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, domain.ErrSessionNotFound
+	} else if err != nil {
+		return s, domain.InternalError{Err: err}
+	}
+	return s, nil
+}
+
 func (r SessionRepository) GetByToken(ctx context.Context, token domain.Token) (s domain.Session, err error) {
 	sql, args, err := r.Builder.
-		Select("id, create_time, user_id, type, token, valid_until").
+		Select(_sessionColumns).
 		From("sessions").
-		Where("token = ?", token).
+		Where("token_hash = ?", []byte(token.Hash())).
 		ToSql()
 	if err != nil {
 		return s, domain.InternalError{Err: err}
 	}
 
-	err = r.Pool.QueryRow(ctx, sql, args...).
-		Scan(&s.Id, &s.CreateTime, &s.UserId, &s.Type, &s.Type, &s.Token, &s.ValidUntil)
+	s, err = scanSession(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, domain.ErrInvalidToken
+	} else if err != nil {
+		return s, domain.InternalError{Err: err}
+	}
+	if !s.TokenHash.Match(token) {
+		return domain.Session{}, domain.ErrInvalidToken
+	}
+	return s, nil
+}
+
+func (r SessionRepository) GetLastByUserId(
+	ctx context.Context,
+	userId domain.EntityId,
+	tokenType domain.TokenType,
+) (s domain.Session, err error) {
+	sql, args, err := r.Builder.
+		Select(_sessionColumns).
+		From("sessions").
+		Where("user_id = ? AND type = ?", userId, tokenType).
+		OrderBy("create_time DESC").
+		Limit(1).
+		ToSql()
 	if err != nil {
 		return s, domain.InternalError{Err: err}
 	}
+
+	s, err = scanSession(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, domain.ErrSessionNotFound
+	} else if err != nil {
+		return s, domain.InternalError{Err: err}
+	}
 	return s, nil
 }
 
+// ListByUserId returns the valid and unused first-party sessions of the type,
+// oldest sign in first. Tokens issued to OAuth clients are not listed.
+func (r SessionRepository) ListByUserId(
+	ctx context.Context,
+	userId domain.EntityId,
+	tokenType domain.TokenType,
+	validAt time.Time,
+) ([]domain.Session, error) {
+	sql, args, err := r.Builder.
+		Select(_sessionColumns).
+		From("sessions").
+		Where("user_id = ? AND type = ? AND use_time IS NULL AND client_id IS NULL", userId, tokenType).
+		Where("(valid_until IS NULL OR valid_until > ?)", validAt).
+		OrderBy("auth_time, id").
+		ToSql()
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	rows, err := r.Conn(ctx).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+	defer rows.Close()
+
+	sessions := make([]domain.Session, 0)
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, domain.InternalError{Err: err}
+		}
+		sessions = append(sessions, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	return sessions, nil
+}
+
+// ListAllByUserId returns every session of the user, valid or not, oldest first.
+func (r SessionRepository) ListAllByUserId(ctx context.Context, userId domain.EntityId) ([]domain.Session, error) {
+	sql, args, err := r.Builder.
+		Select(_sessionColumns).
+		From("sessions").
+		Where("user_id = ?", userId).
+		OrderBy("create_time, id").
+		ToSql()
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	rows, err := r.Conn(ctx).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+	defer rows.Close()
+
+	sessions := make([]domain.Session, 0)
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, domain.InternalError{Err: err}
+		}
+		sessions = append(sessions, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	return sessions, nil
+}
+
 func (r SessionRepository) Update(ctx context.Context, sessionId domain.EntityId, updates domain.EntityUpdate) error {
 	q := r.Builder.Update("sessions").
 		Where("id = ?", sessionId)
 
 	haveUpdate := false
 	if validUntil, ok := updates[domain.SessionValidUntilFieldName]; ok {
-		q.Set("valid_until", validUntil)
+		q = q.Set("valid_until", validUntil)
+		haveUpdate = true
+	}
+	if useTime, ok := updates[domain.SessionUseTimeFieldName]; ok {
+		q = q.Set("use_time", useTime)
 		haveUpdate = true
 	}
 
@@ -69,10 +220,173 @@ func (r SessionRepository) Update(ctx context.Context, sessionId domain.EntityId
 		return domain.InternalError{Err: err}
 	}
 
-	_, err = r.Pool.Exec(ctx, sql, args...)
+	_, err = r.Conn(ctx).Exec(ctx, sql, args...)
 	if err != nil {
 		return domain.InternalError{Err: err}
 	}
 
 	return nil
 }
+
+// Use marks a single use session as used, it fails with domain.ErrInvalidToken
+// if the session was already used, so concurrent uses cannot both succeed.
+func (r SessionRepository) Use(ctx context.Context, sessionId domain.EntityId, useTime time.Time) error {
+	sql, args, err := r.Builder.
+		Update("sessions").
+		Set("use_time", useTime).
+		Where("id = ? AND use_time IS NULL", sessionId).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	tag, err := r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrInvalidToken
+	}
+
+	return nil
+}
+
+// Redeem marks the valid and unused session of the token and type as used in a
+// single statement, so of concurrent redemptions of a token only one succeeds.
+// Sessions are looked up by the hash of their token.
+func (r SessionRepository) Redeem(
+	ctx context.Context,
+	token domain.Token,
+	tokenType domain.TokenType,
+	useTime time.Time,
+) (s domain.Session, err error) {
+	sql, args, err := r.Builder.
+		Update("sessions").
+		Set("use_time", useTime).
+		Where("token_hash = ? AND type = ? AND use_time IS NULL", []byte(token.Hash()), tokenType).
+		Where("(valid_until IS NULL OR valid_until > ?)", useTime).
+		Suffix("RETURNING " + _sessionColumns).
+		ToSql()
+	if err != nil {
+		return s, domain.InternalError{Err: err}
+	}
+
+	s, err = scanSession(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, domain.ErrInvalidToken
+	} else if err != nil {
+		return s, domain.InternalError{Err: err}
+	}
+	if !s.TokenHash.Match(token) {
+		return domain.Session{}, domain.ErrInvalidToken
+	}
+	return s, nil
+}
+
+// AddAttempt counts an attempt to complete the challenge of the session, before
+// the attempt is checked. It fails with domain.ErrInvalidToken when the session
+// is used, expired or has no attempts left, in the same statement, so concurrent
+// attempts cannot go past maxAttempts.
+func (r SessionRepository) AddAttempt(
+	ctx context.Context,
+	sessionId domain.EntityId,
+	maxAttempts int,
+	attemptTime time.Time,
+) error {
+	sql, args, err := r.Builder.
+		Update("sessions").
+		Set("attempts", squirrel.Expr("attempts + 1")).
+		Where("id = ? AND use_time IS NULL AND attempts < ?", sessionId, maxAttempts).
+		Where("(valid_until IS NULL OR valid_until > ?)", attemptTime).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	tag, err := r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrInvalidToken
+	}
+
+	return nil
+}
+
+// RevokeFamily expires every still valid session of the family.
+func (r SessionRepository) RevokeFamily(ctx context.Context, family string, revokeTime time.Time) error {
+	sql, args, err := r.Builder.
+		Update("sessions").
+		Set("valid_until", revokeTime).
+		Where("family = ? AND (valid_until IS NULL OR valid_until > ?)", family, revokeTime).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	_, err = r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	return nil
+}
+
+// RevokeByUserId expires every still valid session of the user with one of the
+// types, except the sessions of the family of except, or except itself when it
+// has no family. The zero session excepts none.
+func (r SessionRepository) RevokeByUserId(
+	ctx context.Context,
+	userId domain.EntityId,
+	tokenTypes []domain.TokenType,
+	except domain.Session,
+	revokeTime time.Time,
+) error {
+	q := r.Builder.
+		Update("sessions").
+		Set("valid_until", revokeTime).
+		Where(squirrel.Eq{"user_id": userId, "type": tokenTypes}).
+		Where("(valid_until IS NULL OR valid_until > ?)", revokeTime)
+	if except.Family != "" {
+		q = q.Where("family <> ?", except.Family)
+	} else if except.Id != 0 {
+		q = q.Where("id <> ?", except.Id)
+	}
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	_, err = r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	return nil
+}
+
+// RevokeAllByUserId expires every still valid session of the user, of any
+// type, and returns how many it expired.
+func (r SessionRepository) RevokeAllByUserId(
+	ctx context.Context,
+	userId domain.EntityId,
+	revokeTime time.Time,
+) (int, error) {
+	sql, args, err := r.Builder.
+		Update("sessions").
+		Set("valid_until", revokeTime).
+		Where("user_id = ? AND (valid_until IS NULL OR valid_until > ?)", userId, revokeTime).
+		ToSql()
+	if err != nil {
+		return 0, domain.InternalError{Err: err}
+	}
+
+	tag, err := r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, domain.InternalError{Err: err}
+	}
+
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,135 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/PanziApp/backend/internal/domain"
+	"github.com/PanziApp/backend/pkg/postgres"
+)
+
+const _signInFailureColumns = "key, failures, last_failure_time, lock_until"
+
+type SignInFailureRepository struct {
+	*postgres.Postgres
+}
+
+func NewSignInFailureRepository(pg *postgres.Postgres) SignInFailureRepository {
+	return SignInFailureRepository{pg}
+}
+
+func scanSignInFailures(row pgx.Row) (f domain.SignInFailures, err error) {
+	err = row.Scan(&f.Key, &f.Failures, &f.LastFailureTime, &f.LockUntil)
+	return f, err
+}
+
+// Get returns the failures of the key, which are zero when none were recorded.
+func (r SignInFailureRepository) Get(ctx context.Context, key string) (domain.SignInFailures, error) {
+	sql, args, err := r.Builder.
+		Select(_signInFailureColumns).
+		From("sign_in_failures").
+		Where("key = ?", key).
+		ToSql()
+	if err != nil {
+		return domain.SignInFailures{}, domain.InternalError{Err: err}
+	}
+
+	f, err := scanSignInFailures(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.SignInFailures{Key: key}, nil
+	} else if err != nil {
+		return f, domain.InternalError{Err: err}
+	}
+	return f, nil
+}
+
+// GetForUpdate returns the failures of the key like Get, and locks them until
+// the transaction of ctx ends, so concurrent sign ins with the key are checked
+// and recorded one after the other. The row locked for a key without failures
+// counts none.
+func (r SignInFailureRepository) GetForUpdate(
+	ctx context.Context,
+	key string,
+	now time.Time,
+) (domain.SignInFailures, error) {
+	sql, args, err := r.Builder.
+		Insert("sign_in_failures").
+		Columns("key, failures, last_failure_time").
+		Values(key, 0, now).
+		Suffix("ON CONFLICT (key) DO UPDATE SET key = EXCLUDED.key RETURNING " + _signInFailureColumns).
+		ToSql()
+	if err != nil {
+		return domain.SignInFailures{}, domain.InternalError{Err: err}
+	}
+
+	f, err := scanSignInFailures(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if err != nil {
+		return f, domain.InternalError{Err: err}
+	}
+	return f, nil
+}
+
+// Record counts a failure of the key at now and returns the updated failures.
+// The count starts over when the last failure happened before since.
+func (r SignInFailureRepository) Record(
+	ctx context.Context,
+	key string,
+	now, since time.Time,
+) (domain.SignInFailures, error) {
+	sql, args, err := r.Builder.
+		Insert("sign_in_failures").
+		Columns("key, failures, last_failure_time").
+		Values(key, 1, now).
+		Suffix("ON CONFLICT (key) DO UPDATE SET "+
+			"failures = CASE WHEN sign_in_failures.last_failure_time < ? THEN 1 "+
+			"ELSE sign_in_failures.failures + 1 END, "+
+			"last_failure_time = EXCLUDED.last_failure_time "+
+			"RETURNING "+_signInFailureColumns, since).
+		ToSql()
+	if err != nil {
+		return domain.SignInFailures{}, domain.InternalError{Err: err}
+	}
+
+	f, err := scanSignInFailures(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if err != nil {
+		return f, domain.InternalError{Err: err}
+	}
+	return f, nil
+}
+
+func (r SignInFailureRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	sql, args, err := r.Builder.
+		Update("sign_in_failures").
+		Set("lock_until", until).
+		Where("key = ?", key).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	_, err = r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+	return nil
+}
+
+// Clear forgets the failures of the key, lifting its lock.
+func (r SignInFailureRepository) Clear(ctx context.Context, key string) error {
+	sql, args, err := r.Builder.
+		Delete("sign_in_failures").
+		Where("key = ?", key).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	_, err = r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/PanziApp/backend/internal/domain"
+	"github.com/PanziApp/backend/pkg/postgres"
+)
+
+// Transactor runs the statements of the repositories in a transaction.
+type Transactor struct {
+	*postgres.Postgres
+}
+
+func NewTransactor(pg *postgres.Postgres) Transactor {
+	return Transactor{pg}
+}
+
+// InTx runs fn in a transaction, the repositories called with the context
+// passed to fn join it. It is committed when fn succeeds, and the error of fn
+// is returned otherwise.
+func (t Transactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	var fnErr error
+	err := t.Postgres.InTx(ctx, func(ctx context.Context) error {
+		fnErr = fn(ctx)
+		return fnErr
+	})
+	if err != nil && fnErr == nil {
+		return domain.InternalError{Err: err}
+	}
+
+	return err
+}
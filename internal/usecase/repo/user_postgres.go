@@ -2,18 +2,38 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+
 	"github.com/PanziApp/backend/internal/domain"
 	"github.com/PanziApp/backend/pkg/postgres"
 )
 
+const _uniqueViolation = "23505"
+
+const _userColumns = "id, create_time, email, email_verify_time, pending_email, hashed_password, fullname, " +
+	"avatar, totp_secret, totp_enable_time, totp_last_step, delete_time, disable_time"
+
 type UserRepository struct {
-	postgres.Postgres
+	*postgres.Postgres
 }
 
-func NewUserRepository(pg postgres.Postgres) UserRepository {
+func NewUserRepository(pg *postgres.Postgres) UserRepository {
 	return UserRepository{pg}
 }
 
+func scanUser(row pgx.Row) (u domain.User, err error) {
+	err = row.Scan(
+		&u.Id, &u.CreateTime, &u.Email, &u.EmailVerifyTime, &u.PendingEmail, &u.HashedPassword, &u.Fullname,
+		&u.Avatar, &u.TotpSecret, &u.TotpEnableTime, &u.TotpLastStep, &u.DeleteTime, &u.DisableTime,
+	)
+	return u, err
+}
+
 func (r UserRepository) Create(ctx context.Context, u domain.User) (domain.EntityId, error) {
 	sql, args, err := r.Builder.
 		Insert("users").
@@ -25,8 +45,11 @@ func (r UserRepository) Create(ctx context.Context, u domain.User) (domain.Entit
 		return 0, domain.InternalError{Err: err}
 	}
 
-	err = r.Pool.QueryRow(ctx, sql, args...).Scan(&u.Id)
-	if err != nil {
+	err = r.Conn(ctx).QueryRow(ctx, sql, args...).Scan(&u.Id)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == _uniqueViolation {
+		return 0, domain.ErrDuplicateEmail
+	} else if err != nil {
 		return 0, domain.InternalError{Err: err}
 	}
 	return u.Id, nil
@@ -34,7 +57,7 @@ func (r UserRepository) Create(ctx context.Context, u domain.User) (domain.Entit
 
 func (r UserRepository) Get(ctx context.Context, userId domain.EntityId) (u domain.User, err error) {
 	sql, args, err := r.Builder.
-		Select("id, create_time, email, email_verify_time, hashed_password, fullname, avatar").
+		Select(_userColumns).
 		From("users").
 		Where("id = ?", userId).
 		ToSql()
@@ -42,9 +65,10 @@ func (r UserRepository) Get(ctx context.Context, userId domain.EntityId) (u doma
 		return u, domain.InternalError{Err: err}
 	}
 
-	err = r.Pool.QueryRow(ctx, sql, args...).
-		Scan(&u.Id, &u.CreateTime, &u.Email, &u.EmailVerifyTime, &u.HashedPassword, &u.Fullname, &u.Avatar)
-	if err != nil {
+	u, err = scanUser(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return u, domain.ErrUserNotFound
+	} else if err != nil {
 		return u, domain.InternalError{Err: err}
 	}
 	return u, nil
@@ -52,7 +76,7 @@ func (r UserRepository) Get(ctx context.Context, userId domain.EntityId) (u doma
 
 func (r UserRepository) GetByEmail(ctx context.Context, email domain.Email) (u domain.User, err error) {
 	sql, args, err := r.Builder.
-		Select("id, create_time, email, email_verify_time, hashed_password, fullname, avatar").
+		Select(_userColumns).
 		From("users").
 		Where("email = ?", email).
 		ToSql()
@@ -60,9 +84,10 @@ func (r UserRepository) GetByEmail(ctx context.Context, email domain.Email) (u d
 		return u, domain.InternalError{Err: err}
 	}
 
-	err = r.Pool.QueryRow(ctx, sql, args...).
-		Scan(&u.Id, &u.CreateTime, &u.Email, &u.EmailVerifyTime, &u.HashedPassword, &u.Fullname, &u.Avatar)
-	if err != nil {
+	u, err = scanUser(r.Conn(ctx).QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return u, domain.ErrUserNotFound
+	} else if err != nil {
 		return u, domain.InternalError{Err: err}
 	}
 	return u, nil
@@ -73,20 +98,48 @@ func (r UserRepository) Update(ctx context.Context, userId domain.EntityId, upda
 		Where("id = ?", userId)
 
 	haveUpdate := false
+	if email, ok := updates[domain.UserEmailFieldName]; ok {
+		q = q.Set("email", email)
+		haveUpdate = true
+	}
 	if emailVerifyTime, ok := updates[domain.UserEmailVerifyTimeFieldName]; ok {
-		q.Set("email_verify_time", emailVerifyTime)
+		q = q.Set("email_verify_time", emailVerifyTime)
+		haveUpdate = true
+	}
+	if pendingEmail, ok := updates[domain.UserPendingEmailFieldName]; ok {
+		q = q.Set("pending_email", pendingEmail)
 		haveUpdate = true
 	}
 	if hashedPassword, ok := updates[domain.UserHashedPasswordFieldName]; ok {
-		q.Set("hashed_password", hashedPassword)
+		q = q.Set("hashed_password", hashedPassword)
 		haveUpdate = true
 	}
 	if fullname, ok := updates[domain.UserFullnameFieldName]; ok {
-		q.Set("fullname", fullname)
+		q = q.Set("fullname", fullname)
 		haveUpdate = true
 	}
 	if avatar, ok := updates[domain.UserAvatarFieldName]; ok {
-		q.Set("avatar", avatar)
+		q = q.Set("avatar", avatar)
+		haveUpdate = true
+	}
+	if totpSecret, ok := updates[domain.UserTotpSecretFieldName]; ok {
+		q = q.Set("totp_secret", totpSecret)
+		haveUpdate = true
+	}
+	if totpEnableTime, ok := updates[domain.UserTotpEnableTimeFieldName]; ok {
+		q = q.Set("totp_enable_time", totpEnableTime)
+		haveUpdate = true
+	}
+	if totpLastStep, ok := updates[domain.UserTotpLastStepFieldName]; ok {
+		q = q.Set("totp_last_step", totpLastStep)
+		haveUpdate = true
+	}
+	if deleteTime, ok := updates[domain.UserDeleteTimeFieldName]; ok {
+		q = q.Set("delete_time", deleteTime)
+		haveUpdate = true
+	}
+	if disableTime, ok := updates[domain.UserDisableTimeFieldName]; ok {
+		q = q.Set("disable_time", disableTime)
 		haveUpdate = true
 	}
 
@@ -99,10 +152,129 @@ func (r UserRepository) Update(ctx context.Context, userId domain.EntityId, upda
 		return domain.InternalError{Err: err}
 	}
 
-	_, err = r.Pool.Exec(ctx, sql, args...)
+	_, err = r.Conn(ctx).Exec(ctx, sql, args...)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == _uniqueViolation {
+		return domain.ErrDuplicateEmail
+	} else if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	return nil
+}
+
+// UseTotpStep records the period of an accepted authenticator app code. It fails
+// with domain.ErrInvalidMfaCode when the step or a later one was already used,
+// so the same code cannot be accepted twice, even concurrently.
+func (r UserRepository) UseTotpStep(ctx context.Context, userId domain.EntityId, step int64) error {
+	sql, args, err := r.Builder.
+		Update("users").
+		Set("totp_last_step", step).
+		Where("id = ? AND totp_last_step < ?", userId, step).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	tag, err := r.Conn(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrInvalidMfaCode
+	}
+
+	return nil
+}
+
+// Search returns up to search.Limit users matching the search, by id.
+func (r UserRepository) Search(ctx context.Context, search domain.UserSearch) ([]domain.User, error) {
+	q := r.Builder.
+		Select(_userColumns).
+		From("users").
+		Where("id > ?", search.AfterId).
+		OrderBy("id").
+		Limit(search.Limit)
+	if search.Query != "" {
+		pattern := "%" + likeEscaper.Replace(search.Query) + "%"
+		q = q.Where("(email ILIKE ? OR fullname ILIKE ?)", pattern, pattern)
+	}
+	if search.CreatedAfter != nil {
+		q = q.Where("create_time >= ?", *search.CreatedAfter)
+	}
+	if search.CreatedBefore != nil {
+		q = q.Where("create_time < ?", *search.CreatedBefore)
+	}
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	return r.list(ctx, sql, args)
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns, so they match literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// ListDeleted returns up to limit users whose pending deletion is due at now, the
+// earliest due first.
+func (r UserRepository) ListDeleted(ctx context.Context, now time.Time, limit uint64) ([]domain.User, error) {
+	sql, args, err := r.Builder.
+		Select(_userColumns).
+		From("users").
+		Where("delete_time <= ?", now).
+		OrderBy("delete_time, id").
+		Limit(limit).
+		ToSql()
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	return r.list(ctx, sql, args)
+}
+
+func (r UserRepository) list(ctx context.Context, sql string, args []interface{}) ([]domain.User, error) {
+	rows, err := r.Conn(ctx).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+	defer rows.Close()
+
+	users := make([]domain.User, 0)
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, domain.InternalError{Err: err}
+		}
+		users = append(users, u)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain.InternalError{Err: err}
+	}
+
+	return users, nil
+}
+
+// DeleteDue removes the user if its pending deletion is due at now, the rows of
+// the user in other tables go with it. It fails with domain.ErrUserNotFound when
+// the user is gone or the deletion was canceled.
+func (r UserRepository) DeleteDue(ctx context.Context, userId domain.EntityId, now time.Time) error {
+	sql, args, err := r.Builder.
+		Delete("users").
+		Where("id = ? AND delete_time IS NOT NULL AND delete_time <= ?", userId, now).
+		ToSql()
+	if err != nil {
+		return domain.InternalError{Err: err}
+	}
+
+	tag, err := r.Conn(ctx).Exec(ctx, sql, args...)
 	if err != nil {
 		return domain.InternalError{Err: err}
 	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
 
 	return nil
 }
@@ -0,0 +1,256 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PanziApp/backend/internal/domain"
+)
+
+// ListRoles returns every role with its permissions.
+func (uc UserUseCase) ListRoles(
+	ctx context.Context,
+	principal Principal,
+) ([]domain.Role, error) {
+	if err := principal.Authorize(domain.PermissionReadRoles); err != nil {
+		return nil, err
+	}
+
+	return uc.repo.role.List(ctx)
+}
+
+func (uc UserUseCase) CreateRole(
+	ctx context.Context,
+	principal Principal,
+	name, description string,
+	permissions []string,
+) (role domain.Role, err error) {
+	if err = principal.Authorize(domain.PermissionManageRoles); err != nil {
+		return role, err
+	}
+
+	role = domain.Role{CreateTime: time.Now()}
+	if role.Name, err = domain.ValidateRoleName(name); err != nil {
+		return role, err
+	}
+	if role.Description, err = domain.ValidateRoleDescription(description); err != nil {
+		return role, err
+	}
+	if role.Permissions, err = domain.ValidatePermissions(permissions); err != nil {
+		return role, err
+	}
+
+	err = uc.audited(ctx, principal, domain.AuditRoleCreate, 0, func(ctx context.Context) (string, error) {
+		role.Id, err = uc.repo.role.Create(ctx, role)
+		return roleDetails(role.Name, role.Permissions), err
+	})
+	return role, err
+}
+
+// roleDetails describes a role in the audit log.
+func roleDetails(name string, permissions []domain.Permission) string {
+	return fmt.Sprintf("%s %v", name, permissions)
+}
+
+type RoleUpdateDTO struct {
+	Description *string
+	Permissions *[]string
+}
+
+// UpdateRole changes the description or permissions of a role, the users of
+// the role are granted the new permissions on their next request.
+func (uc UserUseCase) UpdateRole(
+	ctx context.Context,
+	principal Principal,
+	roleId domain.EntityId,
+	update RoleUpdateDTO,
+) error {
+	if err := principal.Authorize(domain.PermissionManageRoles); err != nil {
+		return err
+	}
+
+	role, err := uc.repo.role.Get(ctx, roleId)
+	if err != nil {
+		return err
+	}
+
+	if role.Builtin() {
+		return domain.ErrBuiltinRole
+	}
+
+	updates := domain.EntityUpdate{}
+	permissions := role.Permissions
+	if update.Description != nil {
+		description, err := domain.ValidateRoleDescription(*update.Description)
+		if err != nil {
+			return err
+		}
+		updates[domain.RoleDescriptionFieldName] = description
+	}
+	if update.Permissions != nil {
+		permissions, err = domain.ValidatePermissions(*update.Permissions)
+		if err != nil {
+			return err
+		}
+		updates[domain.RolePermissionsFieldName] = permissions
+	}
+
+	return uc.audited(ctx, principal, domain.AuditRoleUpdate, 0, func(ctx context.Context) (string, error) {
+		return roleDetails(role.Name, permissions), uc.repo.role.Update(ctx, role.Id, updates)
+	})
+}
+
+// DeleteRole deletes a role, unassigning it from its users.
+func (uc UserUseCase) DeleteRole(
+	ctx context.Context,
+	principal Principal,
+	roleId domain.EntityId,
+) error {
+	if err := principal.Authorize(domain.PermissionManageRoles); err != nil {
+		return err
+	}
+
+	role, err := uc.repo.role.Get(ctx, roleId)
+	if err != nil {
+		return err
+	}
+
+	if role.Builtin() {
+		return domain.ErrBuiltinRole
+	}
+
+	return uc.audited(ctx, principal, domain.AuditRoleDelete, 0, func(ctx context.Context) (string, error) {
+		return role.Name, uc.repo.role.Delete(ctx, role.Id)
+	})
+}
+
+// ListUserRoles returns the roles assigned to the user.
+func (uc UserUseCase) ListUserRoles(
+	ctx context.Context,
+	principal Principal,
+	userId domain.EntityId,
+) ([]domain.Role, error) {
+	if err := principal.Authorize(domain.PermissionReadRoles); err != nil {
+		return nil, err
+	}
+
+	if _, err := uc.repo.user.Get(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	return uc.repo.role.ListByUserId(ctx, userId)
+}
+
+func (uc UserUseCase) AssignRole(
+	ctx context.Context,
+	principal Principal,
+	userId, roleId domain.EntityId,
+) error {
+	if err := principal.Authorize(domain.PermissionManageRoles); err != nil {
+		return err
+	}
+
+	if _, err := uc.repo.user.Get(ctx, userId); err != nil {
+		return err
+	}
+
+	role, err := uc.repo.role.Get(ctx, roleId)
+	if err != nil {
+		return err
+	}
+
+	return uc.audited(ctx, principal, domain.AuditRoleAssign, userId, func(ctx context.Context) (string, error) {
+		return role.Name, uc.repo.role.Assign(ctx, userId, role.Id, time.Now())
+	})
+}
+
+// UnassignRole takes a role from the user. The admin role is kept by at least
+// one user, so the roles can always be managed.
+func (uc UserUseCase) UnassignRole(
+	ctx context.Context,
+	principal Principal,
+	userId, roleId domain.EntityId,
+) error {
+	if err := principal.Authorize(domain.PermissionManageRoles); err != nil {
+		return err
+	}
+
+	role, err := uc.repo.role.Get(ctx, roleId)
+	if err != nil {
+		return err
+	}
+
+	if role.Builtin() {
+		roles, err := uc.repo.role.ListByUserId(ctx, userId)
+		if err != nil {
+			return err
+		}
+
+		admins, err := uc.repo.role.CountUsers(ctx, role.Id)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range roles {
+			if r.Id == role.Id && admins <= 1 {
+				return domain.ErrLastAdmin
+			}
+		}
+	}
+
+	return uc.audited(ctx, principal, domain.AuditRoleUnassign, userId, func(ctx context.Context) (string, error) {
+		return role.Name, uc.repo.role.Unassign(ctx, userId, role.Id)
+	})
+}
+
+// BootstrapAdmin makes sure the admin role exists with every permission, and
+// while no user has it, assigns it to the user of the email once they signed
+// up and verified their email. It returns domain.ErrUserNotFound until then.
+func (uc UserUseCase) BootstrapAdmin(ctx context.Context, email string) error {
+	role, err := uc.repo.role.GetByName(ctx, domain.AdminRoleName)
+	if errors.Is(err, domain.ErrRoleNotFound) {
+		role = domain.Role{
+			CreateTime:  time.Now(),
+			Name:        domain.AdminRoleName,
+			Description: "Every permission",
+			Permissions: domain.AllPermissions,
+		}
+		role.Id, err = uc.repo.role.Create(ctx, role)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Permissions added since the role was created are granted to it.
+	if !domain.HasPermissions(role.Permissions, domain.AllPermissions...) {
+		err = uc.repo.role.Update(ctx, role.Id, domain.EntityUpdate{
+			domain.RolePermissionsFieldName: domain.AllPermissions,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	admins, err := uc.repo.role.CountUsers(ctx, role.Id)
+	if err != nil || admins > 0 || email == "" {
+		return err
+	}
+
+	validEmail, err := domain.ValidateEmail(email)
+	if err != nil {
+		return err
+	}
+
+	// Anyone could sign up with the email, only its owner can verify it.
+	u, err := uc.repo.user.GetByEmail(ctx, validEmail)
+	if err != nil {
+		return err
+	}
+	if u.EmailVerifyTime == nil {
+		return domain.ErrUserNotFound
+	}
+
+	return uc.repo.role.Assign(ctx, u.Id, role.Id, time.Now())
+}
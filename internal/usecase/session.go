@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/PanziApp/backend/internal/domain"
+)
+
+type SessionDTO struct {
+	// Id is the family of the session, which stays the same across token refreshes.
+	Id          string
+	SignInTime  time.Time
+	LastUseTime time.Time
+	UserAgent   string
+	IP          string
+	DeviceName  string
+	Current     bool
+}
+
+// ListSessions returns the active sessions of the user. There is a single
+// unused refresh token per active session, which is refreshed on every use.
+func (uc UserUseCase) ListSessions(
+	ctx context.Context,
+	principal Principal,
+) ([]SessionDTO, error) {
+	sessions, err := uc.repo.session.ListByUserId(ctx, principal.User.Id, domain.RefreshToken, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return newSessionDTOs(sessions, principal.Session.Family), nil
+}
+
+// newSessionDTOs describes the refresh token sessions, the one of currentFamily as current.
+func newSessionDTOs(sessions []domain.Session, currentFamily string) []SessionDTO {
+	dtos := make([]SessionDTO, 0, len(sessions))
+	for _, s := range sessions {
+		dtos = append(dtos, SessionDTO{
+			Id:          s.Family,
+			SignInTime:  s.AuthTime,
+			LastUseTime: s.CreateTime,
+			UserAgent:   s.Meta.UserAgent,
+			IP:          s.Meta.IP,
+			DeviceName:  s.Meta.DeviceName,
+			Current:     s.Family != "" && s.Family == currentFamily,
+		})
+	}
+
+	return dtos
+}
+
+func (uc UserUseCase) RevokeSession(
+	ctx context.Context,
+	principal Principal,
+	sessionId string,
+) error {
+	now := time.Now()
+
+	sessions, err := uc.repo.session.ListByUserId(ctx, principal.User.Id, domain.RefreshToken, now)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sessions {
+		if s.Family == sessionId {
+			return uc.repo.session.RevokeFamily(ctx, s.Family, now)
+		}
+	}
+
+	return domain.ErrSessionNotFound
+}
+
+// RevokeOtherSessions signs the user out everywhere except the current session,
+// which is only its own token for general tokens issued before session families.
+func (uc UserUseCase) RevokeOtherSessions(
+	ctx context.Context,
+	principal Principal,
+) error {
+	return uc.repo.session.RevokeByUserId(
+		ctx,
+		principal.User.Id,
+		[]domain.TokenType{domain.GeneralToken, domain.RefreshToken},
+		principal.Session,
+		time.Now(),
+	)
+}
@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PanziApp/backend/internal/domain"
+)
+
+// matchThrottled checks a password with the email from the ip address by
+// running match, while sign in with the email is throttled. match returns
+// domain.ErrInvalidPassword for a wrong password, which is recorded, and the
+// user of the email when it is registered, who is mailed when their account
+// gets locked. The failures of the email stay locked while match runs, so
+// concurrent guesses are checked and recorded one after the other.
+func (uc UserUseCase) matchThrottled(
+	ctx context.Context,
+	email domain.Email,
+	ip string,
+	match func(ctx context.Context) (*domain.User, error),
+) error {
+	var (
+		user      *domain.User
+		matchErr  error
+		lockUntil *time.Time
+	)
+	err := uc.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := uc.checkSignInThrottle(ctx, email, ip)
+		if err != nil {
+			return err
+		}
+
+		user, matchErr = match(ctx)
+		if errors.Is(matchErr, domain.ErrInvalidPassword) {
+			// The failure is kept, the wrong password is returned once committed.
+			lockUntil, err = uc.recordSignInFailure(ctx, email, ip)
+			return err
+		} else if matchErr != nil {
+			return matchErr
+		}
+
+		return uc.clearSignInFailures(ctx, email)
+	})
+	if err != nil {
+		return err
+	}
+
+	if lockUntil != nil && user != nil {
+		err = uc.mailer.Send(
+			ctx,
+			string(user.Email),
+			string(user.Fullname),
+			"Your Account Was Locked",
+			domain.SignInLockedEmailMessage(*lockUntil),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return matchErr
+}
+
+// checkSignInThrottle fails with a domain.RateLimitError while password sign in
+// with the email is backing off or locked, or the ip address is locked. The
+// failures of the email are locked until the transaction of ctx ends.
+func (uc UserUseCase) checkSignInThrottle(ctx context.Context, email domain.Email, ip string) error {
+	now := time.Now()
+
+	if uc.signInLockout.threshold > 0 {
+		f, err := uc.repo.signInFailure.GetForUpdate(ctx, domain.EmailSignInFailuresKey(email), now)
+		if err != nil {
+			return err
+		}
+
+		wait := f.LockedFor(now)
+		if backoff := f.BackoffFor(now); backoff > wait {
+			wait = backoff
+		}
+		if wait > 0 {
+			return domain.RateLimitError{Err: domain.ErrSignInThrottled, RetryAfter: wait}
+		}
+	}
+
+	// Many users may share an address, so it is locked past its threshold but
+	// does not back off. Its failures are not locked, not to queue the sign ins
+	// of every user behind it.
+	if uc.signInLockout.ipThreshold > 0 && ip != "" {
+		f, err := uc.repo.signInFailure.Get(ctx, domain.IPSignInFailuresKey(ip))
+		if err != nil {
+			return err
+		}
+
+		if wait := f.LockedFor(now); wait > 0 {
+			return domain.RateLimitError{Err: domain.ErrSignInThrottled, RetryAfter: wait}
+		}
+	}
+
+	return nil
+}
+
+// recordSignInFailure counts a failed password sign in with the email from the
+// ip address and locks them when they reach their thresholds. It returns when
+// the email is locked until, if it just got locked.
+func (uc UserUseCase) recordSignInFailure(
+	ctx context.Context,
+	email domain.Email,
+	ip string,
+) (*time.Time, error) {
+	now := time.Now()
+	since := now.Add(-uc.signInLockout.duration)
+	lockUntil := now.Add(uc.signInLockout.duration)
+
+	var locked *time.Time
+	if uc.signInLockout.threshold > 0 {
+		key := domain.EmailSignInFailuresKey(email)
+		f, err := uc.repo.signInFailure.Record(ctx, key, now, since)
+		if err != nil {
+			return nil, err
+		}
+
+		if f.Failures >= uc.signInLockout.threshold {
+			err = uc.repo.signInFailure.Lock(ctx, key, lockUntil)
+			if err != nil {
+				return nil, err
+			}
+
+			if f.Failures == uc.signInLockout.threshold {
+				locked = &lockUntil
+			}
+		}
+	}
+
+	if uc.signInLockout.ipThreshold > 0 && ip != "" {
+		key := domain.IPSignInFailuresKey(ip)
+		f, err := uc.repo.signInFailure.Record(ctx, key, now, since)
+		if err != nil {
+			return nil, err
+		}
+
+		if f.Failures >= uc.signInLockout.ipThreshold {
+			err = uc.repo.signInFailure.Lock(ctx, key, lockUntil)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return locked, nil
+}
+
+// clearSignInFailures forgets the failed sign ins with the email, lifting its lock.
+func (uc UserUseCase) clearSignInFailures(ctx context.Context, email domain.Email) error {
+	if uc.signInLockout.threshold == 0 {
+		return nil
+	}
+
+	return uc.repo.signInFailure.Clear(ctx, domain.EmailSignInFailuresKey(email))
+}
@@ -0,0 +1,233 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PanziApp/backend/internal/domain"
+)
+
+type TokensDTO struct {
+	AccessToken            string
+	AccessTokenValidUntil  time.Time
+	RefreshToken           string
+	RefreshTokenValidUntil time.Time
+	// Scope and IdToken are only set for the tokens of OAuth clients.
+	Scope   string
+	IdToken string
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// issueTokens creates a new access and refresh token pair in the family of the
+// grant, which holds the user, auth time, meta and scope of the new sessions.
+// Tokens of an OAuth client only come with a refresh token when the client may
+// use it, tokens a client is issued for itself never do.
+func (uc UserUseCase) issueTokens(
+	ctx context.Context,
+	grant domain.Session,
+	client *domain.OAuthClient,
+) (t TokensDTO, err error) {
+	now := time.Now()
+	absoluteEnd := grant.AuthTime.Add(uc.lifetime.sessionAbsolute)
+
+	if client != nil {
+		grant.ClientId = &client.Id
+		t.Scope = grant.Scope
+	}
+
+	t.AccessTokenValidUntil = earliest(now.Add(uc.lifetime.accessToken), absoluteEnd)
+	access := grant
+	access.Type = domain.GeneralToken
+	access.ValidUntil = &t.AccessTokenValidUntil
+	access, err = uc.createSession(ctx, access)
+	if err != nil {
+		return t, err
+	}
+
+	if client == nil || (grant.UserId != 0 && client.AllowsGrant(domain.RefreshTokenGrant)) {
+		t.RefreshTokenValidUntil = earliest(now.Add(uc.lifetime.refreshTokenIdle), absoluteEnd)
+		refresh := grant
+		refresh.Type = domain.RefreshToken
+		refresh.ValidUntil = &t.RefreshTokenValidUntil
+		refresh, err = uc.createSession(ctx, refresh)
+		if err != nil {
+			return t, err
+		}
+
+		t.RefreshToken = string(refresh.Token)
+	}
+
+	t.AccessToken = string(access.Token)
+
+	if uc.signer != nil {
+		claims := domain.NewAccessTokenClaims(uc.issuer, access, domain.FormatScope(access.Scopes()))
+		if client != nil {
+			claims.ClientId = client.ClientId
+			if access.UserId == 0 {
+				claims.Subject = client.ClientId
+			}
+		} else {
+			claims.Permissions, err = uc.userPermissions(ctx, access.UserId)
+			if err != nil {
+				return t, err
+			}
+		}
+
+		t.AccessToken, err = uc.signer.Sign(claims)
+		if err != nil {
+			return t, domain.InternalError{Err: err}
+		}
+	}
+
+	return t, nil
+}
+
+// startSession creates a new session family for a user who has just signed in.
+// When the user is at the concurrent sessions cap, their oldest sessions are revoked.
+// Accounts pending deletion cannot sign in until the deletion is canceled, and
+// disabled accounts until they are enabled.
+func (uc UserUseCase) startSession(
+	ctx context.Context,
+	user domain.User,
+	meta domain.SessionMeta,
+) (TokensDTO, error) {
+	if user.PendingDeletion() {
+		return TokensDTO{}, domain.ErrAccountPendingDeletion
+	}
+	if user.Disabled() {
+		return TokensDTO{}, domain.ErrAccountDisabled
+	}
+
+	userId := user.Id
+	now := time.Now()
+
+	if uc.maxSessionsPerUser > 0 {
+		sessions, err := uc.repo.session.ListByUserId(ctx, userId, domain.RefreshToken, now)
+		if err != nil {
+			return TokensDTO{}, err
+		}
+
+		for i := 0; i <= len(sessions)-uc.maxSessionsPerUser; i++ {
+			err = uc.repo.session.RevokeFamily(ctx, sessions[i].Family, now)
+			if err != nil {
+				return TokensDTO{}, err
+			}
+		}
+	}
+
+	family, err := domain.RandomFamily()
+	if err != nil {
+		return TokensDTO{}, err
+	}
+
+	return uc.issueTokens(ctx, domain.Session{
+		UserId:   userId,
+		Family:   family,
+		AuthTime: now,
+		Meta:     meta,
+	}, nil)
+}
+
+// RefreshTokens rotates the refresh token of a first-party session.
+func (uc UserUseCase) RefreshTokens(
+	ctx context.Context,
+	refreshToken string,
+	meta domain.SessionMeta,
+) (t TokensDTO, err error) {
+	s, err := uc.useRefreshToken(ctx, refreshToken, nil)
+	if err != nil {
+		return t, err
+	}
+
+	if meta.DeviceName == "" {
+		meta.DeviceName = s.Meta.DeviceName
+	}
+
+	return uc.issueTokens(ctx, domain.Session{
+		UserId:   s.UserId,
+		Family:   s.Family,
+		AuthTime: s.AuthTime,
+		Meta:     meta,
+	}, nil)
+}
+
+// useRefreshToken redeems a refresh token issued to the OAuth client, nil for
+// first-party sessions. Presenting an already rotated refresh token revokes its
+// whole family, as either it or its successor has leaked.
+func (uc UserUseCase) useRefreshToken(
+	ctx context.Context,
+	refreshToken string,
+	clientId *domain.EntityId,
+) (s domain.Session, err error) {
+	validToken, err := domain.ValidateToken(refreshToken, domain.RefreshToken)
+	if err != nil {
+		return s, err
+	}
+
+	s, err = uc.repo.session.GetByToken(ctx, validToken)
+	if err != nil {
+		return s, err
+	}
+
+	// Checked before the token is used, so presenting it to the wrong client does not burn it.
+	if s.Type != domain.RefreshToken || !sameEntityId(s.ClientId, clientId) {
+		return s, domain.ErrInvalidToken
+	}
+
+	now := time.Now()
+	if s.UseTime != nil {
+		return s, uc.revokeReusedFamily(ctx, s, now)
+	}
+
+	if s.ValidUntil != nil && s.ValidUntil.Before(now) {
+		return s, domain.ErrInvalidToken
+	}
+
+	// The user is refused like when starting a session.
+	user, err := uc.repo.user.Get(ctx, s.UserId)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return s, domain.ErrInvalidToken
+	} else if err != nil {
+		return s, err
+	}
+	if user.PendingDeletion() {
+		return s, domain.ErrAccountPendingDeletion
+	}
+	if user.Disabled() {
+		return s, domain.ErrAccountDisabled
+	}
+
+	err = uc.repo.session.Use(ctx, s.Id, now)
+	if errors.Is(err, domain.ErrInvalidToken) {
+		// Lost a race against another use of the same refresh token.
+		return s, uc.revokeReusedFamily(ctx, s, now)
+	} else if err != nil {
+		return s, err
+	}
+
+	return s, nil
+}
+
+func sameEntityId(a, b *domain.EntityId) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+func (uc UserUseCase) revokeReusedFamily(ctx context.Context, s domain.Session, now time.Time) error {
+	err := uc.repo.session.RevokeFamily(ctx, s.Family, now)
+	if err != nil {
+		return err
+	}
+
+	return domain.ErrInvalidToken
+}
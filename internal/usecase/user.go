@@ -1,156 +1,413 @@
 package usecase
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"github.com/PanziApp/backend/internal/domain"
+	"io"
 	"time"
 )
 
 type UserUseCase struct {
 	repo struct {
-		user    UserRepository
-		session SessionRepository
-	}
-	mailer Mailer
+		user          UserRepository
+		session       SessionRepository
+		recoveryCode  RecoveryCodeRepository
+		passkey       PasskeyRepository
+		external      ExternalIdentityRepository
+		oauth         OAuthRepository
+		dataExport    DataExportRepository
+		signInFailure SignInFailureRepository
+		role          RoleRepository
+		audit         AuditRepository
+	}
+	transactor Transactor
+	mailer     Mailer
+	storage    FileStorage
+	signer     TokenSigner
+	issuer     string
+	lifetime   struct {
+		accessToken      time.Duration
+		refreshTokenIdle time.Duration
+		sessionAbsolute  time.Duration
+	}
+	maxSessionsPerUser  int
+	totpIssuer          string
+	relyingParty        RelyingParty
+	externalProviders   map[string]ExternalProvider
+	authorizationPage   string
+	redirectSchemes     []string
+	deletionGracePeriod time.Duration
+	signInLockout       struct {
+		threshold   int
+		ipThreshold int
+		duration    time.Duration
+	}
+	passwordPolicy    domain.PasswordPolicy
+	breachedPasswords BreachedPasswords
+	passwordHasher    domain.PasswordHasher
+	signInCodeKey     []byte
 }
 
 func New(
 	userRepository UserRepository,
 	sessionRepository SessionRepository,
+	recoveryCodeRepository RecoveryCodeRepository,
+	passkeyRepository PasskeyRepository,
+	externalIdentityRepository ExternalIdentityRepository,
+	oauthRepository OAuthRepository,
+	dataExportRepository DataExportRepository,
+	signInFailureRepository SignInFailureRepository,
+	roleRepository RoleRepository,
+	auditRepository AuditRepository,
+	transactor Transactor,
 	mailer Mailer,
+	storage FileStorage,
+	opts ...Option,
 ) UserUseCase {
 	uc := UserUseCase{}
 
 	uc.repo.user = userRepository
 	uc.repo.session = sessionRepository
-
+	uc.repo.recoveryCode = recoveryCodeRepository
+	uc.repo.passkey = passkeyRepository
+	uc.repo.external = externalIdentityRepository
+	uc.repo.oauth = oauthRepository
+	uc.repo.dataExport = dataExportRepository
+	uc.repo.signInFailure = signInFailureRepository
+	uc.repo.role = roleRepository
+	uc.repo.audit = auditRepository
+
+	uc.transactor = transactor
 	uc.mailer = mailer
+	uc.storage = storage
+
+	uc.lifetime.accessToken = _defaultAccessTokenLifetime
+	uc.lifetime.refreshTokenIdle = _defaultRefreshTokenIdleLifetime
+	uc.lifetime.sessionAbsolute = _defaultSessionAbsoluteLifetime
+	uc.totpIssuer = _defaultTotpIssuer
+	uc.deletionGracePeriod = _defaultDeletionGracePeriod
+	uc.signInLockout.threshold = _defaultSignInLockoutThreshold
+	uc.signInLockout.ipThreshold = _defaultSignInLockoutIPThreshold
+	uc.signInLockout.duration = _defaultSignInLockoutDuration
+	uc.passwordPolicy = domain.DefaultPasswordPolicy
+	uc.passwordHasher = domain.DefaultPasswordHasher
+
+	// Custom options
+	for _, opt := range opts {
+		opt(&uc)
+	}
 
 	return uc
 }
 
 func (uc UserUseCase) createSession(
 	ctx context.Context,
-	userId domain.EntityId,
-	tokenType domain.TokenType,
-	validUntil *time.Time,
-) (session domain.Session, err error) {
-	session = domain.Session{
-		CreateTime: time.Now(),
-		UserId:     userId,
-		Type:       tokenType,
-		ValidUntil: validUntil,
+	session domain.Session,
+) (_ domain.Session, err error) {
+	session.CreateTime = time.Now()
+	if session.AuthTime.IsZero() {
+		session.AuthTime = session.CreateTime
 	}
-	session.Token, err = domain.RandomToken()
+	session.Token, err = domain.RandomToken(session.Type)
 	if err != nil {
-		return
+		return session, err
 	}
 	session.Id, err = uc.repo.session.Create(ctx, session)
-	return
+	return session, err
+}
+
+// createSupersedingSession creates a single use session valid for the lifetime,
+// revoking the sessions of the same type previously issued to the user.
+func (uc UserUseCase) createSupersedingSession(
+	ctx context.Context,
+	session domain.Session,
+	lifetime time.Duration,
+) (domain.Session, error) {
+	now := time.Now()
+
+	err := uc.repo.session.RevokeByUserId(ctx, session.UserId, []domain.TokenType{session.Type}, domain.Session{}, now)
+	if err != nil {
+		return session, err
+	}
+
+	validUntil := now.Add(lifetime)
+	session.ValidUntil = &validUntil
+
+	return uc.createSession(ctx, session)
+}
+
+// Principal is the authenticated user along with the session they are authenticated by.
+type Principal struct {
+	Session domain.Session
+	User    domain.User
+	// Permissions are granted by the roles of the user, tokens of OAuth
+	// clients act for the user without them.
+	Permissions []domain.Permission
 }
 
-func (uc UserUseCase) getGeneralValidSession(
+// Authorize checks the principal has every one of the permissions.
+func (p Principal) Authorize(permissions ...domain.Permission) error {
+	if !domain.HasPermissions(p.Permissions, permissions...) {
+		return domain.ErrPermissionDenied
+	}
+
+	return nil
+}
+
+// Authenticate resolves the principal of a general token or a signed access
+// token. Tokens of OAuth clients need the default scope to access the user API.
+func (uc UserUseCase) Authenticate(
+	ctx context.Context,
+	token string,
+) (p Principal, err error) {
+	s, err := uc.accessTokenSession(ctx, token)
+	if err != nil {
+		return p, err
+	}
+
+	if !domain.ScopeContains(s.Scopes(), []string{domain.DefaultAccessTokenScope}) {
+		return p, domain.ErrInvalidToken
+	}
+
+	u, err := uc.repo.user.Get(ctx, s.UserId)
+	if errors.Is(err, domain.ErrUserNotFound) || (err == nil && u.Disabled()) {
+		return p, domain.ErrInvalidToken
+	} else if err != nil {
+		return p, err
+	}
+
+	p = Principal{Session: s, User: u, Permissions: []domain.Permission{}}
+	if s.ClientId == nil {
+		p.Permissions, err = uc.userPermissions(ctx, u.Id)
+		if err != nil {
+			return p, err
+		}
+	}
+
+	return p, nil
+}
+
+// userPermissions returns the permissions granted by the roles of the user.
+func (uc UserUseCase) userPermissions(ctx context.Context, userId domain.EntityId) ([]domain.Permission, error) {
+	roles, err := uc.repo.role.ListByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.RolesPermissions(roles), nil
+}
+
+// accessTokenSession returns the still valid session of a general token or a signed access token.
+func (uc UserUseCase) accessTokenSession(
 	ctx context.Context,
 	token string,
 ) (s domain.Session, err error) {
-	validToken, err := domain.ValidateToken(token)
+	s, err = uc.tokenSession(ctx, token)
 	if err != nil {
 		return s, err
 	}
 
-	s, err = uc.repo.session.GetByToken(ctx, validToken)
+	if s.Type != domain.GeneralToken || (s.ValidUntil != nil && s.ValidUntil.Before(time.Now())) {
+		return s, domain.ErrInvalidToken
+	}
+
+	return s, nil
+}
+
+// tokenSession returns the session of an opaque token, or the session a signed
+// access token is backed by, whether or not it is still valid.
+func (uc UserUseCase) tokenSession(
+	ctx context.Context,
+	token string,
+) (s domain.Session, err error) {
+	if uc.signer != nil && domain.IsSignedToken(token) {
+		return uc.signedTokenSession(ctx, token)
+	}
+
+	validToken, err := domain.ValidateToken(token, domain.GeneralToken, domain.RefreshToken)
 	if err != nil {
 		return s, err
 	}
 
-	if s.Type != domain.GeneralToken || (s.ValidUntil != nil && s.ValidUntil.Before(time.Now())) {
+	return uc.repo.session.GetByToken(ctx, validToken)
+}
+
+func (uc UserUseCase) signedTokenSession(
+	ctx context.Context,
+	token string,
+) (s domain.Session, err error) {
+	var claims domain.AccessTokenClaims
+	if err = uc.signer.Verify(token, &claims); err != nil {
+		return s, domain.ErrInvalidToken
+	}
+
+	if err = claims.Validate(uc.issuer, time.Now()); err != nil {
+		return s, err
+	}
+
+	// The backing session is still checked, so signing out revokes signed tokens too.
+	s, err = uc.repo.session.Get(ctx, claims.SessionId)
+	if errors.Is(err, domain.ErrSessionNotFound) {
+		return s, domain.ErrInvalidToken
+	} else if err != nil {
+		return s, err
+	}
+
+	if s.Type != domain.GeneralToken {
 		return s, domain.ErrInvalidToken
 	}
 
+	// Tokens a client is issued for itself have the client as their subject.
+	if s.UserId != 0 {
+		userId, err := claims.UserId()
+		if err != nil || userId != s.UserId {
+			return s, domain.ErrInvalidToken
+		}
+	}
+
 	return s, nil
 }
 
+func (uc UserUseCase) sendEmailVerification(
+	ctx context.Context,
+	user domain.User,
+) error {
+	session, err := uc.createSupersedingSession(ctx, domain.Session{
+		UserId: user.Id,
+		Type:   domain.EmailVerificationToken,
+	}, domain.EmailVerificationTokenLifetime)
+	if err != nil {
+		return err
+	}
+
+	name := string(user.Fullname)
+	if name == "" {
+		name = "User"
+	}
+
+	return uc.mailer.Send(
+		ctx,
+		string(user.Email),
+		name,
+		"Email Verification",
+		domain.EmailVerificationMessage(string(session.Token)),
+	)
+}
+
 func (uc UserUseCase) SignUp(
 	ctx context.Context,
 	email, password string,
-) (token string, err error) {
+	meta domain.SessionMeta,
+) (t TokensDTO, err error) {
 	validEmail, err := domain.ValidateEmail(email)
 	if err != nil {
-		return "", err
+		return t, err
 	}
 
 	validPassword, err := domain.ValidatePassword(password)
 	if err != nil {
-		return "", err
+		return t, err
+	}
+
+	err = uc.checkNewPassword(ctx, validPassword, validEmail, "")
+	if err != nil {
+		return t, err
 	}
 
 	user := domain.User{
 		CreateTime: time.Now(),
 		Email:      validEmail,
 	}
-	user.HashedPassword, err = domain.HashPassword(validPassword)
+	user.HashedPassword, err = uc.passwordHasher.Hash(validPassword)
 	if err != nil {
-		return "", err
+		return t, err
 	}
 	user.Id, err = uc.repo.user.Create(ctx, user)
 	if err != nil {
-		return "", err
-	}
-
-	session, err := uc.createSession(ctx, user.Id, domain.EmailVerificationToken, nil)
-	if err != nil {
-		return "", err
+		return t, err
 	}
 
-	err = uc.mailer.Send(
-		ctx,
-		string(user.Email),
-		"User",
-		"Email Verification",
-		domain.EmailVerificationMessage(string(session.Token)),
-	)
+	err = uc.sendEmailVerification(ctx, user)
 	if err != nil {
-		return "", err
+		return t, err
 	}
 
-	session, err = uc.createSession(ctx, user.Id, domain.GeneralToken, nil)
-	if err != nil {
-		return "", err
-	}
+	return uc.startSession(ctx, user, meta)
+}
 
-	return string(session.Token), nil
+// SignInDTO holds either the tokens of the new session, or when two-factor
+// authentication is enabled, the token of the challenge to complete with VerifyMfa.
+type SignInDTO struct {
+	Tokens   TokensDTO
+	MfaToken string
 }
 
 func (uc UserUseCase) SignIn(
 	ctx context.Context,
 	email, password string,
-) (token string, err error) {
+	meta domain.SessionMeta,
+) (r SignInDTO, err error) {
 	validEmail, err := domain.ValidateEmail(email)
 	if err != nil {
-		return "", err
+		return r, err
 	}
 
 	validPassword, err := domain.ValidatePassword(password)
 	if err != nil {
-		return "", err
+		return r, err
 	}
 
-	user, err := uc.repo.user.GetByEmail(ctx, validEmail)
+	var user domain.User
+	err = uc.matchThrottled(ctx, validEmail, meta.IP, func(ctx context.Context) (*domain.User, error) {
+		u, err := uc.repo.user.GetByEmail(ctx, validEmail)
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, domain.ErrInvalidPassword
+		} else if err != nil {
+			return nil, err
+		}
+		user = u
+
+		return &user, uc.passwordHasher.Match(user.HashedPassword, validPassword)
+	})
 	if err != nil {
-		return "", err
+		return r, err
 	}
 
-	err = user.HashedPassword.Match(validPassword)
-	if err != nil {
-		return "", err
+	// Hashes of older algorithms or parameters, and unpeppered hashes once a
+	// pepper is set, are upgraded while the password is at hand.
+	if uc.passwordHasher.NeedsRehash(user.HashedPassword) {
+		user.HashedPassword, err = uc.passwordHasher.Hash(validPassword)
+		if err != nil {
+			return r, err
+		}
+
+		err = uc.repo.user.Update(ctx, user.Id, domain.EntityUpdate{
+			domain.UserHashedPasswordFieldName: user.HashedPassword,
+		})
+		if err != nil {
+			return r, err
+		}
 	}
 
-	session, err := uc.createSession(ctx, user.Id, domain.GeneralToken, nil)
-	if err != nil {
-		return "", err
+	return uc.completeSignIn(ctx, user, meta)
+}
+
+// completeSignIn starts a session for the user who proved their first factor,
+// or a two-factor authentication challenge when it is enabled.
+func (uc UserUseCase) completeSignIn(
+	ctx context.Context,
+	user domain.User,
+	meta domain.SessionMeta,
+) (r SignInDTO, err error) {
+	if user.MfaEnabled() {
+		r.MfaToken, err = uc.createMfaChallenge(ctx, user.Id, meta)
+		return r, err
 	}
 
-	return string(session.Token), nil
+	r.Tokens, err = uc.startSession(ctx, user, meta)
+	return r, err
 }
 
 func (uc UserUseCase) SendResetPasswordLink(
@@ -163,28 +420,36 @@ func (uc UserUseCase) SendResetPasswordLink(
 	}
 
 	user, err := uc.repo.user.GetByEmail(ctx, validEmail)
-	if err != nil {
+	if errors.Is(err, domain.ErrUserNotFound) {
+		// Do not reveal which emails are registered.
+		return nil
+	} else if err != nil {
 		return err
 	}
 
-	anHour := time.Now().Add(time.Hour)
-	session, err := uc.createSession(ctx, user.Id, domain.ResetPasswordToken, &anHour)
+	return uc.sendResetPasswordLink(ctx, user)
+}
+
+func (uc UserUseCase) sendResetPasswordLink(ctx context.Context, user domain.User) error {
+	session, err := uc.createSupersedingSession(ctx, domain.Session{
+		UserId: user.Id,
+		Type:   domain.ResetPasswordToken,
+	}, domain.ResetPasswordTokenLifetime)
 	if err != nil {
 		return err
 	}
 
-	err = uc.mailer.Send(
+	return uc.mailResetPasswordLink(ctx, user, session.Token)
+}
+
+func (uc UserUseCase) mailResetPasswordLink(ctx context.Context, user domain.User, token domain.Token) error {
+	return uc.mailer.Send(
 		ctx,
 		string(user.Email),
 		string(user.Fullname),
 		"Reset Password",
-		domain.ResetPasswordEmailMessage(string(session.Token)),
+		domain.ResetPasswordEmailMessage(string(token)),
 	)
-	if err != nil {
-		return err
-	}
-
-	return nil
 }
 
 func (uc UserUseCase) ResetPassword(
@@ -196,26 +461,27 @@ func (uc UserUseCase) ResetPassword(
 		return err
 	}
 
-	validToken, err := domain.ValidateToken(token)
+	validToken, err := domain.ValidateToken(token, domain.ResetPasswordToken)
 	if err != nil {
 		return err
 	}
 
-	s, err := uc.repo.session.GetByToken(ctx, validToken)
+	s, err := uc.repo.session.Redeem(ctx, validToken, domain.ResetPasswordToken, time.Now())
 	if err != nil {
 		return err
 	}
 
-	if s.Type != domain.ResetPasswordToken || (s.ValidUntil != nil && s.ValidUntil.Before(time.Now())) {
-		return domain.ErrInvalidToken
+	u, err := uc.repo.user.Get(ctx, s.UserId)
+	if err != nil {
+		return err
 	}
 
-	u, err := uc.repo.user.Get(ctx, s.UserId)
+	err = uc.checkNewPassword(ctx, validPassword, u.Email, u.Fullname)
 	if err != nil {
 		return err
 	}
 
-	u.HashedPassword, err = domain.HashPassword(validPassword)
+	u.HashedPassword, err = uc.passwordHasher.Hash(validPassword)
 	if err != nil {
 		return err
 	}
@@ -229,56 +495,145 @@ func (uc UserUseCase) ResetPassword(
 		return err
 	}
 
-	return nil
+	err = uc.clearSignInFailures(ctx, u.Email)
+	if err != nil {
+		return err
+	}
+
+	return uc.afterPasswordChange(ctx, u, domain.Session{})
 }
 
-func (uc UserUseCase) SignOut(
+func (uc UserUseCase) VerifyEmail(
 	ctx context.Context,
 	token string,
 ) error {
-	s, err := uc.getGeneralValidSession(ctx, token)
+	validToken, err := domain.ValidateToken(token, domain.EmailVerificationToken)
+	if err != nil {
+		return err
+	}
+
+	s, err := uc.repo.session.Redeem(ctx, validToken, domain.EmailVerificationToken, time.Now())
 	if err != nil {
 		return err
 	}
 
-	err = uc.repo.session.Update(ctx, s.Id, domain.EntityUpdate{domain.SessionValidUntilFieldName: time.Now()})
+	u, err := uc.repo.user.Get(ctx, s.UserId)
 	if err != nil {
 		return err
 	}
 
+	if u.EmailVerifyTime == nil {
+		err = uc.repo.user.Update(ctx, u.Id, domain.EntityUpdate{domain.UserEmailVerifyTimeFieldName: time.Now()})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (uc UserUseCase) ChangePassword(
+func (uc UserUseCase) ResendEmailVerification(
 	ctx context.Context,
-	token string,
-	oldPassword, newPassword string,
+	principal Principal,
+) error {
+	u := principal.User
+	if u.EmailVerifyTime != nil {
+		return domain.ErrEmailAlreadyVerified
+	}
+
+	last, err := uc.repo.session.GetLastByUserId(ctx, u.Id, domain.EmailVerificationToken)
+	if err != nil && !errors.Is(err, domain.ErrSessionNotFound) {
+		return err
+	}
+	if err == nil {
+		if wait := time.Until(last.CreateTime.Add(domain.EmailVerificationResendDelay)); wait > 0 {
+			return domain.RateLimitError{Err: domain.ErrVerificationEmailTooSoon, RetryAfter: wait}
+		}
+	}
+
+	return uc.sendEmailVerification(ctx, u)
+}
+
+func (uc UserUseCase) SignOut(
+	ctx context.Context,
+	principal Principal,
 ) error {
-	s, err := uc.getGeneralValidSession(ctx, token)
+	if principal.Session.Family != "" {
+		return uc.repo.session.RevokeFamily(ctx, principal.Session.Family, time.Now())
+	}
+
+	err := uc.repo.session.Update(ctx, principal.Session.Id, domain.EntityUpdate{
+		domain.SessionValidUntilFieldName: time.Now(),
+	})
 	if err != nil {
 		return err
 	}
 
-	validOldPassword, err := domain.ValidatePassword(oldPassword)
+	return nil
+}
+
+// afterPasswordChange revokes the sessions and pending reset password links of
+// the user, except the session to keep, and notifies the user by email.
+func (uc UserUseCase) afterPasswordChange(
+	ctx context.Context,
+	u domain.User,
+	keep domain.Session,
+) error {
+	err := uc.repo.session.RevokeByUserId(
+		ctx,
+		u.Id,
+		[]domain.TokenType{domain.GeneralToken, domain.RefreshToken},
+		keep,
+		time.Now(),
+	)
 	if err != nil {
 		return err
 	}
 
-	validNewPassword, err := domain.ValidatePassword(newPassword)
+	err = uc.repo.session.RevokeByUserId(
+		ctx,
+		u.Id,
+		[]domain.TokenType{domain.ResetPasswordToken, domain.SignInLinkToken, domain.SignInCodeToken},
+		domain.Session{},
+		time.Now(),
+	)
 	if err != nil {
 		return err
 	}
 
-	u, err := uc.repo.user.Get(ctx, s.UserId)
+	return uc.mailer.Send(
+		ctx,
+		string(u.Email),
+		string(u.Fullname),
+		"Your Password Was Changed",
+		domain.PasswordChangedEmailMessage(),
+	)
+}
+
+// ChangePassword sets a new password and signs out every other session,
+// and the current one too unless keepCurrentSession.
+func (uc UserUseCase) ChangePassword(
+	ctx context.Context,
+	principal Principal,
+	oldPassword, newPassword string,
+	keepCurrentSession bool,
+) error {
+	validNewPassword, err := domain.ValidatePassword(newPassword)
 	if err != nil {
 		return err
 	}
 
-	if err = u.HashedPassword.Match(validOldPassword); err != nil {
-		return domain.ErrInvalidPassword
+	u := principal.User
+	if err = uc.matchPassword(ctx, u, oldPassword); err != nil {
+		return err
+	}
+
+	err = uc.checkNewPassword(ctx, validNewPassword, u.Email, u.Fullname)
+	if err != nil {
+		return err
 	}
 
-	u.HashedPassword, err = domain.HashPassword(validNewPassword)
+	u.HashedPassword, err = uc.passwordHasher.Hash(validNewPassword)
 	if err != nil {
 		return err
 	}
@@ -289,35 +644,40 @@ func (uc UserUseCase) ChangePassword(
 		return err
 	}
 
-	return nil
+	keep := domain.Session{}
+	if keepCurrentSession {
+		keep = principal.Session
+	}
+
+	return uc.afterPasswordChange(ctx, u, keep)
 }
 
 type ProfileDTO struct {
 	Email           domain.Email
 	EmailIsVerified bool
-	Fullname        domain.Fullname
-	Avatar          string
+	// PendingEmail is the address of an email change waiting for confirmation.
+	PendingEmail domain.Email
+	Fullname     domain.Fullname
+	Avatar       string
+	MfaEnabled   bool
+	// Permissions are granted to the user by their roles.
+	Permissions []domain.Permission
 }
 
 func (uc UserUseCase) GetProfile(
 	ctx context.Context,
-	token string,
+	principal Principal,
 ) (p ProfileDTO, err error) {
-	s, err := uc.getGeneralValidSession(ctx, token)
-	if err != nil {
-		return p, err
-	}
-
-	u, err := uc.repo.user.Get(ctx, s.UserId)
-	if err != nil {
-		return p, err
-	}
+	u := principal.User
 
 	return ProfileDTO{
 		Email:           u.Email,
 		EmailIsVerified: u.EmailVerifyTime != nil,
+		PendingEmail:    u.PendingEmail,
 		Fullname:        u.Fullname,
 		Avatar:          u.Avatar,
+		MfaEnabled:      u.MfaEnabled(),
+		Permissions:     principal.Permissions,
 	}, nil
 }
 
@@ -328,19 +688,9 @@ type ProfileUpdateDTO struct {
 
 func (uc UserUseCase) UpdateProfile(
 	ctx context.Context,
-	token string,
+	principal Principal,
 	profileUpdate ProfileUpdateDTO,
 ) error {
-	s, err := uc.getGeneralValidSession(ctx, token)
-	if err != nil {
-		return err
-	}
-
-	u, err := uc.repo.user.Get(ctx, s.UserId)
-	if err != nil {
-		return err
-	}
-
 	updates := domain.EntityUpdate{}
 	{
 		if profileUpdate.Fullname != nil {
@@ -356,7 +706,7 @@ func (uc UserUseCase) UpdateProfile(
 			updates[domain.UserAvatarFieldName] = *profileUpdate.Avatar
 		}
 	}
-	err = uc.repo.user.Update(ctx, u.Id, updates)
+	err := uc.repo.user.Update(ctx, principal.User.Id, updates)
 	if err != nil {
 		return err
 	}
@@ -364,5 +714,55 @@ func (uc UserUseCase) UpdateProfile(
 	return nil
 }
 
-// Upload
-// Download
+func (uc UserUseCase) UploadAvatar(
+	ctx context.Context,
+	principal Principal,
+	content []byte,
+) (avatar string, err error) {
+	if err = domain.ValidateAvatar(content); err != nil {
+		return "", err
+	}
+
+	u := principal.User
+
+	filename, err := domain.RandomFilename()
+	if err != nil {
+		return "", err
+	}
+
+	err = uc.storage.Save(ctx, string(filename), bytes.NewReader(content))
+	if err != nil {
+		return "", domain.ServiceError{Name: "storage", Err: err}
+	}
+
+	err = uc.repo.user.Update(ctx, u.Id, domain.EntityUpdate{domain.UserAvatarFieldName: string(filename)})
+	if err != nil {
+		return "", err
+	}
+
+	if u.Avatar != "" {
+		err = uc.storage.Remove(ctx, u.Avatar)
+		if err != nil {
+			return "", domain.ServiceError{Name: "storage", Err: err}
+		}
+	}
+
+	return string(filename), nil
+}
+
+func (uc UserUseCase) GetAvatar(
+	ctx context.Context,
+	principal Principal,
+) (io.ReadCloser, error) {
+	u := principal.User
+	if u.Avatar == "" {
+		return nil, domain.ErrAvatarNotFound
+	}
+
+	content, err := uc.storage.Open(ctx, u.Avatar)
+	if err != nil {
+		return nil, domain.ServiceError{Name: "storage", Err: err}
+	}
+
+	return content, nil
+}
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS users(
+    id bigserial PRIMARY KEY,
+    create_time timestamptz NOT NULL,
+    email VARCHAR(100) NOT NULL UNIQUE,
+    email_verify_time timestamptz,
+    hashed_password bytea NOT NULL,
+    fullname VARCHAR(100) NOT NULL DEFAULT '',
+    avatar VARCHAR(100) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS sessions(
+    id bigserial PRIMARY KEY,
+    create_time timestamptz NOT NULL,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(32) NOT NULL,
+    token VARCHAR(100) NOT NULL UNIQUE,
+    valid_until timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions(user_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS sessions_family_idx;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS use_time;
+ALTER TABLE sessions DROP COLUMN IF EXISTS auth_time;
+ALTER TABLE sessions DROP COLUMN IF EXISTS family;
@@ -0,0 +1,11 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS family VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS auth_time timestamptz;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS use_time timestamptz;
+
+UPDATE sessions SET auth_time = create_time WHERE auth_time IS NULL;
+ALTER TABLE sessions ALTER COLUMN auth_time SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS sessions_family_idx ON sessions(family) WHERE family <> '';
+
+-- General tokens used to never expire, bound them by the default absolute session lifetime.
+UPDATE sessions SET valid_until = create_time + INTERVAL '90 days' WHERE type = 'general' AND valid_until IS NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS sessions_user_id_type_idx;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS device_name;
+ALTER TABLE sessions DROP COLUMN IF EXISTS ip;
+ALTER TABLE sessions DROP COLUMN IF EXISTS user_agent;
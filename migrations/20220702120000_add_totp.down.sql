@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS recovery_codes;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS attempts;
+
+ALTER TABLE users DROP COLUMN IF EXISTS totp_last_step;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_enable_time;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_secret;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS passkeys(
+    id bigserial PRIMARY KEY,
+    create_time timestamptz NOT NULL,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    credential_id bytea NOT NULL UNIQUE,
+    public_key bytea NOT NULL,
+    sign_count bigint NOT NULL DEFAULT 0,
+    transports text[] NOT NULL DEFAULT '{}',
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    last_use_time timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS passkeys_user_id_idx ON passkeys(user_id);
+
+CREATE TABLE IF NOT EXISTS passkey_challenges(
+    id bigserial PRIMARY KEY,
+    create_time timestamptz NOT NULL,
+    user_id bigint REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(32) NOT NULL,
+    challenge VARCHAR(100) NOT NULL UNIQUE,
+    valid_until timestamptz NOT NULL,
+    use_time timestamptz
+);
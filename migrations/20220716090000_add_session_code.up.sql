@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS code VARCHAR(6) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS external_sign_ins;
+DROP TABLE IF EXISTS external_identities;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS external_identities(
+    id bigserial PRIMARY KEY,
+    create_time timestamptz NOT NULL,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(32) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    confirm_time timestamptz,
+    confirm_token VARCHAR(100) UNIQUE,
+    confirm_valid_until timestamptz,
+    last_use_time timestamptz,
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS external_identities_user_id_idx ON external_identities(user_id);
+
+CREATE TABLE IF NOT EXISTS external_sign_ins(
+    id bigserial PRIMARY KEY,
+    create_time timestamptz NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    state VARCHAR(100) NOT NULL UNIQUE,
+    nonce VARCHAR(100) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    user_id bigint REFERENCES users(id) ON DELETE CASCADE,
+    valid_until timestamptz NOT NULL,
+    use_time timestamptz
+);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS sessions_client_id_idx;
+
+DELETE FROM sessions WHERE user_id IS NULL;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS scope;
+ALTER TABLE sessions DROP COLUMN IF EXISTS client_id;
+ALTER TABLE sessions ALTER COLUMN user_id SET NOT NULL;
+
+DROP TABLE IF EXISTS oauth_authorization_codes;
+DROP TABLE IF EXISTS oauth_consents;
+DROP TABLE IF EXISTS oauth_clients;
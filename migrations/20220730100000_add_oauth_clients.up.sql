@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS oauth_clients(
+    id bigserial PRIMARY KEY,
+    create_time timestamptz NOT NULL,
+    owner_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    client_id VARCHAR(64) NOT NULL UNIQUE,
+    hashed_secret bytea,
+    name VARCHAR(100) NOT NULL,
+    redirect_uris TEXT[] NOT NULL DEFAULT '{}',
+    grant_types TEXT[] NOT NULL,
+    scopes TEXT[] NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS oauth_clients_owner_id_idx ON oauth_clients(owner_id);
+
+CREATE TABLE IF NOT EXISTS oauth_consents(
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    client_id bigint NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    scopes TEXT[] NOT NULL,
+    update_time timestamptz NOT NULL,
+    PRIMARY KEY (user_id, client_id)
+);
+
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes(
+    id bigserial PRIMARY KEY,
+    create_time timestamptz NOT NULL,
+    code VARCHAR(100) NOT NULL UNIQUE,
+    client_id bigint NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    redirect_uri TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    nonce VARCHAR(255) NOT NULL DEFAULT '',
+    code_challenge VARCHAR(128) NOT NULL DEFAULT '',
+    family VARCHAR(32) NOT NULL,
+    auth_time timestamptz NOT NULL,
+    valid_until timestamptz NOT NULL,
+    use_time timestamptz
+);
+
+-- Tokens a client is issued for itself with client credentials have no user.
+ALTER TABLE sessions ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS client_id bigint REFERENCES oauth_clients(id) ON DELETE CASCADE;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS scope VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS sessions_client_id_idx ON sessions(client_id);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pending_email VARCHAR(100) NOT NULL DEFAULT '';
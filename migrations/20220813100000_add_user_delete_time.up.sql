@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS delete_time timestamptz;
+
+CREATE INDEX IF NOT EXISTS users_delete_time_idx ON users(delete_time) WHERE delete_time IS NOT NULL;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS data_exports(
+    id bigserial PRIMARY KEY,
+    create_time timestamptz NOT NULL,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    complete_time timestamptz,
+    filename VARCHAR(64) NOT NULL DEFAULT '',
+    token VARCHAR(100) UNIQUE,
+    valid_until timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS data_exports_user_id_idx ON data_exports(user_id);
+CREATE INDEX IF NOT EXISTS data_exports_pending_idx ON data_exports(create_time) WHERE complete_time IS NULL;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS sign_in_failures(
+    key VARCHAR(128) PRIMARY KEY,
+    failures int NOT NULL,
+    last_failure_time timestamptz NOT NULL,
+    lock_until timestamptz
+);
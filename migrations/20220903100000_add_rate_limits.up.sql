@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS rate_limits(
+    key VARCHAR(255) PRIMARY KEY,
+    value double precision NOT NULL,
+    previous double precision NOT NULL,
+    time timestamptz NOT NULL,
+    expire_time timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS rate_limits_expire_time_idx ON rate_limits(expire_time);
@@ -0,0 +1,11 @@
+-- Tokens cannot be recovered from their hashes, so the sessions are revoked.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS token VARCHAR(100);
+UPDATE sessions SET token = encode(token_hash, 'hex') WHERE token IS NULL;
+UPDATE sessions SET valid_until = now() WHERE valid_until IS NULL OR valid_until > now();
+ALTER TABLE sessions ALTER COLUMN token SET NOT NULL;
+ALTER TABLE sessions ADD CONSTRAINT sessions_token_key UNIQUE (token);
+DROP INDEX IF EXISTS sessions_token_hash_idx;
+ALTER TABLE sessions DROP COLUMN IF EXISTS token_hash;
+
+UPDATE sessions SET code = '' WHERE code <> '';
+ALTER TABLE sessions ALTER COLUMN code TYPE VARCHAR(6);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS audit_log;
+DROP INDEX IF EXISTS users_create_time_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS disable_time;
@@ -0,0 +1,15 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disable_time timestamptz;
+
+CREATE INDEX IF NOT EXISTS users_create_time_idx ON users(create_time);
+
+CREATE TABLE IF NOT EXISTS audit_log(
+    id bigserial PRIMARY KEY,
+    create_time timestamptz NOT NULL,
+    actor_id bigint REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(64) NOT NULL,
+    -- Entries outlive the users they were taken on, so the id is kept as is.
+    target_user_id bigint,
+    details TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_target_user_id_idx ON audit_log(target_user_id) WHERE target_user_id IS NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE data_exports ADD COLUMN IF NOT EXISTS attempts integer NOT NULL DEFAULT 0;
+ALTER TABLE data_exports ADD COLUMN IF NOT EXISTS retry_time timestamptz;
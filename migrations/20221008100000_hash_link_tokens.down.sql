@@ -0,0 +1,20 @@
+-- Tokens cannot be recovered from their hashes, so the pending ones expire.
+ALTER TABLE data_exports ADD COLUMN IF NOT EXISTS token VARCHAR(100) UNIQUE;
+UPDATE data_exports SET token = encode(token_hash, 'hex') WHERE token_hash IS NOT NULL;
+UPDATE data_exports SET valid_until = now() WHERE valid_until > now();
+DROP INDEX IF EXISTS data_exports_token_hash_idx;
+ALTER TABLE data_exports DROP COLUMN IF EXISTS token_hash;
+
+ALTER TABLE oauth_authorization_codes ADD COLUMN IF NOT EXISTS code VARCHAR(100) UNIQUE;
+UPDATE oauth_authorization_codes SET code = encode(code_hash, 'hex') WHERE code IS NULL;
+UPDATE oauth_authorization_codes SET valid_until = now() WHERE valid_until > now();
+ALTER TABLE oauth_authorization_codes ALTER COLUMN code SET NOT NULL;
+DROP INDEX IF EXISTS oauth_authorization_codes_code_hash_idx;
+ALTER TABLE oauth_authorization_codes DROP COLUMN IF EXISTS code_hash;
+
+ALTER TABLE external_identities ADD COLUMN IF NOT EXISTS confirm_token VARCHAR(100) UNIQUE;
+UPDATE external_identities SET confirm_token = encode(confirm_token_hash, 'hex')
+    WHERE confirm_token_hash IS NOT NULL;
+UPDATE external_identities SET confirm_valid_until = now() WHERE confirm_valid_until > now();
+DROP INDEX IF EXISTS external_identities_confirm_token_hash_idx;
+ALTER TABLE external_identities DROP COLUMN IF EXISTS confirm_token_hash;
@@ -0,0 +1,17 @@
+-- The links and codes in flight are hashed in place so they keep working.
+ALTER TABLE external_identities ADD COLUMN IF NOT EXISTS confirm_token_hash bytea;
+UPDATE external_identities SET confirm_token_hash = sha256(convert_to(confirm_token, 'UTF8'))
+    WHERE confirm_token IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS external_identities_confirm_token_hash_idx ON external_identities(confirm_token_hash);
+ALTER TABLE external_identities DROP COLUMN IF EXISTS confirm_token;
+
+ALTER TABLE oauth_authorization_codes ADD COLUMN IF NOT EXISTS code_hash bytea;
+UPDATE oauth_authorization_codes SET code_hash = sha256(convert_to(code, 'UTF8')) WHERE code_hash IS NULL;
+ALTER TABLE oauth_authorization_codes ALTER COLUMN code_hash SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS oauth_authorization_codes_code_hash_idx ON oauth_authorization_codes(code_hash);
+ALTER TABLE oauth_authorization_codes DROP COLUMN IF EXISTS code;
+
+ALTER TABLE data_exports ADD COLUMN IF NOT EXISTS token_hash bytea;
+UPDATE data_exports SET token_hash = sha256(convert_to(token, 'UTF8')) WHERE token IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS data_exports_token_hash_idx ON data_exports(token_hash);
+ALTER TABLE data_exports DROP COLUMN IF EXISTS token;
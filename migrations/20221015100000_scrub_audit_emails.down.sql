@@ -0,0 +1 @@
+-- The scrubbed emails cannot be restored.
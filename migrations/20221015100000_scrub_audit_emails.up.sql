@@ -0,0 +1,4 @@
+-- Entries outlive the users they are about, so they keep no emails.
+UPDATE audit_log SET details = '' WHERE action IN ('user.verify_email', 'user.reset_password', 'user.enable');
+UPDATE audit_log SET details = regexp_replace(details, '^[^,]*, ', '')
+    WHERE action IN ('user.disable', 'user.revoke_sessions');
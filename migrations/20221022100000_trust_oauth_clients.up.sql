@@ -0,0 +1,3 @@
+-- Only trusted clients may introspect tokens, admins flag the services that
+-- need to by registering them again.
+ALTER TABLE oauth_clients ADD COLUMN IF NOT EXISTS trusted BOOLEAN NOT NULL DEFAULT false;
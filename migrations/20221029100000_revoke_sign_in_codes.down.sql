@@ -0,0 +1 @@
+-- The revoked sign in codes cannot be restored.
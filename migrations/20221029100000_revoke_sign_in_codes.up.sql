@@ -0,0 +1,4 @@
+-- Sign in codes hashed without the server secret by an earlier migration can
+-- never match, the outstanding codes are revoked so users request new ones.
+UPDATE sessions SET valid_until = now()
+    WHERE type = 'sign-in-code' AND (valid_until IS NULL OR valid_until > now());
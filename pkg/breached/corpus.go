@@ -0,0 +1,96 @@
+// Package breached checks passwords against a local copy of the range files of
+// breached password hashes, like the ones Pwned Passwords serves with its
+// k-anonymity API. Each file is named by the first 5 hex digits of the SHA-1
+// hashes it lists, and has a "SUFFIX:COUNT" line for each hash.
+package breached
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1" //nolint:gosec // The corpus is of SHA-1 hashes.
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const _prefixLength = 5
+
+// Corpus -.
+type Corpus struct {
+	dir      string
+	minCount int
+}
+
+// New returns the corpus of the range files in dir, a password is counted as
+// breached when it appeared in at least minCount breaches.
+func New(dir string, minCount int) (Corpus, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return Corpus{}, fmt.Errorf("breached - New - os.Stat: %w", err)
+	}
+	if !info.IsDir() {
+		return Corpus{}, fmt.Errorf("breached - New - %s is not a directory", dir)
+	}
+
+	if minCount < 1 {
+		minCount = 1
+	}
+
+	return Corpus{dir: dir, minCount: minCount}, nil
+}
+
+// Contains returns whether the password is breached. A range missing from the
+// corpus holds no breached passwords.
+func (c Corpus) Contains(ctx context.Context, password []byte) (bool, error) {
+	sum := sha1.Sum(password) //nolint:gosec // The corpus is of SHA-1 hashes.
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:_prefixLength], hash[_prefixLength:]
+
+	f, err := c.openRange(prefix)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("breached - Corpus - Contains - c.openRange: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		i := strings.IndexByte(line, ':')
+		if i < 0 || !strings.EqualFold(line[:i], suffix) {
+			continue
+		}
+
+		count, err := strconv.Atoi(line[i+1:])
+		if err != nil {
+			return false, fmt.Errorf("breached - Corpus - Contains - strconv.Atoi: %w", err)
+		}
+
+		return count >= c.minCount, nil
+	}
+
+	if err = scanner.Err(); err != nil {
+		return false, fmt.Errorf("breached - Corpus - Contains - scanner.Err: %w", err)
+	}
+
+	return false, nil
+}
+
+// openRange opens the range file of the prefix, which may have a .txt extension.
+func (c Corpus) openRange(prefix string) (*os.File, error) {
+	f, err := os.Open(filepath.Join(c.dir, prefix))
+	if errors.Is(err, os.ErrNotExist) {
+		return os.Open(filepath.Join(c.dir, prefix+".txt"))
+	}
+
+	return f, err
+}
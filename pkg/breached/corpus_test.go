@@ -0,0 +1,48 @@
+package breached_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PanziApp/backend/pkg/breached"
+)
+
+func TestContains(t *testing.T) {
+	dir := t.TempDir()
+
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8.
+	err := os.WriteFile(filepath.Join(dir, "5BAA6"), []byte(
+		"003D68EB55068C33ACE09247EE4C639306B:3\r\n"+
+			"1E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\r\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// SHA-1 of "letmein" is B7A875FC1EA228B9061041B7CEC4BD3C52AB3CE3.
+	err = os.WriteFile(filepath.Join(dir, "B7A87.txt"), []byte("5FC1EA228B9061041B7CEC4BD3C52AB3CE3:2\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	corpus, err := breached.New(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for password, want := range map[string]bool{
+		"password":               true,
+		"letmein":                false, // under the min count
+		"correct horse battery":  false, // range missing
+		"not in the range 5BAA6": false,
+	} {
+		got, err := corpus.Contains(context.Background(), []byte(password))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Contains(%q): %v, want %v", password, got, want)
+		}
+	}
+}
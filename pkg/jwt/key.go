@@ -0,0 +1,186 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Signing algorithms.
+const (
+	EdDSA = "EdDSA"
+	ES256 = "ES256"
+)
+
+const _es256KeySize = 32
+
+var (
+	// ErrUnsupportedAlgorithm -.
+	ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+	// ErrInvalidSignature -.
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// Key is a signing key identified by its key id.
+type Key struct {
+	Id         string
+	Algorithm  string
+	privateKey crypto.Signer
+}
+
+// GenerateKey -.
+func GenerateKey(id, algorithm string) (Key, error) {
+	k := Key{Id: id, Algorithm: algorithm}
+
+	var err error
+
+	switch algorithm {
+	case EdDSA:
+		_, k.privateKey, err = ed25519.GenerateKey(rand.Reader)
+	case ES256:
+		k.privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return Key{}, ErrUnsupportedAlgorithm
+	}
+
+	if err != nil {
+		return Key{}, fmt.Errorf("jwt - GenerateKey: %w", err)
+	}
+
+	return k, nil
+}
+
+// ParseKey parses a PKCS #8 PEM encoded Ed25519 or P-256 private key.
+func ParseKey(id string, pemBytes []byte) (Key, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return Key{}, fmt.Errorf("jwt - ParseKey - %s: no PEM block", id)
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return Key{}, fmt.Errorf("jwt - ParseKey - x509.ParsePKCS8PrivateKey: %w", err)
+	}
+
+	switch pk := privateKey.(type) {
+	case ed25519.PrivateKey:
+		return Key{Id: id, Algorithm: EdDSA, privateKey: pk}, nil
+	case *ecdsa.PrivateKey:
+		if pk.Curve != elliptic.P256() {
+			return Key{}, ErrUnsupportedAlgorithm
+		}
+
+		return Key{Id: id, Algorithm: ES256, privateKey: pk}, nil
+	default:
+		return Key{}, ErrUnsupportedAlgorithm
+	}
+}
+
+// MarshalPEM encodes the private key as PKCS #8 PEM.
+func (k Key) MarshalPEM() ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("jwt - Key - MarshalPEM: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func (k Key) sign(signingInput []byte) ([]byte, error) {
+	switch pk := k.privateKey.(type) {
+	case ed25519.PrivateKey:
+		return ed25519.Sign(pk, signingInput), nil
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(signingInput)
+
+		r, s, err := ecdsa.Sign(rand.Reader, pk, digest[:])
+		if err != nil {
+			return nil, fmt.Errorf("jwt - Key - sign: %w", err)
+		}
+
+		// JWS uses the fixed size R || S encoding rather than ASN.1.
+		signature := make([]byte, 2*_es256KeySize)
+		r.FillBytes(signature[:_es256KeySize])
+		s.FillBytes(signature[_es256KeySize:])
+
+		return signature, nil
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+}
+
+func (k Key) verify(signingInput, signature []byte) error {
+	switch pk := k.privateKey.(type) {
+	case ed25519.PrivateKey:
+		publicKey, _ := pk.Public().(ed25519.PublicKey)
+		if !ed25519.Verify(publicKey, signingInput, signature) {
+			return ErrInvalidSignature
+		}
+
+		return nil
+	case *ecdsa.PrivateKey:
+		if len(signature) != 2*_es256KeySize {
+			return ErrInvalidSignature
+		}
+
+		digest := sha256.Sum256(signingInput)
+		r := new(big.Int).SetBytes(signature[:_es256KeySize])
+		s := new(big.Int).SetBytes(signature[_es256KeySize:])
+
+		if !ecdsa.Verify(&pk.PublicKey, digest[:], r, s) {
+			return ErrInvalidSignature
+		}
+
+		return nil
+	default:
+		return ErrUnsupportedAlgorithm
+	}
+}
+
+// JWK is the public part of a key in JSON Web Key format.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	KeyId     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (k Key) publicJWK() JWK {
+	jwk := JWK{Use: "sig", KeyId: k.Id, Algorithm: k.Algorithm}
+
+	switch pk := k.privateKey.(type) {
+	case ed25519.PrivateKey:
+		publicKey, _ := pk.Public().(ed25519.PublicKey)
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	case *ecdsa.PrivateKey:
+		x := make([]byte, _es256KeySize)
+		y := make([]byte, _es256KeySize)
+		pk.X.FillBytes(x)
+		pk.Y.FillBytes(y)
+		jwk.KeyType = "EC"
+		jwk.Curve = "P-256"
+		jwk.X = base64.RawURLEncoding.EncodeToString(x)
+		jwk.Y = base64.RawURLEncoding.EncodeToString(y)
+	}
+
+	return jwk
+}
@@ -0,0 +1,241 @@
+// Package jwt implements signing and verification of JSON Web Tokens.
+package jwt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const _keyFileExt = ".pem"
+
+var (
+	// ErrMalformedToken -.
+	ErrMalformedToken = errors.New("malformed token")
+	// ErrUnknownKey -.
+	ErrUnknownKey = errors.New("unknown key")
+	// ErrActiveKey -.
+	ErrActiveKey = errors.New("active key cannot be removed")
+)
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyId     string `json:"kid"`
+}
+
+// Keyring signs tokens with its active key and verifies them with the active
+// and retired keys, so tokens signed before a rotation stay valid until they expire.
+type Keyring struct {
+	mu     sync.RWMutex
+	active string
+	keys   map[string]Key
+}
+
+// NewKeyring -.
+func NewKeyring(active Key, retired ...Key) *Keyring {
+	k := &Keyring{
+		active: active.Id,
+		keys:   map[string]Key{active.Id: active},
+	}
+
+	for _, key := range retired {
+		k.keys[key.Id] = key
+	}
+
+	return k
+}
+
+// LoadKeyring loads the "<key id>.pem" files of dir. The key with activeKeyId
+// is generated with the algorithm and saved to dir when it does not exist yet,
+// which makes rotating the key a matter of changing activeKeyId.
+func LoadKeyring(dir, activeKeyId, algorithm string) (*Keyring, error) {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("jwt - LoadKeyring - os.MkdirAll: %w", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+_keyFileExt))
+	if err != nil {
+		return nil, fmt.Errorf("jwt - LoadKeyring - filepath.Glob: %w", err)
+	}
+
+	var (
+		active  *Key
+		retired []Key
+	)
+
+	for _, path := range paths {
+		pemBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("jwt - LoadKeyring - os.ReadFile: %w", err)
+		}
+
+		key, err := ParseKey(strings.TrimSuffix(filepath.Base(path), _keyFileExt), pemBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		if key.Id == activeKeyId {
+			active = &key
+		} else {
+			retired = append(retired, key)
+		}
+	}
+
+	if active == nil {
+		key, err := GenerateKey(activeKeyId, algorithm)
+		if err != nil {
+			return nil, err
+		}
+
+		pemBytes, err := key.MarshalPEM()
+		if err != nil {
+			return nil, err
+		}
+
+		err = os.WriteFile(filepath.Join(dir, activeKeyId+_keyFileExt), pemBytes, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("jwt - LoadKeyring - os.WriteFile: %w", err)
+		}
+
+		active = &key
+	}
+
+	return NewKeyring(*active, retired...), nil
+}
+
+// Rotate makes key the active key, the previous active key is retired.
+func (k *Keyring) Rotate(key Key) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys[key.Id] = key
+	k.active = key.Id
+}
+
+// Remove drops a retired key, tokens signed with it are no longer valid.
+func (k *Keyring) Remove(keyId string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if keyId == k.active {
+		return ErrActiveKey
+	}
+
+	delete(k.keys, keyId)
+
+	return nil
+}
+
+// Sign returns the compact serialization of a token with the claims.
+func (k *Keyring) Sign(claims interface{}) (string, error) {
+	k.mu.RLock()
+	key := k.keys[k.active]
+	k.mu.RUnlock()
+
+	headerJSON, err := json.Marshal(header{Algorithm: key.Algorithm, Type: "JWT", KeyId: key.Id})
+	if err != nil {
+		return "", fmt.Errorf("jwt - Keyring - Sign - json.Marshal: %w", err)
+	}
+
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("jwt - Keyring - Sign - json.Marshal: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." +
+		base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	signature, err := key.sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Verify checks the signature of the token and decodes its claims.
+// Validating the claims themselves, like the expiry, is up to the caller.
+func (k *Keyring) Verify(token string, claims interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ErrMalformedToken
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return ErrMalformedToken
+	}
+
+	var h header
+	if err = json.Unmarshal(headerJSON, &h); err != nil {
+		return ErrMalformedToken
+	}
+
+	k.mu.RLock()
+	key, ok := k.keys[h.KeyId]
+	k.mu.RUnlock()
+
+	if !ok {
+		return ErrUnknownKey
+	}
+
+	// The algorithm is bound to the key, never trust the header to choose it.
+	if h.Algorithm != key.Algorithm {
+		return ErrInvalidSignature
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return ErrMalformedToken
+	}
+
+	err = key.verify([]byte(parts[0]+"."+parts[1]), signature)
+	if err != nil {
+		return err
+	}
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ErrMalformedToken
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(claimsJSON))
+	decoder.UseNumber()
+
+	if err = decoder.Decode(claims); err != nil {
+		return ErrMalformedToken
+	}
+
+	return nil
+}
+
+// JWKS returns the public keys of the active and retired keys.
+func (k *Keyring) JWKS() JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	jwks := JWKS{Keys: make([]JWK, 0, len(k.keys))}
+	for _, key := range k.keys {
+		jwks.Keys = append(jwks.Keys, key.publicJWK())
+	}
+
+	// Active key first, the rest in a stable order.
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		if jwks.Keys[i].KeyId == k.active || jwks.Keys[j].KeyId == k.active {
+			return jwks.Keys[i].KeyId == k.active
+		}
+
+		return jwks.Keys[i].KeyId < jwks.Keys[j].KeyId
+	})
+
+	return jwks
+}
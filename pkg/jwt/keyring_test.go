@@ -0,0 +1,255 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/PanziApp/backend/pkg/jwt"
+)
+
+type claims struct {
+	Subject string `json:"sub"`
+	Scope   string `json:"scope"`
+}
+
+var testClaims = claims{Subject: "42", Scope: "openid email"}
+
+func generateKey(t *testing.T, id, algorithm string) jwt.Key {
+	t.Helper()
+
+	key, err := jwt.GenerateKey(id, algorithm)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	return key
+}
+
+func sign(t *testing.T, k *jwt.Keyring) string {
+	t.Helper()
+
+	token, err := k.Sign(testClaims)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	return token
+}
+
+// decodePart decodes the header or payload of the token into v.
+func decodePart(t *testing.T, token string, part int, v interface{}) {
+	t.Helper()
+
+	b, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[part])
+	if err != nil {
+		t.Fatalf("decode part %d: %v", part, err)
+	}
+	if err = json.Unmarshal(b, v); err != nil {
+		t.Fatalf("unmarshal part %d: %v", part, err)
+	}
+}
+
+// replacePart re-encodes v as the header or payload of the token, keeping the signature.
+func replacePart(t *testing.T, token string, part int, v interface{}) string {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal part %d: %v", part, err)
+	}
+
+	parts := strings.Split(token, ".")
+	parts[part] = base64.RawURLEncoding.EncodeToString(b)
+
+	return strings.Join(parts, ".")
+}
+
+func TestSignVerify(t *testing.T) {
+	for _, algorithm := range []string{jwt.EdDSA, jwt.ES256} {
+		t.Run(algorithm, func(t *testing.T) {
+			k := jwt.NewKeyring(generateKey(t, "key-1", algorithm))
+			token := sign(t, k)
+
+			var header map[string]string
+			decodePart(t, token, 0, &header)
+			if header["alg"] != algorithm || header["kid"] != "key-1" || header["typ"] != "JWT" {
+				t.Fatalf("header = %v, want alg %s, kid key-1 and typ JWT", header, algorithm)
+			}
+
+			var got claims
+			if err := k.Verify(token, &got); err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if got != testClaims {
+				t.Fatalf("claims = %+v, want %+v", got, testClaims)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsAlgorithmMismatch(t *testing.T) {
+	k := jwt.NewKeyring(generateKey(t, "key-1", jwt.EdDSA))
+	token := sign(t, k)
+
+	for _, algorithm := range []string{jwt.ES256, "none", "HS256"} {
+		forged := replacePart(t, token, 0, map[string]string{"alg": algorithm, "typ": "JWT", "kid": "key-1"})
+
+		var got claims
+		if err := k.Verify(forged, &got); !errors.Is(err, jwt.ErrInvalidSignature) {
+			t.Fatalf("alg %s: err = %v, want %v", algorithm, err, jwt.ErrInvalidSignature)
+		}
+	}
+}
+
+func TestVerifyRejectsUnknownKey(t *testing.T) {
+	token := sign(t, jwt.NewKeyring(generateKey(t, "key-1", jwt.EdDSA)))
+	other := jwt.NewKeyring(generateKey(t, "key-2", jwt.EdDSA))
+
+	var got claims
+	if err := other.Verify(token, &got); !errors.Is(err, jwt.ErrUnknownKey) {
+		t.Fatalf("err = %v, want %v", err, jwt.ErrUnknownKey)
+	}
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	for _, algorithm := range []string{jwt.EdDSA, jwt.ES256} {
+		t.Run(algorithm, func(t *testing.T) {
+			k := jwt.NewKeyring(generateKey(t, "key-1", algorithm))
+			token := sign(t, k)
+
+			payload := replacePart(t, token, 1, claims{Subject: "1", Scope: testClaims.Scope})
+
+			parts := strings.Split(token, ".")
+			signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+			if err != nil {
+				t.Fatalf("decode signature: %v", err)
+			}
+			signature[0] ^= 0xff
+			parts[2] = base64.RawURLEncoding.EncodeToString(signature)
+			tamperedSignature := strings.Join(parts, ".")
+
+			for name, tampered := range map[string]string{"payload": payload, "signature": tamperedSignature} {
+				var got claims
+				if err := k.Verify(tampered, &got); !errors.Is(err, jwt.ErrInvalidSignature) {
+					t.Fatalf("%s: err = %v, want %v", name, err, jwt.ErrInvalidSignature)
+				}
+			}
+
+			var got claims
+			if err := k.Verify(token+".extra", &got); !errors.Is(err, jwt.ErrMalformedToken) {
+				t.Fatalf("err = %v, want %v", err, jwt.ErrMalformedToken)
+			}
+		})
+	}
+}
+
+func TestRotateKeepsRetiredKeyForVerification(t *testing.T) {
+	k := jwt.NewKeyring(generateKey(t, "key-1", jwt.EdDSA))
+	old := sign(t, k)
+
+	k.Rotate(generateKey(t, "key-2", jwt.ES256))
+	token := sign(t, k)
+
+	var header map[string]string
+	decodePart(t, token, 0, &header)
+	if header["kid"] != "key-2" || header["alg"] != jwt.ES256 {
+		t.Fatalf("header = %v, want the retired key not to sign", header)
+	}
+
+	var got claims
+	if err := k.Verify(old, &got); err != nil {
+		t.Fatalf("Verify token of the retired key: %v", err)
+	}
+
+	if err := k.Remove("key-2"); !errors.Is(err, jwt.ErrActiveKey) {
+		t.Fatalf("Remove active key: err = %v, want %v", err, jwt.ErrActiveKey)
+	}
+
+	if err := k.Remove("key-1"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := k.Verify(old, &got); !errors.Is(err, jwt.ErrUnknownKey) {
+		t.Fatalf("Verify token of the removed key: err = %v, want %v", err, jwt.ErrUnknownKey)
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	dir := t.TempDir()
+
+	k, err := jwt.LoadKeyring(dir, "key-1", jwt.EdDSA)
+	if err != nil {
+		t.Fatalf("LoadKeyring: %v", err)
+	}
+	old := sign(t, k)
+
+	// Changing the active key id generates the new key and retires the old one.
+	k, err = jwt.LoadKeyring(dir, "key-2", jwt.ES256)
+	if err != nil {
+		t.Fatalf("LoadKeyring: %v", err)
+	}
+
+	var got claims
+	if err = k.Verify(old, &got); err != nil {
+		t.Fatalf("Verify token of the retired key: %v", err)
+	}
+
+	jwks := k.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].KeyId != "key-2" || jwks.Keys[1].KeyId != "key-1" {
+		t.Fatalf("JWKS = %+v, want key-2 then key-1", jwks)
+	}
+}
+
+func TestParseKeyRejectsGarbage(t *testing.T) {
+	if _, err := jwt.ParseKey("key-1", []byte("not a key")); err == nil {
+		t.Fatal("ParseKey accepted a file without a PEM block")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	edKey := generateKey(t, "key-1", jwt.EdDSA)
+	k := jwt.NewKeyring(generateKey(t, "key-2", jwt.ES256), edKey)
+	ecToken := sign(t, k)
+
+	jwks := k.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("JWKS has %d keys, want 2", len(jwks.Keys))
+	}
+
+	ec, okp := jwks.Keys[0], jwks.Keys[1]
+	if ec.KeyId != "key-2" || ec.KeyType != "EC" || ec.Curve != "P-256" || ec.Algorithm != jwt.ES256 || ec.Use != "sig" {
+		t.Fatalf("EC key = %+v", ec)
+	}
+	if okp.KeyId != "key-1" || okp.KeyType != "OKP" || okp.Curve != "Ed25519" || okp.Algorithm != jwt.EdDSA || okp.Y != "" {
+		t.Fatalf("OKP key = %+v", okp)
+	}
+
+	// The published key verifies the signatures of the keyring.
+	x, _ := base64.RawURLEncoding.DecodeString(ec.X)
+	y, _ := base64.RawURLEncoding.DecodeString(ec.Y)
+	publicKey := ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+
+	parts := strings.Split(ecToken, ".")
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(&publicKey, digest[:], r, s) {
+		t.Fatal("EC JWK does not verify the token")
+	}
+
+	edToken := sign(t, jwt.NewKeyring(edKey))
+
+	okpX, _ := base64.RawURLEncoding.DecodeString(okp.X)
+	parts = strings.Split(edToken, ".")
+	signature, _ = base64.RawURLEncoding.DecodeString(parts[2])
+	if !ed25519.Verify(ed25519.PublicKey(okpX), []byte(parts[0]+"."+parts[1]), signature) {
+		t.Fatal("OKP JWK does not verify the token")
+	}
+}
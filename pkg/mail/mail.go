@@ -0,0 +1,9 @@
+// Package mail implements email senders.
+package mail
+
+import "context"
+
+// Mailer -.
+type Mailer interface {
+	Send(ctx context.Context, receiver, name, subject, messageInHtml string) error
+}
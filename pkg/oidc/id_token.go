@@ -0,0 +1,153 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// _clockSkew is the leeway given to the clocks of providers.
+const _clockSkew = time.Minute
+
+type idTokenHeader struct {
+	Algorithm string `json:"alg"`
+	KeyId     string `json:"kid"`
+}
+
+type idTokenClaims struct {
+	Issuer          string          `json:"iss"`
+	Subject         string          `json:"sub"`
+	Audience        audience        `json:"aud"`
+	AuthorizedParty string          `json:"azp"`
+	Expiry          int64           `json:"exp"`
+	IssuedAt        int64           `json:"iat"`
+	Nonce           string          `json:"nonce"`
+	Email           string          `json:"email"`
+	EmailVerified   json.RawMessage `json:"email_verified"`
+	Name            string          `json:"name"`
+}
+
+// audience is a single audience or a list of them.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+
+	*a = list
+
+	return nil
+}
+
+func (a audience) contains(clientId string) bool {
+	for _, aud := range a {
+		if aud == clientId {
+			return true
+		}
+	}
+
+	return false
+}
+
+// emailVerified accepts the boolean of the specification, and the string some providers send.
+func (c idTokenClaims) emailVerified() bool {
+	var b bool
+	if json.Unmarshal(c.EmailVerified, &b) == nil {
+		return b
+	}
+
+	var s string
+	if json.Unmarshal(c.EmailVerified, &s) == nil {
+		return s == "true"
+	}
+
+	return false
+}
+
+// verifyIDToken validates the ID token, OpenID Connect Core section 3.1.3.7.
+func (p *Provider) verifyIDToken(ctx context.Context, token, nonce string) (Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Identity{}, ErrInvalidIDToken
+	}
+
+	var h idTokenHeader
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return Identity{}, err
+	}
+
+	m, err := p.discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	key, err := p.key(ctx, m, h.KeyId)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	// The algorithm is bound to the key, never trust the header to choose it.
+	if h.Algorithm != key.algorithm {
+		return Identity{}, ErrInvalidIDToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Identity{}, ErrInvalidIDToken
+	}
+
+	if err = key.verify([]byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return Identity{}, err
+	}
+
+	var c idTokenClaims
+	if err = decodeSegment(parts[1], &c); err != nil {
+		return Identity{}, err
+	}
+
+	now := p.now()
+
+	switch {
+	case c.Issuer != p.cfg.Issuer,
+		c.Subject == "",
+		c.Nonce == "",
+		!c.Audience.contains(p.cfg.ClientId),
+		len(c.Audience) > 1 && c.AuthorizedParty != p.cfg.ClientId,
+		now.After(time.Unix(c.Expiry, 0).Add(_clockSkew)),
+		time.Unix(c.IssuedAt, 0).After(now.Add(_clockSkew)),
+		subtle.ConstantTimeCompare([]byte(c.Nonce), []byte(nonce)) != 1:
+		return Identity{}, ErrInvalidIDToken
+	}
+
+	return Identity{
+		Issuer:        c.Issuer,
+		Subject:       c.Subject,
+		Email:         c.Email,
+		EmailVerified: c.emailVerified(),
+		Name:          c.Name,
+	}, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrInvalidIDToken
+	}
+
+	if err = json.Unmarshal(b, v); err != nil {
+		return ErrInvalidIDToken
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+)
+
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyId     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// verificationKey is a public key of the provider and the algorithm it verifies.
+type verificationKey struct {
+	algorithm string
+	key       crypto.PublicKey
+}
+
+// parseKey parses a signing key, keys of other uses and unsupported types are skipped.
+func (k jwk) parseKey() (verificationKey, bool) {
+	if k.Use != "" && k.Use != "sig" {
+		return verificationKey{}, false
+	}
+
+	switch {
+	case k.KeyType == "RSA" && (k.Algorithm == "" || k.Algorithm == RS256):
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return verificationKey{}, false
+		}
+
+		return verificationKey{RS256, &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}}, true
+	case k.KeyType == "EC" && k.Curve == "P-256" && (k.Algorithm == "" || k.Algorithm == ES256):
+		x, errX := base64.RawURLEncoding.DecodeString(k.X)
+		y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+		if errX != nil || errY != nil {
+			return verificationKey{}, false
+		}
+
+		pk := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pk.Curve.IsOnCurve(pk.X, pk.Y) {
+			return verificationKey{}, false
+		}
+
+		return verificationKey{ES256, pk}, true
+	case k.KeyType == "OKP" && k.Curve == "Ed25519" && (k.Algorithm == "" || k.Algorithm == EdDSA):
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return verificationKey{}, false
+		}
+
+		return verificationKey{EdDSA, ed25519.PublicKey(x)}, true
+	default:
+		return verificationKey{}, false
+	}
+}
+
+func (k verificationKey) verify(signingInput, signature []byte) error {
+	var ok bool
+
+	switch pk := k.key.(type) {
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(signingInput)
+		ok = rsa.VerifyPKCS1v15(pk, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		// JWS ECDSA signatures are the fixed size concatenation of r and s.
+		if len(signature) == 64 {
+			digest := sha256.Sum256(signingInput)
+			r := new(big.Int).SetBytes(signature[:32])
+			s := new(big.Int).SetBytes(signature[32:])
+			ok = ecdsa.Verify(pk, digest[:], r, s)
+		}
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(pk, signingInput, signature)
+	}
+
+	if !ok {
+		return ErrInvalidIDToken
+	}
+
+	return nil
+}
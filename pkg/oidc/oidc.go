@@ -0,0 +1,267 @@
+// Package oidc implements the relying party side of the OpenID Connect authorization
+// code flow with PKCE, https://openid.net/specs/openid-connect-core-1_0.html.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Signing algorithms accepted for ID tokens.
+const (
+	RS256 = "RS256"
+	ES256 = "ES256"
+	EdDSA = "EdDSA"
+)
+
+const (
+	_defaultTimeout = 10 * time.Second
+	// _jwksRefreshInterval limits how often unknown key ids trigger a JWKS refetch.
+	_jwksRefreshInterval = time.Minute
+	_maxResponseSize     = 1 << 20
+)
+
+var (
+	// ErrDiscovery is returned when the provider metadata or keys cannot be fetched.
+	ErrDiscovery = errors.New("provider discovery failed")
+	// ErrExchange is returned when the provider rejects the authorization code.
+	ErrExchange = errors.New("authorization code exchange failed")
+	// ErrInvalidIDToken -.
+	ErrInvalidIDToken = errors.New("invalid id token")
+)
+
+// Config of a provider the application is registered with as a client.
+type Config struct {
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Identity is the end-user an ID token was issued for.
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider is an OpenID provider, its metadata and keys are discovered on first use.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+	now    func() time.Time
+
+	mu            sync.Mutex
+	metadata      *metadata
+	keys          map[string]verificationKey
+	keysFetchTime time.Time
+}
+
+// New -.
+func New(cfg Config, opts ...Option) *Provider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	p := &Provider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: _defaultTimeout},
+		now:    time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// AuthCodeURL returns the URL of the authorization endpoint to send the user to.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", p.cfg.ClientId)
+	v.Set("redirect_uri", p.cfg.RedirectURL)
+	v.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", codeChallenge)
+	v.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(m.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return m.AuthorizationEndpoint + sep + v.Encode(), nil
+}
+
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange redeems the authorization code and verifies the returned ID token,
+// which must carry the nonce of the authorization request.
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (Identity, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Identity{}, fmt.Errorf("oidc - Exchange - http.NewRequest: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientId), url.QueryEscape(p.cfg.ClientSecret))
+
+	var resp tokenResponse
+
+	status, err := p.do(req, &resp)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %s", ErrExchange, err)
+	}
+
+	if status != http.StatusOK || resp.IDToken == "" {
+		return Identity{}, fmt.Errorf("%w: %d %s %s", ErrExchange, status, resp.Error, resp.ErrorDescription)
+	}
+
+	return p.verifyIDToken(ctx, resp.IDToken, nonce)
+}
+
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	wellKnown := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, fmt.Errorf("oidc - discover - http.NewRequest: %w", err)
+	}
+
+	var m metadata
+
+	status, err := p.do(req, &m)
+	if err != nil || status != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s: %d %v", ErrDiscovery, wellKnown, status, err)
+	}
+
+	// The issuer must be the one configured, OpenID Connect Discovery section 4.3.
+	if m.Issuer != p.cfg.Issuer || m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: %s: invalid metadata", ErrDiscovery, wellKnown)
+	}
+
+	p.metadata = &m
+
+	return p.metadata, nil
+}
+
+// key returns the verification key with the id, refetching the provider keys
+// when it is unknown since providers rotate their keys.
+func (p *Provider) key(ctx context.Context, m *metadata, keyId string) (verificationKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if k, ok := p.lookupKey(keyId); ok {
+		return k, nil
+	}
+
+	if p.keys != nil && p.now().Sub(p.keysFetchTime) < _jwksRefreshInterval {
+		return verificationKey{}, ErrInvalidIDToken
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.JWKSURI, nil)
+	if err != nil {
+		return verificationKey{}, fmt.Errorf("oidc - key - http.NewRequest: %w", err)
+	}
+
+	var set jwks
+
+	status, err := p.do(req, &set)
+	if err != nil || status != http.StatusOK {
+		return verificationKey{}, fmt.Errorf("%w: %s: %d %v", ErrDiscovery, m.JWKSURI, status, err)
+	}
+
+	p.keys = make(map[string]verificationKey, len(set.Keys))
+	p.keysFetchTime = p.now()
+
+	for _, k := range set.Keys {
+		if vk, ok := k.parseKey(); ok {
+			p.keys[k.KeyId] = vk
+		}
+	}
+
+	if k, ok := p.lookupKey(keyId); ok {
+		return k, nil
+	}
+
+	return verificationKey{}, ErrInvalidIDToken
+}
+
+// lookupKey finds the key with the id, a token without key id is accepted when there is a single key.
+func (p *Provider) lookupKey(keyId string) (verificationKey, bool) {
+	if k, ok := p.keys[keyId]; ok {
+		return k, true
+	}
+
+	if keyId == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k, true
+		}
+	}
+
+	return verificationKey{}, false
+}
+
+func (p *Provider) do(req *http.Request, v interface{}) (int, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(io.LimitReader(resp.Body, _maxResponseSize)).Decode(v)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,144 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PanziApp/backend/pkg/oidc"
+	"github.com/PanziApp/backend/pkg/oidc/oidctest"
+)
+
+const (
+	clientId     = "panzi"
+	clientSecret = "client-secret"
+	redirectURL  = "http://localhost:8080/sign-in/external/test/callback"
+	verifier     = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+var user = oidctest.User{Subject: "subject-1", Email: "user@example.com", EmailVerified: true, Name: "John Doe"}
+
+func newProvider(idp *oidctest.Server, opts ...oidc.Option) *oidc.Provider {
+	return oidc.New(oidc.Config{
+		Issuer:       idp.Issuer(),
+		ClientId:     idp.ClientId,
+		ClientSecret: idp.ClientSecret,
+		RedirectURL:  redirectURL,
+	}, opts...)
+}
+
+// signIn runs the authorization code flow as the user and exchanges the code, expecting the nonce.
+func signIn(t *testing.T, idp *oidctest.Server, p *oidc.Provider, nonce string) (oidc.Identity, error) {
+	t.Helper()
+
+	authURL, err := p.AuthCodeURL(context.Background(), "state-1", "nonce-1", oidc.CodeChallenge(verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	code, state, err := idp.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if state != "state-1" {
+		t.Fatalf("state = %q, want %q", state, "state-1")
+	}
+
+	return p.Exchange(context.Background(), code, verifier, nonce)
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	idp := oidctest.NewServer(clientId, clientSecret, user)
+	defer idp.Close()
+
+	identity, err := signIn(t, idp, newProvider(idp), "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	want := oidc.Identity{
+		Issuer:        idp.Issuer(),
+		Subject:       user.Subject,
+		Email:         user.Email,
+		EmailVerified: true,
+		Name:          user.Name,
+	}
+	if identity != want {
+		t.Fatalf("identity = %+v, want %+v", identity, want)
+	}
+}
+
+func TestExchangeRejectsOtherNonce(t *testing.T) {
+	idp := oidctest.NewServer(clientId, clientSecret, user)
+	defer idp.Close()
+
+	_, err := signIn(t, idp, newProvider(idp), "other-nonce")
+	if !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Fatalf("err = %v, want %v", err, oidc.ErrInvalidIDToken)
+	}
+}
+
+func TestExchangeRequiresCodeVerifier(t *testing.T) {
+	idp := oidctest.NewServer(clientId, clientSecret, user)
+	defer idp.Close()
+
+	p := newProvider(idp)
+
+	authURL, err := p.AuthCodeURL(context.Background(), "state-1", "nonce-1", oidc.CodeChallenge(verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, _, err := idp.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = p.Exchange(context.Background(), code, "wrong-verifier-wrong-verifier-wrong-verifier", "nonce-1")
+	if !errors.Is(err, oidc.ErrExchange) {
+		t.Fatalf("err = %v, want %v", err, oidc.ErrExchange)
+	}
+}
+
+func TestExchangeRejectsWrongClientSecret(t *testing.T) {
+	idp := oidctest.NewServer(clientId, clientSecret, user)
+	defer idp.Close()
+
+	p := oidc.New(oidc.Config{
+		Issuer:       idp.Issuer(),
+		ClientId:     clientId,
+		ClientSecret: "wrong-secret",
+		RedirectURL:  redirectURL,
+	})
+
+	_, err := signIn(t, idp, p, "nonce-1")
+	if !errors.Is(err, oidc.ErrExchange) {
+		t.Fatalf("err = %v, want %v", err, oidc.ErrExchange)
+	}
+}
+
+func TestProviderKeyRotation(t *testing.T) {
+	idp := oidctest.NewServer(clientId, clientSecret, user)
+	defer idp.Close()
+
+	now := time.Now()
+	p := newProvider(idp, oidc.Clock(func() time.Time { return now }))
+
+	if _, err := signIn(t, idp, p, "nonce-1"); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	idp.RotateKey()
+
+	// Keys are not refetched more than once a minute.
+	if _, err := signIn(t, idp, p, "nonce-1"); !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Fatalf("err = %v, want %v", err, oidc.ErrInvalidIDToken)
+	}
+
+	now = now.Add(2 * time.Minute)
+
+	if _, err := signIn(t, idp, p, "nonce-1"); err != nil {
+		t.Fatalf("Exchange after rotation: %v", err)
+	}
+}
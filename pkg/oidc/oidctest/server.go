@@ -0,0 +1,292 @@
+// Package oidctest provides a stand-in OpenID provider to drive the authorization
+// code flow in tests, the way a real provider and a consenting user would.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const _idTokenLifetime = 5 * time.Minute
+
+// ErrNoRedirect is returned by Authorize when the provider did not redirect back.
+var ErrNoRedirect = errors.New("no redirect")
+
+// User is the account the stand-in user signs in to the provider with.
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type grant struct {
+	user          User
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+}
+
+// Server is an OpenID provider with a single client, it signs ID tokens with
+// an RS256 key and authorizes every request as User.
+type Server struct {
+	*httptest.Server
+
+	ClientId     string
+	ClientSecret string
+
+	mu     sync.Mutex
+	user   User
+	keyId  int
+	key    *rsa.PrivateKey
+	grants map[string]grant
+}
+
+// NewServer starts a provider, Close it when done.
+func NewServer(clientId, clientSecret string, user User) *Server {
+	s := &Server{
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		user:         user,
+		grants:       map[string]grant{},
+	}
+
+	s.RotateKey()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/jwks", s.jwks)
+
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// Issuer -.
+func (s *Server) Issuer() string {
+	return s.URL
+}
+
+// SetUser changes the account authorized from now on.
+func (s *Server) SetUser(user User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.user = user
+}
+
+// RotateKey replaces the signing key with a new one of a new key id.
+func (s *Server) RotateKey() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("oidctest: rsa.GenerateKey: %v", err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keyId++
+	s.key = key
+}
+
+// Authorize follows the authorization URL as a browser would and returns
+// the code and state the provider redirected back with.
+func (s *Server) Authorize(authURL string) (code, state string, err error) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	resp, err := client.Get(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	location, err := resp.Location()
+	if err != nil {
+		return "", "", ErrNoRedirect
+	}
+
+	q := location.Query()
+	if e := q.Get("error"); e != "" {
+		return "", "", fmt.Errorf("oidctest: authorize: %s", e)
+	}
+
+	return q.Get("code"), q.Get("state"), nil
+}
+
+func (s *Server) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.URL,
+		"authorization_endpoint":                s.URL + "/authorize",
+		"token_endpoint":                        s.URL + "/token",
+		"jwks_uri":                              s.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || q.Get("client_id") != s.ClientId {
+		http.Error(w, "invalid client", http.StatusBadRequest)
+		return
+	}
+
+	params := url.Values{}
+	params.Set("state", q.Get("state"))
+
+	if q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		params.Set("error", "invalid_request")
+	} else {
+		code := randomString()
+
+		s.mu.Lock()
+		s.grants[code] = grant{
+			user:          s.user,
+			redirectURI:   redirectURI.String(),
+			nonce:         q.Get("nonce"),
+			codeChallenge: q.Get("code_challenge"),
+		}
+		s.mu.Unlock()
+
+		params.Set("code", code)
+	}
+
+	redirectURI.RawQuery = params.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	clientId, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientId, _ = url.QueryUnescape(clientId)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	}
+
+	if !ok || clientId != s.ClientId || clientSecret != s.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	code := r.PostFormValue("code")
+
+	s.mu.Lock()
+	g, found := s.grants[code]
+	delete(s.grants, code)
+	s.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	challenge := base64.RawURLEncoding.EncodeToString(verifier[:])
+
+	if r.PostFormValue("grant_type") != "authorization_code" || !found ||
+		r.PostFormValue("redirect_uri") != g.redirectURI || challenge != g.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+
+	idToken, err := s.sign(map[string]interface{}{
+		"iss":            s.URL,
+		"sub":            g.user.Subject,
+		"aud":            s.ClientId,
+		"exp":            now.Add(_idTokenLifetime).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          g.nonce,
+		"email":          g.user.Email,
+		"email_verified": g.user.EmailVerified,
+		"name":           g.user.Name,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   int(_idTokenLifetime.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pk := s.key.PublicKey
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": s.kid(),
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pk.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pk.E)).Bytes()),
+		}},
+	})
+}
+
+func (s *Server) sign(claims map[string]interface{}) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": s.kid()})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func (s *Server) kid() string {
+	return fmt.Sprintf("key-%d", s.keyId)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("oidctest: rand.Read: %v", err))
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,23 @@
+package oidc
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option -.
+type Option func(*Provider)
+
+// HTTPClient sets the client used to reach the provider.
+func HTTPClient(client *http.Client) Option {
+	return func(p *Provider) {
+		p.client = client
+	}
+}
+
+// Clock sets the time used to validate ID tokens.
+func Clock(now func() time.Time) Option {
+	return func(p *Provider) {
+		p.now = now
+	}
+}
@@ -0,0 +1,251 @@
+package passwordstrength
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// _referenceYear is the year recent years are guessed around.
+const _referenceYear = 2022
+
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+	"qazwsxedcrfvtgbyhnujmik,ol.p;/",
+}
+
+var l33tSubstitutions = map[rune]rune{
+	'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '1': 'i', '!': 'i', '|': 'l',
+	'0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z',
+}
+
+type match struct {
+	end     int
+	guesses float64
+}
+
+// findMatches returns the matches of the patterns by their start.
+func findMatches(chars []rune, userRanks map[string]int) [][]match {
+	n := len(chars)
+	matches := make([][]match, n)
+	add := func(start, end int, guesses float64) {
+		matches[start] = append(matches[start], match{end: end, guesses: math.Max(guesses, 1)})
+	}
+
+	lower := []rune(strings.ToLower(string(chars)))
+	unl33t := make([]rune, n)
+	for i, c := range lower {
+		unl33t[i] = c
+		if s, ok := l33tSubstitutions[c]; ok {
+			unl33t[i] = s
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		for j := i + 1; j <= n; j++ {
+			word := string(lower[i:j])
+			variations := uppercaseVariations(chars[i:j])
+
+			if r, ok := dictionaryRank(word, userRanks); ok {
+				add(i, j, float64(r)*variations)
+			}
+			if r, ok := dictionaryRank(reverse(word), userRanks); ok {
+				add(i, j, float64(r)*variations*2)
+			}
+			if l33t := string(unl33t[i:j]); l33t != word {
+				if r, ok := dictionaryRank(l33t, userRanks); ok {
+					add(i, j, float64(r)*variations*l33tVariations(lower[i:j], unl33t[i:j]))
+				}
+			}
+
+			if j-i >= 3 {
+				if g, ok := keyboardGuesses(word); ok {
+					add(i, j, g*variations)
+				}
+			}
+
+			if j-i == 4 {
+				if year, err := strconv.Atoi(word); err == nil && year >= 1900 && year <= 2099 {
+					add(i, j, math.Max(math.Abs(float64(year-_referenceYear)), 20))
+				}
+			}
+		}
+	}
+
+	addRuns(lower, add)
+
+	return matches
+}
+
+func dictionaryRank(word string, userRanks map[string]int) (int, bool) {
+	if r, ok := userRanks[word]; ok && len(word) >= 3 {
+		return r, true
+	}
+
+	r, ok := commonRanks[word]
+
+	return r, ok
+}
+
+// addRuns adds the repeats of a character and the sequences like "abc" or "975".
+func addRuns(lower []rune, add func(start, end int, guesses float64)) {
+	n := len(lower)
+
+	for i := 0; i < n; {
+		j := i + 1
+		for j < n && lower[j] == lower[i] {
+			j++
+		}
+		if j-i >= 3 {
+			add(i, j, cardinality(lower[i])*float64(j-i))
+		}
+		i = j
+	}
+
+	for i := 0; i+2 < n; {
+		delta := lower[i+1] - lower[i]
+		j := i + 1
+		for j < n && lower[j]-lower[j-1] == delta && sameClass(lower[j], lower[i]) {
+			j++
+		}
+		if (delta == 1 || delta == -1) && j-i >= 3 {
+			base := cardinality(lower[i])
+			if strings.ContainsRune("az019", lower[i]) {
+				base = 4
+			}
+			if delta < 0 {
+				base *= 2
+			}
+			add(i, j, base*float64(j-i))
+		}
+		if j-1 > i {
+			i = j - 1
+		} else {
+			i++
+		}
+	}
+}
+
+func keyboardGuesses(word string) (float64, bool) {
+	for _, row := range keyboardRows {
+		if strings.Contains(row, word) {
+			return 40 * float64(len(word)), true
+		}
+		if strings.Contains(row, reverse(word)) {
+			return 80 * float64(len(word)), true
+		}
+	}
+
+	return 0, false
+}
+
+func cardinality(c rune) float64 {
+	switch {
+	case unicode.IsDigit(c):
+		return 10
+	case unicode.IsLetter(c):
+		return 26
+	default:
+		return 33
+	}
+}
+
+func sameClass(a, b rune) bool {
+	return unicode.IsDigit(a) == unicode.IsDigit(b) && unicode.IsLetter(a) == unicode.IsLetter(b)
+}
+
+// uppercaseVariations is how many ways the letters of the word could be cased
+// as likely as its casing.
+func uppercaseVariations(word []rune) float64 {
+	var upper, lower int
+	for _, c := range word {
+		if unicode.IsUpper(c) {
+			upper++
+		} else if unicode.IsLower(c) {
+			lower++
+		}
+	}
+
+	if upper == 0 {
+		return 1
+	}
+	// Capitalized, all caps and the last letter cased are the common casings.
+	if lower == 0 || (upper == 1 && (unicode.IsUpper(word[0]) || unicode.IsUpper(word[len(word)-1]))) {
+		return 2
+	}
+
+	return binomialSum(upper+lower, min(upper, lower))
+}
+
+// l33tVariations is how many ways the letters the word substitutes could be
+// substituted.
+func l33tVariations(lower, unl33t []rune) float64 {
+	substituted := make(map[rune]int)
+	for i := range lower {
+		if lower[i] != unl33t[i] {
+			substituted[unl33t[i]]++
+		}
+	}
+
+	variations := 1.0
+	for letter, s := range substituted {
+		var u int
+		for i := range lower {
+			if lower[i] == letter {
+				u++
+			}
+		}
+
+		if u == 0 {
+			variations *= 2
+		} else {
+			variations *= binomialSum(s+u, min(s, u))
+		}
+	}
+
+	return variations
+}
+
+// binomialSum sums the binomial coefficients of n over 1 to k.
+func binomialSum(n, k int) float64 {
+	var sum float64
+	for i := 1; i <= k; i++ {
+		sum += binomial(n, i)
+	}
+
+	return sum
+}
+
+func binomial(n, k int) float64 {
+	if k > n {
+		return 0
+	}
+
+	r := 1.0
+	for i := 1; i <= k; i++ {
+		r = r * float64(n-k+i) / float64(i)
+	}
+
+	return r
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+
+	return string(r)
+}
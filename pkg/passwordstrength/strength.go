@@ -0,0 +1,136 @@
+// Package passwordstrength estimates how many guesses a password takes to crack
+// and scores it from 0 to 4, after the approach of zxcvbn: the password is split
+// into the sequence of common words, keyboard and alphabet sequences, repeats,
+// years and brute forced characters that takes the fewest guesses.
+package passwordstrength
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+)
+
+const (
+	// _bruteforceCardinality is the guesses per brute forced character.
+	_bruteforceCardinality = 10
+	// _minGuessesBeforeGrowingSequence is the guesses of each part a password
+	// splits into past the first, so adding parts keeps increasing the guesses.
+	_minGuessesBeforeGrowingSequence = 10000
+)
+
+//go:embed common.txt
+var commonList string
+
+// commonRanks are the ranks of common passwords and words, the most common first.
+var commonRanks = ranks(strings.Fields(commonList))
+
+func ranks(words []string) map[string]int {
+	r := make(map[string]int, len(words))
+	for i, w := range words {
+		if _, ok := r[w]; !ok {
+			r[w] = i + 1
+		}
+	}
+
+	return r
+}
+
+// Guesses estimates how many guesses the password takes to crack. userInputs
+// are words the password is easier to guess with, like the name and email of
+// its user.
+func Guesses(password string, userInputs ...string) float64 {
+	chars := []rune(password)
+	n := len(chars)
+	if n == 0 {
+		return 1
+	}
+
+	var inputs []string
+	for _, in := range userInputs {
+		inputs = append(inputs, strings.Fields(strings.ToLower(in))...)
+	}
+	userRanks := ranks(inputs)
+
+	matches := findMatches(chars, userRanks)
+
+	// best[i][k] is the product of the guesses of the k parts best covering the
+	// first i characters.
+	best := make([][]float64, n+1)
+	for i := range best {
+		best[i] = make([]float64, n+1)
+		for k := range best[i] {
+			best[i][k] = math.Inf(1)
+		}
+	}
+	best[0][0] = 1
+
+	for i := 1; i <= n; i++ {
+		for j := 0; j < i; j++ {
+			guesses := bruteforceGuesses(i - j)
+			for _, m := range matches[j] {
+				if m.end == i && m.guesses < guesses {
+					guesses = m.guesses
+				}
+			}
+
+			for k := 1; k <= i; k++ {
+				if g := best[j][k-1] * guesses; g < best[i][k] {
+					best[i][k] = g
+				}
+			}
+		}
+	}
+
+	guesses := math.Inf(1)
+	for k := 1; k <= n; k++ {
+		if math.IsInf(best[n][k], 1) {
+			continue
+		}
+
+		g := factorial(k)*best[n][k] + math.Pow(_minGuessesBeforeGrowingSequence, float64(k-1))
+		if g < guesses {
+			guesses = g
+		}
+	}
+
+	return guesses
+}
+
+// Score rates the guesses of the password from 0, too guessable, to 4, very
+// unguessable, on the scale of zxcvbn.
+func Score(password string, userInputs ...string) int {
+	guesses := Guesses(password, userInputs...)
+
+	const delta = 5
+	switch {
+	case guesses < 1e3+delta:
+		return 0
+	case guesses < 1e6+delta:
+		return 1
+	case guesses < 1e8+delta:
+		return 2
+	case guesses < 1e10+delta:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func bruteforceGuesses(length int) float64 {
+	guesses := math.Pow(_bruteforceCardinality, float64(length))
+	// Single characters are more likely to be matched by other patterns.
+	if length == 1 {
+		return math.Max(guesses, 11)
+	}
+
+	return math.Max(guesses, 51)
+}
+
+func factorial(n int) float64 {
+	f := 1.0
+	for i := 2; i <= n; i++ {
+		f *= float64(i)
+	}
+
+	return f
+}
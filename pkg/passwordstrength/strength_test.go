@@ -0,0 +1,41 @@
+package passwordstrength_test
+
+import (
+	"testing"
+
+	"github.com/PanziApp/backend/pkg/passwordstrength"
+)
+
+func TestScore(t *testing.T) {
+	for _, tc := range []struct {
+		password   string
+		userInputs []string
+		score      int
+	}{
+		{"password", nil, 0},
+		{"Password1", nil, 0},
+		{"p@ssw0rd", nil, 0},
+		{"qwerty123", nil, 0},
+		{"aaaaaaaa", nil, 0},
+		{"abcdefgh", nil, 0},
+		{"2019", nil, 0},
+		{"jdoe1234", []string{"jdoe"}, 1},
+		{"secret-password", nil, 2},
+		{"correct horse battery staple", nil, 4},
+		{"xK9#mQ2vL7pZ", nil, 4},
+	} {
+		if score := passwordstrength.Score(tc.password, tc.userInputs...); score != tc.score {
+			t.Errorf("Score(%q): %d, want %d", tc.password, score, tc.score)
+		}
+	}
+}
+
+func TestUserInputs(t *testing.T) {
+	const password = "stavros1999"
+
+	without := passwordstrength.Guesses(password)
+	with := passwordstrength.Guesses(password, "Stavros Papadopoulos")
+	if with >= without {
+		t.Fatalf("guesses with the name %g, want fewer than %g", with, without)
+	}
+}
@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// Conn runs statements, on the pool or in a transaction.
+type Conn interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+type txContextKey struct{}
+
+// Conn returns the transaction ctx was given by InTx, or the pool outside of transactions.
+func (p *Postgres) Conn(ctx context.Context) Conn {
+	if tx, ok := ctx.Value(txContextKey{}).(pgx.Tx); ok {
+		return tx
+	}
+
+	return p.Pool
+}
+
+// InTx runs fn in a transaction, which the statements run on Conn with the
+// context passed to fn join. The transaction is committed when fn succeeds and
+// rolled back otherwise, the error of fn is returned as is. Nested calls join
+// the outer transaction.
+func (p *Postgres) InTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txContextKey{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("postgres - InTx - p.Pool.Begin: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if err = fn(context.WithValue(ctx, txContextKey{}, tx)); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("postgres - InTx - tx.Commit: %w", err)
+	}
+
+	return nil
+}
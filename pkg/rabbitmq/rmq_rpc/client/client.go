@@ -159,6 +159,10 @@ func (c *Client) RemoteCall(handler string, request, response interface{}) error
 		return rmqrpc.ErrInternalServer
 	}
 
+	if call.status == rmqrpc.ErrTooManyRequests.Error() {
+		return rmqrpc.ErrTooManyRequests
+	}
+
 	return nil
 }
 
@@ -9,6 +9,8 @@ var (
 	ErrInternalServer = errors.New("internal server error")
 	// ErrBadHandler -.
 	ErrBadHandler = errors.New("unregistered handler")
+	// ErrTooManyRequests is returned by call handlers denying calls over their rate limits.
+	ErrTooManyRequests = errors.New("too many requests")
 )
 
 // Success -.
@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -92,6 +93,13 @@ func (s *Server) serveCall(d *amqp.Delivery) {
 	}
 
 	response, err := callHandler(d)
+	if errors.Is(err, rmqrpc.ErrTooManyRequests) {
+		body, _ := json.Marshal(response)
+		s.publish(d, body, rmqrpc.ErrTooManyRequests.Error())
+
+		return
+	}
+
 	if err != nil {
 		s.publish(d, nil, rmqrpc.ErrInternalServer.Error())
 
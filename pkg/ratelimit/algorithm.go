@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// take counts a call at now in the state, and returns the new state with the
+// time it may be dropped after.
+func (p Policy) take(s State, now time.Time) (State, Result, time.Time) {
+	if p.Algorithm == TokenBucket {
+		return p.takeToken(s, now)
+	}
+
+	return p.takeSlidingWindow(s, now)
+}
+
+func (p Policy) takeToken(s State, now time.Time) (State, Result, time.Time) {
+	limit := float64(p.Limit)
+	perToken := p.Period / time.Duration(p.Limit)
+
+	// A new bucket is full.
+	tokens := limit
+	if !s.Time.IsZero() {
+		tokens = math.Min(limit, s.Value+float64(now.Sub(s.Time))/float64(perToken))
+	}
+
+	r := Result{Limit: p.Limit}
+	if tokens >= 1 {
+		tokens--
+		r.Allowed = true
+	} else {
+		r.RetryAfter = time.Duration((1 - tokens) * float64(perToken))
+	}
+
+	r.Remaining = int(tokens)
+	r.Reset = time.Duration((limit - tokens) * float64(perToken))
+
+	return State{Value: tokens, Time: now}, r, now.Add(r.Reset)
+}
+
+func (p Policy) takeSlidingWindow(s State, now time.Time) (State, Result, time.Time) {
+	limit := float64(p.Limit)
+
+	start := now.Truncate(p.Period)
+	switch {
+	case s.Time.Equal(start):
+	case s.Time.Equal(start.Add(-p.Period)):
+		s = State{Previous: s.Value}
+	default:
+		s = State{}
+	}
+	s.Time = start
+
+	end := start.Add(p.Period)
+	// weight of the previous window still covered by the sliding window
+	weight := float64(end.Sub(now)) / float64(p.Period)
+	calls := s.Previous*weight + s.Value
+
+	r := Result{Limit: p.Limit, Reset: end.Sub(now)}
+	if calls+1 <= limit {
+		s.Value++
+		calls++
+		r.Allowed = true
+	} else {
+		r.RetryAfter = p.slidingWindowRetryAfter(s, now)
+	}
+
+	r.Remaining = int(math.Max(0, limit-calls))
+
+	return s, r, end.Add(p.Period)
+}
+
+// slidingWindowRetryAfter returns how long until the weighted calls of the
+// state leave room for one more call.
+func (p Policy) slidingWindowRetryAfter(s State, now time.Time) time.Duration {
+	limit := float64(p.Limit)
+	end := s.Time.Add(p.Period)
+
+	// Room is made within the current window as the previous one slides out.
+	if s.Previous > 0 && s.Value+1 <= limit {
+		at := s.Time.Add(time.Duration((1 - (limit-s.Value-1)/s.Previous) * float64(p.Period)))
+		if at.After(now) {
+			return at.Sub(now)
+		}
+	}
+
+	// Otherwise the calls of the current window slide out in the next one.
+	wait := end.Sub(now)
+	if s.Value+1 > limit {
+		wait += time.Duration((1 - (limit-1)/s.Value) * float64(p.Period))
+	}
+
+	return wait
+}
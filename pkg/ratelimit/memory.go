@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// _memorySweepInterval is how many updates a memory store takes between
+// dropping the expired states.
+const _memorySweepInterval = 1000
+
+type memoryEntry struct {
+	state  State
+	expire time.Time
+}
+
+// MemoryStore keeps the states in memory, the limits then hold per replica.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	updates int
+}
+
+// NewMemoryStore -.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry)}
+}
+
+// Update -.
+func (s *MemoryStore) Update(_ context.Context, key string, update func(State) (State, time.Time)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	s.updates++
+	if s.updates%_memorySweepInterval == 0 {
+		for k, e := range s.entries {
+			if e.expire.Before(now) {
+				delete(s.entries, k)
+			}
+		}
+	}
+
+	e, ok := s.entries[key]
+	if !ok || e.expire.Before(now) {
+		e = memoryEntry{}
+	}
+
+	e.state, e.expire = update(e.state)
+	s.entries[key] = e
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PanziApp/backend/pkg/postgres"
+)
+
+// PostgresStore keeps the states in the rate_limits table, so the limits hold
+// across the replicas sharing the database.
+type PostgresStore struct {
+	pg *postgres.Postgres
+}
+
+// NewPostgresStore -.
+func NewPostgresStore(pg *postgres.Postgres) *PostgresStore {
+	return &PostgresStore{pg: pg}
+}
+
+// Update locks the row of the key while updating it.
+func (s *PostgresStore) Update(ctx context.Context, key string, update func(State) (State, time.Time)) (err error) {
+	tx, err := s.pg.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("ratelimit - PostgresStore - Update - s.pg.Pool.Begin: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	// The row is created first so concurrent calls of a new key wait on its lock.
+	sql, args, err := s.pg.Builder.
+		Insert("rate_limits").
+		Columns("key, value, previous, time, expire_time").
+		Values(key, 0, 0, time.Time{}, time.Time{}).
+		Suffix("ON CONFLICT (key) DO NOTHING").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("ratelimit - PostgresStore - Update - insert: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("ratelimit - PostgresStore - Update - tx.Exec: %w", err)
+	}
+
+	sql, args, err = s.pg.Builder.
+		Select("value, previous, time, expire_time").
+		From("rate_limits").
+		Where("key = ?", key).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("ratelimit - PostgresStore - Update - select: %w", err)
+	}
+
+	var (
+		state  State
+		expire time.Time
+	)
+	err = tx.QueryRow(ctx, sql, args...).Scan(&state.Value, &state.Previous, &state.Time, &expire)
+	if err != nil {
+		return fmt.Errorf("ratelimit - PostgresStore - Update - tx.QueryRow: %w", err)
+	}
+
+	if expire.Before(time.Now()) {
+		state = State{}
+	}
+
+	state, expire = update(state)
+
+	sql, args, err = s.pg.Builder.
+		Update("rate_limits").
+		Set("value", state.Value).
+		Set("previous", state.Previous).
+		Set("time", state.Time).
+		Set("expire_time", expire).
+		Where("key = ?", key).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("ratelimit - PostgresStore - Update - update: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("ratelimit - PostgresStore - Update - tx.Exec: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("ratelimit - PostgresStore - Update - tx.Commit: %w", err)
+	}
+
+	return nil
+}
+
+// Prune deletes the expired states and returns how many were deleted.
+func (s *PostgresStore) Prune(ctx context.Context) (int, error) {
+	sql, args, err := s.pg.Builder.
+		Delete("rate_limits").
+		Where("expire_time < ?", time.Now()).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("ratelimit - PostgresStore - Prune - delete: %w", err)
+	}
+
+	tag, err := s.pg.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("ratelimit - PostgresStore - Prune - s.pg.Pool.Exec: %w", err)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
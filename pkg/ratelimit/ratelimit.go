@@ -0,0 +1,117 @@
+// Package ratelimit implements rate limiting with token bucket and sliding
+// window algorithms, over stores that may be shared by several replicas.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Algorithm -.
+type Algorithm string
+
+const (
+	// TokenBucket allows bursts of Limit calls, refilling Limit tokens evenly
+	// over the Period.
+	TokenBucket Algorithm = "token_bucket"
+	// SlidingWindow allows Limit calls in any Period, weighting the calls of the
+	// previous fixed window by how much of it the sliding window still covers.
+	SlidingWindow Algorithm = "sliding_window"
+)
+
+// Policy allows Limit calls per Period by the Algorithm.
+type Policy struct {
+	Algorithm Algorithm
+	Limit     int
+	Period    time.Duration
+}
+
+// State is what the algorithms keep per key between calls.
+type State struct {
+	// Value is the tokens left in the bucket or the calls in the current window.
+	Value float64
+	// Previous is the calls in the previous window.
+	Previous float64
+	// Time is when the bucket was last refilled or the current window started.
+	Time time.Time
+}
+
+// Store keeps the states of the keys.
+type Store interface {
+	// Update replaces the state of the key with the one update returns, atomically
+	// with regard to other updates of the key. Update is given the zero State for
+	// a new key, and the state may be dropped after the time it returns.
+	Update(ctx context.Context, key string, update func(State) (State, time.Time)) error
+}
+
+// Result of a call.
+type Result struct {
+	Allowed bool
+	Limit   int
+	// Remaining is how many more calls are allowed right away.
+	Remaining int
+	// Reset is how long until the limit is fully available again.
+	Reset time.Duration
+	// RetryAfter is how long until a call is allowed again, when it was not.
+	RetryAfter time.Duration
+}
+
+// Limiter limits the calls per key by a policy.
+type Limiter struct {
+	name   string
+	policy Policy
+	store  Store
+}
+
+// New returns a limiter of the policy keeping its states in the store, the
+// name tells its keys apart from the ones of other limiters in the store.
+func New(name string, policy Policy, store Store) (*Limiter, error) {
+	if policy.Algorithm != TokenBucket && policy.Algorithm != SlidingWindow {
+		return nil, fmt.Errorf("ratelimit - New - unknown algorithm %q", policy.Algorithm)
+	}
+
+	if policy.Limit <= 0 || policy.Period <= 0 {
+		return nil, fmt.Errorf("ratelimit - New - limit and period of %q must be positive", name)
+	}
+
+	return &Limiter{name: name, policy: policy, store: store}, nil
+}
+
+// Allow counts a call by the key and returns whether it is allowed.
+func (l *Limiter) Allow(ctx context.Context, key string) (r Result, err error) {
+	now := time.Now()
+
+	err = l.store.Update(ctx, l.name+":"+key, func(s State) (State, time.Time) {
+		var expire time.Time
+		s, r, expire = l.policy.take(s, now)
+
+		return s, expire
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("ratelimit - Limiter - Allow - l.store.Update: %w", err)
+	}
+
+	return r, nil
+}
+
+// Strictest returns the denied result retrying the latest, or when all are
+// allowed the one with the fewest remaining calls. Results of no limit, with a
+// zero Limit, are skipped.
+func Strictest(results ...Result) (strictest Result) {
+	for _, r := range results {
+		switch {
+		case r.Limit == 0:
+		case strictest.Limit == 0:
+			strictest = r
+		case !r.Allowed:
+			if strictest.Allowed || r.RetryAfter > strictest.RetryAfter {
+				strictest = r
+			}
+		case strictest.Allowed && r.Remaining < strictest.Remaining:
+			strictest = r
+		}
+	}
+
+	return strictest
+}
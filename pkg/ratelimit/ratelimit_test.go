@@ -0,0 +1,171 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC)
+
+// takeAll counts calls of the policy at the offsets from start, and returns
+// their results.
+func takeAll(p Policy, offsets ...time.Duration) []Result {
+	var (
+		s       State
+		results []Result
+	)
+	for _, offset := range offsets {
+		var r Result
+		s, r, _ = p.take(s, start.Add(offset))
+		results = append(results, r)
+	}
+
+	return results
+}
+
+func TestTokenBucket(t *testing.T) {
+	p := Policy{Algorithm: TokenBucket, Limit: 3, Period: 3 * time.Minute}
+
+	results := takeAll(p, 0, 0, 0, 0, 30*time.Second, time.Minute, time.Minute)
+
+	for i, allowed := range []bool{true, true, true, false, false, true, false} {
+		if results[i].Allowed != allowed {
+			t.Fatalf("call %d: allowed %v, want %v", i, results[i].Allowed, allowed)
+		}
+	}
+
+	if r := results[2]; r.Remaining != 0 || r.Reset != 3*time.Minute {
+		t.Fatalf("burst: remaining %d reset %v, want 0 and 3m", r.Remaining, r.Reset)
+	}
+
+	if r := results[3]; r.RetryAfter != time.Minute {
+		t.Fatalf("empty bucket: retry after %v, want 1m", r.RetryAfter)
+	}
+
+	if r := results[4]; r.RetryAfter != 30*time.Second {
+		t.Fatalf("half refilled: retry after %v, want 30s", r.RetryAfter)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	p := Policy{Algorithm: SlidingWindow, Limit: 4, Period: time.Minute}
+
+	// Four calls fill the first window, a quarter into the next one the previous
+	// window still weighs three calls.
+	results := takeAll(p, 0, time.Second, 2*time.Second, 3*time.Second, 4*time.Second,
+		75*time.Second, 76*time.Second, 3*time.Minute)
+
+	for i, allowed := range []bool{true, true, true, true, false, true, false, true} {
+		if results[i].Allowed != allowed {
+			t.Fatalf("call %d: allowed %v, want %v", i, results[i].Allowed, allowed)
+		}
+	}
+
+	if r := results[4]; r.RetryAfter != 71*time.Second {
+		t.Fatalf("full window: retry after %v, want 1m11s", r.RetryAfter)
+	}
+
+	// 4*(1-x) + 1 + 1 <= 4 from half into the second window.
+	if r := results[6]; r.RetryAfter != 14*time.Second {
+		t.Fatalf("sliding: retry after %v, want 14s", r.RetryAfter)
+	}
+
+	if r := results[7]; r.Remaining != 3 {
+		t.Fatalf("after idle windows: remaining %d, want 3", r.Remaining)
+	}
+}
+
+func TestLimiterKeys(t *testing.T) {
+	store := NewMemoryStore()
+	p := Policy{Algorithm: SlidingWindow, Limit: 1, Period: time.Hour}
+
+	signIn, err := New("sign-in", p, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signUp, err := New("sign-up", p, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for _, call := range []struct {
+		limiter *Limiter
+		key     string
+		allowed bool
+	}{
+		{signIn, "1.2.3.4", true},
+		{signIn, "1.2.3.4", false},
+		{signIn, "5.6.7.8", true},
+		{signUp, "1.2.3.4", true},
+	} {
+		r, err := call.limiter.Allow(ctx, call.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Allowed != call.allowed {
+			t.Fatalf("%s by %s: allowed %v, want %v", call.limiter.name, call.key, r.Allowed, call.allowed)
+		}
+	}
+}
+
+// keyStore records the keys it is updated with.
+type keyStore struct {
+	*MemoryStore
+	keys []string
+}
+
+func (s *keyStore) Update(ctx context.Context, key string, update func(State) (State, time.Time)) error {
+	s.keys = append(s.keys, key)
+
+	return s.MemoryStore.Update(ctx, key, update)
+}
+
+func TestRoutesHashKeys(t *testing.T) {
+	store := &keyStore{MemoryStore: NewMemoryStore()}
+	limiter, err := New("sign-in", Policy{Algorithm: SlidingWindow, Limit: 1, Period: time.Hour}, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := Routes{}
+	if err = routes.Add("POST /v1/sign-in", Rule{Key: KeyEmail, Limiter: limiter}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	email := strings.Repeat("a", 1000) + "@example.com"
+	for _, allowed := range []bool{true, false} {
+		r, err := routes.Check(ctx, "POST /v1/sign-in", func(string) string { return email })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Allowed != allowed {
+			t.Fatalf("allowed %v, want %v", r.Allowed, allowed)
+		}
+	}
+
+	// sign-in:email: and the hex SHA-256 of the email.
+	if key := store.keys[0]; len(key) != len("sign-in:email:")+64 || strings.Contains(key, "example.com") {
+		t.Fatalf("key %q is not hashed", key)
+	}
+}
+
+func TestStrictest(t *testing.T) {
+	allowed := Result{Allowed: true, Limit: 10, Remaining: 5}
+	fewer := Result{Allowed: true, Limit: 3, Remaining: 1}
+	denied := Result{Limit: 5, RetryAfter: time.Second}
+	longer := Result{Limit: 5, RetryAfter: time.Minute}
+
+	if r := Strictest(Result{}, allowed, fewer); r != fewer {
+		t.Fatalf("allowed: got %+v, want %+v", r, fewer)
+	}
+	if r := Strictest(denied, allowed, longer, fewer); r != longer {
+		t.Fatalf("denied: got %+v, want %+v", r, longer)
+	}
+	if r := Strictest(); r.Limit != 0 {
+		t.Fatalf("none: got %+v", r)
+	}
+}
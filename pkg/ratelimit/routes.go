@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// Key kinds the calls of a route are limited by.
+const (
+	KeyIP     = "ip"
+	KeyEmail  = "email"
+	KeyUser   = "user"
+	KeyAPIKey = "api_key"
+)
+
+// Rule limits the calls of a route by the value of a key kind.
+type Rule struct {
+	Key     string
+	Limiter *Limiter
+}
+
+// Routes are the rules of each route.
+type Routes map[string][]Rule
+
+// Add -.
+func (rs Routes) Add(route string, rule Rule) error {
+	switch rule.Key {
+	case KeyIP, KeyEmail, KeyUser, KeyAPIKey:
+	default:
+		return fmt.Errorf("ratelimit - Routes - Add - unknown key %q of route %q", rule.Key, route)
+	}
+
+	rs[route] = append(rs[route], rule)
+
+	return nil
+}
+
+// Check counts a call of the route by its rules and returns the strictest
+// result. keyOf returns the value of a key kind for the call, the rules of the
+// kinds it returns empty are skipped. The values come from the callers, they
+// are hashed to keep the keys short and free of personal data.
+func (rs Routes) Check(ctx context.Context, route string, keyOf func(kind string) string) (Result, error) {
+	results := make([]Result, 0, len(rs[route]))
+	for _, rule := range rs[route] {
+		key := keyOf(rule.Key)
+		if key == "" {
+			continue
+		}
+
+		sum := sha256.Sum256([]byte(key))
+		r, err := rule.Limiter.Allow(ctx, rule.Key+":"+hex.EncodeToString(sum[:]))
+		if err != nil {
+			return Result{}, err
+		}
+		results = append(results, r)
+	}
+
+	return Strictest(results...), nil
+}
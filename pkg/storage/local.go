@@ -0,0 +1,65 @@
+// Package storage implements file storages.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Local stores files in a directory of the local file system.
+type Local struct {
+	root string
+}
+
+// NewLocal -.
+func NewLocal(root string) (Local, error) {
+	err := os.MkdirAll(root, 0o750)
+	if err != nil {
+		return Local{}, fmt.Errorf("storage - NewLocal - os.MkdirAll: %w", err)
+	}
+
+	return Local{root: root}, nil
+}
+
+func (s Local) path(name string) string {
+	return filepath.Join(s.root, filepath.Base(name))
+}
+
+// Save -.
+func (s Local) Save(ctx context.Context, name string, content io.Reader) error {
+	f, err := os.Create(s.path(name))
+	if err != nil {
+		return fmt.Errorf("storage - Local - Save - os.Create: %w", err)
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, content)
+	if err != nil {
+		return fmt.Errorf("storage - Local - Save - io.Copy: %w", err)
+	}
+
+	return nil
+}
+
+// Open -.
+func (s Local) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	f, err := os.Open(s.path(name))
+	if err != nil {
+		return nil, fmt.Errorf("storage - Local - Open - os.Open: %w", err)
+	}
+
+	return f, nil
+}
+
+// Remove -.
+func (s Local) Remove(ctx context.Context, name string) error {
+	err := os.Remove(s.path(name))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("storage - Local - Remove - os.Remove: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Authenticator data flags.
+const (
+	FlagUserPresent        = 0x01
+	FlagUserVerified       = 0x04
+	FlagBackupEligible     = 0x08
+	FlagBackupState        = 0x10
+	FlagAttestedCredential = 0x40
+	FlagExtensionData      = 0x80
+)
+
+const _authenticatorDataMinLength = 37
+
+// authenticatorData is the binary structure signed by authenticators,
+// https://www.w3.org/TR/webauthn-2/#sctn-authenticator-data.
+type authenticatorData struct {
+	raw                 []byte
+	rpIdHash            []byte
+	flags               byte
+	signCount           uint32
+	credentialId        []byte
+	credentialPublicKey []byte
+}
+
+func parseAuthenticatorData(raw []byte) (authenticatorData, error) {
+	if len(raw) < _authenticatorDataMinLength {
+		return authenticatorData{}, ErrInvalidAuthenticatorData
+	}
+
+	d := authenticatorData{
+		raw:       raw,
+		rpIdHash:  raw[:32],
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+
+	if d.flags&FlagAttestedCredential == 0 {
+		return d, nil
+	}
+
+	// AAGUID (16 bytes), credential id length (2 bytes), credential id and public key.
+	rest := raw[_authenticatorDataMinLength:]
+	if len(rest) < 18 {
+		return authenticatorData{}, ErrInvalidAuthenticatorData
+	}
+
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLength {
+		return authenticatorData{}, ErrInvalidAuthenticatorData
+	}
+
+	d.credentialId = rest[:idLength]
+	rest = rest[idLength:]
+
+	// The public key is followed by the extensions when there are some,
+	// decode a single CBOR item to find where it ends.
+	var publicKey cbor.RawMessage
+	if err := cbor.NewDecoder(bytes.NewReader(rest)).Decode(&publicKey); err != nil {
+		return authenticatorData{}, ErrInvalidAuthenticatorData
+	}
+
+	d.credentialPublicKey = publicKey
+
+	return d, nil
+}
+
+func (d authenticatorData) has(flag byte) bool {
+	return d.flags&flag != 0
+}
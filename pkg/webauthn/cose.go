@@ -0,0 +1,149 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"math/big"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// COSE algorithm identifiers, https://www.iana.org/assignments/cose.
+const (
+	ES256 = -7
+	EdDSA = -8
+	RS256 = -257
+)
+
+// COSE key parameters.
+const (
+	_coseKty = 1
+	_coseAlg = 3
+
+	_coseKtyOKP = 1
+	_coseKtyEC2 = 2
+	_coseKtyRSA = 3
+
+	_coseCrvP256    = 1
+	_coseCrvEd25519 = 6
+)
+
+// _supportedAlgorithms are offered to authenticators in order of preference.
+var _supportedAlgorithms = []int{ES256, EdDSA, RS256}
+
+// publicKey is a credential public key decoded from its COSE_Key encoding.
+type publicKey struct {
+	algorithm int
+	key       crypto.PublicKey
+}
+
+func parsePublicKey(coseKey []byte) (publicKey, error) {
+	var params map[int]cbor.RawMessage
+	if err := cbor.Unmarshal(coseKey, &params); err != nil {
+		return publicKey{}, ErrInvalidPublicKey
+	}
+
+	var kty, alg int
+	if err := unmarshalParam(params, _coseKty, &kty); err != nil {
+		return publicKey{}, err
+	}
+	if err := unmarshalParam(params, _coseAlg, &alg); err != nil {
+		return publicKey{}, err
+	}
+
+	switch {
+	case kty == _coseKtyEC2 && alg == ES256:
+		var crv int
+		var x, y []byte
+		if err := unmarshalParams(params, map[int]interface{}{-1: &crv, -2: &x, -3: &y}); err != nil {
+			return publicKey{}, err
+		}
+
+		curve := elliptic.P256()
+		if crv != _coseCrvP256 || len(x) != 32 || len(y) != 32 {
+			return publicKey{}, ErrInvalidPublicKey
+		}
+
+		k := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(k.X, k.Y) {
+			return publicKey{}, ErrInvalidPublicKey
+		}
+
+		return publicKey{algorithm: alg, key: k}, nil
+	case kty == _coseKtyOKP && alg == EdDSA:
+		var crv int
+		var x []byte
+		if err := unmarshalParams(params, map[int]interface{}{-1: &crv, -2: &x}); err != nil {
+			return publicKey{}, err
+		}
+
+		if crv != _coseCrvEd25519 || len(x) != ed25519.PublicKeySize {
+			return publicKey{}, ErrInvalidPublicKey
+		}
+
+		return publicKey{algorithm: alg, key: ed25519.PublicKey(x)}, nil
+	case kty == _coseKtyRSA && alg == RS256:
+		var n, e []byte
+		if err := unmarshalParams(params, map[int]interface{}{-1: &n, -2: &e}); err != nil {
+			return publicKey{}, err
+		}
+
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) < 256 || !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return publicKey{}, ErrInvalidPublicKey
+		}
+
+		return publicKey{algorithm: alg, key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}}, nil
+	default:
+		return publicKey{}, ErrUnsupportedAlgorithm
+	}
+}
+
+func unmarshalParam(params map[int]cbor.RawMessage, label int, v interface{}) error {
+	raw, ok := params[label]
+	if !ok {
+		return ErrInvalidPublicKey
+	}
+
+	if err := cbor.Unmarshal(raw, v); err != nil {
+		return ErrInvalidPublicKey
+	}
+
+	return nil
+}
+
+func unmarshalParams(params map[int]cbor.RawMessage, values map[int]interface{}) error {
+	for label, v := range values {
+		if err := unmarshalParam(params, label, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verify checks the signature over data, ECDSA signatures are ASN.1 DER encoded.
+func (k publicKey) verify(data, signature []byte) error {
+	var ok bool
+
+	switch pk := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		ok = ecdsa.VerifyASN1(pk, digest[:], signature)
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(pk, data, signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		ok = rsa.VerifyPKCS1v15(pk, crypto.SHA256, digest[:], signature) == nil
+	}
+
+	if !ok {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
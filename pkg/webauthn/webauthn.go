@@ -0,0 +1,398 @@
+// Package webauthn implements the relying party side of the WebAuthn
+// registration and authentication ceremonies, https://www.w3.org/TR/webauthn-2/.
+// Only the "none" and self "packed" attestation formats are accepted,
+// the relying party asks authenticators not to attest.
+package webauthn
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	_publicKeyCredentialType = "public-key"
+	_defaultTimeout          = 5 * time.Minute
+
+	_clientDataTypeCreate = "webauthn.create"
+	_clientDataTypeGet    = "webauthn.get"
+)
+
+var (
+	// ErrUnsupportedAlgorithm -.
+	ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+	// ErrUnsupportedAttestation -.
+	ErrUnsupportedAttestation = errors.New("unsupported attestation")
+	// ErrInvalidPublicKey -.
+	ErrInvalidPublicKey = errors.New("invalid public key")
+	// ErrInvalidSignature -.
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrInvalidClientData -.
+	ErrInvalidClientData = errors.New("invalid client data")
+	// ErrInvalidAuthenticatorData -.
+	ErrInvalidAuthenticatorData = errors.New("invalid authenticator data")
+	// ErrInvalidCredential is returned when the response is not for the expected credential.
+	ErrInvalidCredential = errors.New("invalid credential")
+	// ErrUserNotVerified is returned when the authenticator did not verify the user.
+	ErrUserNotVerified = errors.New("user not verified")
+	// ErrSignCount is returned when the signature counter of the authenticator did not
+	// increase, a sign that the credential was cloned.
+	ErrSignCount = errors.New("signature counter did not increase")
+)
+
+// RelyingParty verifies the credentials created for, and the assertions made to,
+// the relying party id by the clients of the origins.
+type RelyingParty struct {
+	Id      string
+	Name    string
+	Origins []string
+	Timeout time.Duration
+}
+
+// New -.
+func New(id, name string, origins []string) *RelyingParty {
+	return &RelyingParty{
+		Id:      id,
+		Name:    name,
+		Origins: origins,
+		Timeout: _defaultTimeout,
+	}
+}
+
+// User is the account a credential is registered for, the id must not contain
+// personal information.
+type User struct {
+	Id          []byte
+	Name        string
+	DisplayName string
+}
+
+// Credential is a registered public key credential.
+type Credential struct {
+	Id         []byte
+	PublicKey  []byte
+	SignCount  uint32
+	Transports []string
+}
+
+// CredentialDescriptor identifies a credential in the options.
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	Id         Bytes    `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// NewCredentialDescriptor -.
+func NewCredentialDescriptor(id []byte, transports []string) CredentialDescriptor {
+	return CredentialDescriptor{Type: _publicKeyCredentialType, Id: id, Transports: transports}
+}
+
+type (
+	rpEntity struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	}
+
+	userEntity struct {
+		Id          Bytes  `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	}
+
+	credentialParameters struct {
+		Type      string `json:"type"`
+		Algorithm int    `json:"alg"`
+	}
+
+	authenticatorSelection struct {
+		ResidentKey        string `json:"residentKey"`
+		RequireResidentKey bool   `json:"requireResidentKey"`
+		UserVerification   string `json:"userVerification"`
+	}
+)
+
+// CreationOptions are the PublicKeyCredentialCreationOptions to pass to navigator.credentials.create.
+type CreationOptions struct {
+	Challenge              Bytes                  `json:"challenge"`
+	RP                     rpEntity               `json:"rp"`
+	User                   userEntity             `json:"user"`
+	PubKeyCredParams       []credentialParameters `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection authenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are the PublicKeyCredentialRequestOptions to pass to navigator.credentials.get.
+type RequestOptions struct {
+	Challenge        Bytes                  `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPId             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// AttestationResponse is the PublicKeyCredential returned by navigator.credentials.create.
+type AttestationResponse struct {
+	Id       string `json:"id"`
+	RawId    Bytes  `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    Bytes    `json:"clientDataJSON"`
+		AttestationObject Bytes    `json:"attestationObject"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the PublicKeyCredential returned by navigator.credentials.get.
+type AssertionResponse struct {
+	Id       string `json:"id"`
+	RawId    Bytes  `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    Bytes `json:"clientDataJSON"`
+		AuthenticatorData Bytes `json:"authenticatorData"`
+		Signature         Bytes `json:"signature"`
+		UserHandle        Bytes `json:"userHandle"`
+	} `json:"response"`
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   Bytes  `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+type attestationObject struct {
+	Format    string                     `cbor:"fmt"`
+	Statement map[string]cbor.RawMessage `cbor:"attStmt"`
+	AuthData  []byte                     `cbor:"authData"`
+}
+
+// CreationOptions returns the options of a registration ceremony, the excluded
+// credentials are the ones the user already registered.
+func (rp *RelyingParty) CreationOptions(challenge []byte, user User, exclude []CredentialDescriptor) CreationOptions {
+	params := make([]credentialParameters, 0, len(_supportedAlgorithms))
+	for _, alg := range _supportedAlgorithms {
+		params = append(params, credentialParameters{Type: _publicKeyCredentialType, Algorithm: alg})
+	}
+
+	if exclude == nil {
+		exclude = []CredentialDescriptor{}
+	}
+
+	return CreationOptions{
+		Challenge:          challenge,
+		RP:                 rpEntity{Id: rp.Id, Name: rp.Name},
+		User:               userEntity{Id: user.Id, Name: user.Name, DisplayName: user.DisplayName},
+		PubKeyCredParams:   params,
+		Timeout:            rp.Timeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: authenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: "required",
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions returns the options of an authentication ceremony, without allowed
+// credentials the client offers the discoverable credentials of the relying party.
+func (rp *RelyingParty) RequestOptions(challenge []byte, allow []CredentialDescriptor) RequestOptions {
+	if allow == nil {
+		allow = []CredentialDescriptor{}
+	}
+
+	return RequestOptions{
+		Challenge:        challenge,
+		Timeout:          rp.Timeout.Milliseconds(),
+		RPId:             rp.Id,
+		AllowCredentials: allow,
+		UserVerification: "required",
+	}
+}
+
+// Challenge returns the challenge the client signed, to look up the ceremony it answers.
+func (r AttestationResponse) Challenge() ([]byte, error) {
+	c, err := parseClientData(r.Response.ClientDataJSON)
+	return c.Challenge, err
+}
+
+// Challenge returns the challenge the client signed, to look up the ceremony it answers.
+func (r AssertionResponse) Challenge() ([]byte, error) {
+	c, err := parseClientData(r.Response.ClientDataJSON)
+	return c.Challenge, err
+}
+
+// VerifyRegistration verifies the response of a registration ceremony started
+// with the challenge and returns the new credential.
+func (rp *RelyingParty) VerifyRegistration(challenge []byte, r AttestationResponse) (Credential, error) {
+	if r.Type != _publicKeyCredentialType {
+		return Credential{}, ErrInvalidCredential
+	}
+
+	err := rp.verifyClientData(r.Response.ClientDataJSON, _clientDataTypeCreate, challenge)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	var att attestationObject
+	if err = cbor.Unmarshal(r.Response.AttestationObject, &att); err != nil {
+		return Credential{}, ErrInvalidAuthenticatorData
+	}
+
+	authData, err := parseAuthenticatorData(att.AuthData)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	if err = rp.verifyAuthenticatorData(authData); err != nil {
+		return Credential{}, err
+	}
+
+	if !authData.has(FlagAttestedCredential) || len(authData.credentialId) == 0 {
+		return Credential{}, ErrInvalidAuthenticatorData
+	}
+
+	if subtle.ConstantTimeCompare(authData.credentialId, r.RawId) != 1 {
+		return Credential{}, ErrInvalidCredential
+	}
+
+	pk, err := parsePublicKey(authData.credentialPublicKey)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	if err = verifyAttestation(att, pk, r.Response.ClientDataJSON); err != nil {
+		return Credential{}, err
+	}
+
+	return Credential{
+		Id:         authData.credentialId,
+		PublicKey:  authData.credentialPublicKey,
+		SignCount:  authData.signCount,
+		Transports: r.Response.Transports,
+	}, nil
+}
+
+// VerifyAssertion verifies the response of an authentication ceremony started with
+// the challenge, made with the credential, and returns the new signature counter.
+func (rp *RelyingParty) VerifyAssertion(challenge []byte, r AssertionResponse, c Credential) (uint32, error) {
+	if r.Type != _publicKeyCredentialType || subtle.ConstantTimeCompare(c.Id, r.RawId) != 1 {
+		return 0, ErrInvalidCredential
+	}
+
+	err := rp.verifyClientData(r.Response.ClientDataJSON, _clientDataTypeGet, challenge)
+	if err != nil {
+		return 0, err
+	}
+
+	authData, err := parseAuthenticatorData(r.Response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = rp.verifyAuthenticatorData(authData); err != nil {
+		return 0, err
+	}
+
+	pk, err := parsePublicKey(c.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = pk.verify(signedData(authData.raw, r.Response.ClientDataJSON), r.Response.Signature); err != nil {
+		return 0, err
+	}
+
+	// Authenticators that do not count signatures always report zero.
+	if (authData.signCount != 0 || c.SignCount != 0) && authData.signCount <= c.SignCount {
+		return 0, ErrSignCount
+	}
+
+	return authData.signCount, nil
+}
+
+func parseClientData(raw []byte) (clientData, error) {
+	var c clientData
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return clientData{}, ErrInvalidClientData
+	}
+
+	return c, nil
+}
+
+func (rp *RelyingParty) verifyClientData(raw []byte, typ string, challenge []byte) error {
+	c, err := parseClientData(raw)
+	if err != nil {
+		return err
+	}
+
+	if c.Type != typ || c.CrossOrigin || subtle.ConstantTimeCompare(c.Challenge, challenge) != 1 {
+		return ErrInvalidClientData
+	}
+
+	for _, origin := range rp.Origins {
+		if c.Origin == origin {
+			return nil
+		}
+	}
+
+	return ErrInvalidClientData
+}
+
+func (rp *RelyingParty) verifyAuthenticatorData(d authenticatorData) error {
+	rpIdHash := sha256.Sum256([]byte(rp.Id))
+	if subtle.ConstantTimeCompare(d.rpIdHash, rpIdHash[:]) != 1 {
+		return ErrInvalidAuthenticatorData
+	}
+
+	if !d.has(FlagUserPresent) || !d.has(FlagUserVerified) {
+		return ErrUserNotVerified
+	}
+
+	return nil
+}
+
+// verifyAttestation accepts no attestation, or a "packed" self attestation signed
+// by the credential key itself.
+func verifyAttestation(att attestationObject, pk publicKey, clientDataJSON []byte) error {
+	switch att.Format {
+	case "none":
+		if len(att.Statement) != 0 {
+			return ErrUnsupportedAttestation
+		}
+
+		return nil
+	case "packed":
+		if _, ok := att.Statement["x5c"]; ok {
+			return ErrUnsupportedAttestation
+		}
+
+		var alg int
+		var sig []byte
+		if cbor.Unmarshal(att.Statement["alg"], &alg) != nil || cbor.Unmarshal(att.Statement["sig"], &sig) != nil {
+			return ErrUnsupportedAttestation
+		}
+
+		if alg != pk.algorithm {
+			return ErrUnsupportedAttestation
+		}
+
+		return pk.verify(signedData(att.AuthData, clientDataJSON), sig)
+	default:
+		return ErrUnsupportedAttestation
+	}
+}
+
+// signedData is what authenticators sign, the authenticator data followed by the client data hash.
+func signedData(authData, clientDataJSON []byte) []byte {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+
+	return append(append([]byte{}, authData...), clientDataHash[:]...)
+}
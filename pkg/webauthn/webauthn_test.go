@@ -0,0 +1,159 @@
+package webauthn_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/PanziApp/backend/pkg/webauthn"
+	"github.com/PanziApp/backend/pkg/webauthn/webauthntest"
+)
+
+const (
+	rpId   = "localhost"
+	origin = "http://localhost:8080"
+)
+
+var user = webauthn.User{Id: []byte("user-1"), Name: "user@example.com", DisplayName: "John Doe"}
+
+func register(t *testing.T, rp *webauthn.RelyingParty, a *webauthntest.Authenticator) webauthn.Credential {
+	t.Helper()
+
+	challenge := []byte("registration-challenge")
+
+	r, err := a.Create(rp.CreationOptions(challenge, user, nil))
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	c, err := rp.VerifyRegistration(challenge, r)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+
+	return c
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	rp := webauthn.New(rpId, "Panzi", []string{origin})
+	a := webauthntest.NewAuthenticator(origin)
+
+	c := register(t, rp, a)
+	if c.SignCount != 0 {
+		t.Fatalf("SignCount = %d, want 0", c.SignCount)
+	}
+
+	for i := 1; i <= 2; i++ {
+		challenge := []byte("assertion-challenge")
+
+		r, err := a.Get(rp.RequestOptions(challenge, nil))
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+
+		// Responses go through JSON between the client and the server.
+		data, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded webauthn.AssertionResponse
+		if err = json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := decoded.Challenge()
+		if err != nil || string(got) != string(challenge) {
+			t.Fatalf("Challenge() = %q, %v", got, err)
+		}
+
+		c.SignCount, err = rp.VerifyAssertion(challenge, decoded, c)
+		if err != nil {
+			t.Fatalf("VerifyAssertion: %v", err)
+		}
+		if c.SignCount != uint32(i) {
+			t.Fatalf("SignCount = %d, want %d", c.SignCount, i)
+		}
+	}
+}
+
+func TestRegistrationRejectsOtherOriginAndChallenge(t *testing.T) {
+	rp := webauthn.New(rpId, "Panzi", []string{origin})
+
+	r, err := webauthntest.NewAuthenticator("https://evil.example").Create(rp.CreationOptions([]byte("challenge"), user, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = rp.VerifyRegistration([]byte("challenge"), r); !errors.Is(err, webauthn.ErrInvalidClientData) {
+		t.Fatalf("other origin: err = %v, want %v", err, webauthn.ErrInvalidClientData)
+	}
+
+	r, err = webauthntest.NewAuthenticator(origin).Create(rp.CreationOptions([]byte("challenge"), user, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = rp.VerifyRegistration([]byte("other-challenge"), r); !errors.Is(err, webauthn.ErrInvalidClientData) {
+		t.Fatalf("other challenge: err = %v, want %v", err, webauthn.ErrInvalidClientData)
+	}
+
+	other := webauthn.New("example.com", "Example", []string{origin})
+	if _, err = other.VerifyRegistration([]byte("challenge"), r); !errors.Is(err, webauthn.ErrInvalidAuthenticatorData) {
+		t.Fatalf("other relying party: err = %v, want %v", err, webauthn.ErrInvalidAuthenticatorData)
+	}
+}
+
+func TestRegistrationExcludesCredentials(t *testing.T) {
+	rp := webauthn.New(rpId, "Panzi", []string{origin})
+	a := webauthntest.NewAuthenticator(origin)
+	c := register(t, rp, a)
+
+	exclude := []webauthn.CredentialDescriptor{webauthn.NewCredentialDescriptor(c.Id, c.Transports)}
+	if _, err := a.Create(rp.CreationOptions([]byte("challenge"), user, exclude)); !errors.Is(err, webauthntest.ErrCredentialExcluded) {
+		t.Fatalf("err = %v, want %v", err, webauthntest.ErrCredentialExcluded)
+	}
+}
+
+func TestAssertionRejectsTamperedSignature(t *testing.T) {
+	rp := webauthn.New(rpId, "Panzi", []string{origin})
+	a := webauthntest.NewAuthenticator(origin)
+	c := register(t, rp, a)
+
+	r, err := a.Get(rp.RequestOptions([]byte("challenge"), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Response.AuthenticatorData[len(r.Response.AuthenticatorData)-1]++
+
+	if _, err = rp.VerifyAssertion([]byte("challenge"), r, c); !errors.Is(err, webauthn.ErrInvalidSignature) {
+		t.Fatalf("err = %v, want %v", err, webauthn.ErrInvalidSignature)
+	}
+}
+
+func TestAssertionDetectsClonedAuthenticator(t *testing.T) {
+	rp := webauthn.New(rpId, "Panzi", []string{origin})
+	a := webauthntest.NewAuthenticator(origin)
+	c := register(t, rp, a)
+	clone := a.Clone()
+
+	r, err := a.Get(rp.RequestOptions([]byte("challenge"), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.SignCount, err = rp.VerifyAssertion([]byte("challenge"), r, c)
+	if err != nil {
+		t.Fatalf("VerifyAssertion: %v", err)
+	}
+
+	r, err = clone.Get(rp.RequestOptions([]byte("challenge"), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = rp.VerifyAssertion([]byte("challenge"), r, c); !errors.Is(err, webauthn.ErrSignCount) {
+		t.Fatalf("err = %v, want %v", err, webauthn.ErrSignCount)
+	}
+}
@@ -0,0 +1,199 @@
+// Package webauthntest provides a software authenticator to drive WebAuthn
+// ceremonies in tests, the way a browser and a security key would.
+package webauthntest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/PanziApp/backend/pkg/webauthn"
+)
+
+var (
+	// ErrCredentialExcluded is returned when the authenticator already holds an excluded credential.
+	ErrCredentialExcluded = errors.New("credential excluded")
+	// ErrNoCredential is returned when the authenticator holds no allowed credential.
+	ErrNoCredential = errors.New("no credential")
+)
+
+type credential struct {
+	id         []byte
+	rpId       string
+	userHandle []byte
+	key        *ecdsa.PrivateKey
+	signCount  uint32
+}
+
+// Authenticator is a P-256 authenticator that always verifies the user and keeps
+// its credentials discoverable. Responses are made as if by a client of the origin.
+type Authenticator struct {
+	Origin      string
+	credentials []*credential
+}
+
+// NewAuthenticator -.
+func NewAuthenticator(origin string) *Authenticator {
+	return &Authenticator{Origin: origin}
+}
+
+// Clone returns an authenticator holding copies of the credentials, with the same
+// signature counters, as an attacker who extracted the keys would.
+func (a *Authenticator) Clone() *Authenticator {
+	c := &Authenticator{Origin: a.Origin}
+	for _, cred := range a.credentials {
+		copied := *cred
+		c.credentials = append(c.credentials, &copied)
+	}
+
+	return c
+}
+
+// Create answers a registration ceremony like navigator.credentials.create.
+func (a *Authenticator) Create(options webauthn.CreationOptions) (r webauthn.AttestationResponse, err error) {
+	for _, excluded := range options.ExcludeCredentials {
+		if a.find(options.RP.Id, excluded.Id) != nil {
+			return r, ErrCredentialExcluded
+		}
+	}
+
+	cred := &credential{rpId: options.RP.Id, userHandle: options.User.Id}
+
+	cred.id = make([]byte, 32)
+	if _, err = rand.Read(cred.id); err != nil {
+		return r, err
+	}
+
+	cred.key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return r, err
+	}
+
+	publicKey, err := cbor.Marshal(map[int]interface{}{
+		1:  2, // kty: EC2
+		3:  webauthn.ES256,
+		-1: 1, // crv: P-256
+		-2: padded(cred.key.X.Bytes()),
+		-3: padded(cred.key.Y.Bytes()),
+	})
+	if err != nil {
+		return r, err
+	}
+
+	var attested bytes.Buffer
+	attested.Write(make([]byte, 16)) // AAGUID
+	_ = binary.Write(&attested, binary.BigEndian, uint16(len(cred.id)))
+	attested.Write(cred.id)
+	attested.Write(publicKey)
+
+	authData := authenticatorData(cred, webauthn.FlagAttestedCredential, attested.Bytes())
+
+	r.Response.AttestationObject, err = cbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	if err != nil {
+		return r, err
+	}
+
+	r.Response.ClientDataJSON, err = a.clientData("webauthn.create", options.Challenge)
+	if err != nil {
+		return r, err
+	}
+
+	a.credentials = append(a.credentials, cred)
+
+	r.Id = base64.RawURLEncoding.EncodeToString(cred.id)
+	r.RawId = cred.id
+	r.Type = "public-key"
+	r.Response.Transports = []string{"internal"}
+
+	return r, nil
+}
+
+// Get answers an authentication ceremony like navigator.credentials.get.
+func (a *Authenticator) Get(options webauthn.RequestOptions) (r webauthn.AssertionResponse, err error) {
+	var cred *credential
+	if len(options.AllowCredentials) == 0 {
+		cred = a.find(options.RPId, nil)
+	}
+	for _, allowed := range options.AllowCredentials {
+		if cred = a.find(options.RPId, allowed.Id); cred != nil {
+			break
+		}
+	}
+
+	if cred == nil {
+		return r, ErrNoCredential
+	}
+
+	cred.signCount++
+	authData := authenticatorData(cred, 0, nil)
+
+	r.Response.ClientDataJSON, err = a.clientData("webauthn.get", options.Challenge)
+	if err != nil {
+		return r, err
+	}
+
+	clientDataHash := sha256.Sum256(r.Response.ClientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+
+	r.Response.Signature, err = ecdsa.SignASN1(rand.Reader, cred.key, digest[:])
+	if err != nil {
+		return r, err
+	}
+
+	r.Id = base64.RawURLEncoding.EncodeToString(cred.id)
+	r.RawId = cred.id
+	r.Type = "public-key"
+	r.Response.AuthenticatorData = authData
+	r.Response.UserHandle = cred.userHandle
+
+	return r, nil
+}
+
+// find returns the credential with the id, or the first one of the relying party when id is nil.
+func (a *Authenticator) find(rpId string, id []byte) *credential {
+	for _, cred := range a.credentials {
+		if cred.rpId == rpId && (id == nil || bytes.Equal(cred.id, id)) {
+			return cred
+		}
+	}
+
+	return nil
+}
+
+func (a *Authenticator) clientData(typ string, challenge []byte) ([]byte, error) {
+	return json.Marshal(struct {
+		Type        string         `json:"type"`
+		Challenge   webauthn.Bytes `json:"challenge"`
+		Origin      string         `json:"origin"`
+		CrossOrigin bool           `json:"crossOrigin"`
+	}{typ, challenge, a.Origin, false})
+}
+
+func authenticatorData(cred *credential, flags byte, attested []byte) []byte {
+	rpIdHash := sha256.Sum256([]byte(cred.rpId))
+
+	var d bytes.Buffer
+	d.Write(rpIdHash[:])
+	d.WriteByte(webauthn.FlagUserPresent | webauthn.FlagUserVerified | flags)
+	_ = binary.Write(&d, binary.BigEndian, cred.signCount)
+	d.Write(attested)
+
+	return d.Bytes()
+}
+
+// padded left pads a P-256 coordinate to 32 bytes.
+func padded(b []byte) []byte {
+	return append(make([]byte, 32-len(b)), b...)
+}